@@ -0,0 +1,172 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentAnalysis describes how content is encoded, see AnalyzeContent().
+type ContentAnalysis struct {
+	// Data modes the whole content could be encoded in as a single segment,
+	// densest first. Byte mode is always included.
+	Modes []SegmentMode
+
+	// Densest data mode encoding the whole content as a single segment, i.e.
+	// Modes[0].
+	Mode SegmentMode
+
+	// Segments New() encodes the content in. Content mixing e.g. digits and
+	// lower case letters may be split into several segments.
+	Segments []Segment
+
+	// Length of the encoded data in bits, including the segment headers.
+	NumBits int
+
+	// Human readable suggestions of changes to the content which would allow
+	// a denser data mode, e.g. upper casing it. Empty if there are none.
+	Suggestions []string
+}
+
+// AnalyzeContent describes the data modes content qualifies for, and how New()
+// encodes it, e.g. to explain why a QR Code is larger than expected.
+//
+// Lower case letters, and leading or trailing spaces, rule out the denser
+// numeric and alphanumeric data modes, so often go unnoticed. For UTF-8
+// content, suggestions are made of changes which would allow a denser mode,
+// with the number of bits saved. NumBits and the savings are measured as for
+// the smallest QR Code versions (1-9), or the smallest versions able to hold
+// the content.
+//
+// Empty content qualifies for no data modes, and gives a zero ContentAnalysis.
+func AnalyzeContent(content string) ContentAnalysis {
+	if content == "" {
+		return ContentAnalysis{}
+	}
+
+	var a ContentAnalysis
+
+	a.Modes = contentModes(content)
+	a.Mode = a.Modes[0]
+
+	encoder, numBits := analysisEncoder(content)
+	if encoder == nil {
+		// Content too long for any version is still described.
+		encoder = newDataEncoder(dataEncoderType27To40)
+		encoder.segmentData([]byte(content))
+	}
+	a.NumBits = numBits
+
+	for _, s := range encoder.optimised {
+		a.Segments = append(a.Segments, Segment{
+			Mode: segmentMode(s.dataMode),
+			Data: append([]byte{}, s.data...),
+		})
+	}
+
+	// Upper casing would mangle Shift JIS and binary content.
+	if !utf8.ValidString(content) {
+		return a
+	}
+
+	upper := strings.ToUpper(content)
+	trimmed := strings.TrimSpace(content)
+
+	changes := []struct {
+		description string
+		content     string
+	}{
+		{"upper casing the content (see NewUpper())", upper},
+		{"removing leading and trailing whitespace", trimmed},
+		{"upper casing the content and removing leading and trailing whitespace", strings.TrimSpace(upper)},
+	}
+
+	best := a.Mode
+	for _, c := range changes {
+		if c.content == "" || c.content == content {
+			continue
+		}
+
+		// Suggest only changes allowing a denser mode than the simpler
+		// changes already suggested.
+		mode := contentModes(c.content)[0]
+		if mode >= best {
+			continue
+		}
+		best = mode
+
+		s := fmt.Sprintf("%s would allow %s mode", c.description, dataModeString(segmentDataModes[mode]))
+		if _, n := analysisEncoder(c.content); n > 0 && numBits > 0 && n < numBits {
+			s += fmt.Sprintf(", saving %d bits", numBits-n)
+		}
+
+		a.Suggestions = append(a.Suggestions, s)
+	}
+
+	return a
+}
+
+// analysisEncoder returns an encoder of the smallest versions able to encode
+// content, having segmented it, and the length of the encoded data in bits. A
+// nil encoder is returned if content is too long.
+func analysisEncoder(content string) (*dataEncoder, int) {
+	for _, encoder := range newDataEncoders() {
+		numBits, err := encoder.encodedBits([]byte(content))
+		if err == nil && chooseQRCodeVersion(Low, encoder, numBits) != nil {
+			return encoder, numBits
+		}
+	}
+
+	return nil, 0
+}
+
+// contentModes returns the data modes non-empty content could be encoded in as
+// a single segment, densest first.
+func contentModes(content string) []SegmentMode {
+	numeric, alphanumeric := true, true
+	for i := 0; i < len(content); i++ {
+		// isAlphanumericCharacter() excludes the digits.
+		numeric = numeric && isNumericCharacter(content[i])
+		alphanumeric = alphanumeric && (isNumericCharacter(content[i]) || isAlphanumericCharacter(content[i]))
+	}
+
+	// As for New(), valid UTF-8 content never uses Kanji mode.
+	kanji := len(content)%2 == 0 && !utf8.ValidString(content)
+	for i := 0; kanji && i < len(content); i += 2 {
+		kanji = isKanjiCharacter(content[i], content[i+1])
+	}
+
+	var modes []SegmentMode
+	switch {
+	case numeric:
+		modes = append(modes, Numeric, Alphanumeric)
+	case alphanumeric:
+		modes = append(modes, Alphanumeric)
+	case kanji:
+		modes = append(modes, Kanji)
+	}
+
+	return append(modes, Byte)
+}
+
+// segmentDataModes maps each SegmentMode to its data mode.
+var segmentDataModes = [...]dataMode{
+	Numeric:      dataModeNumeric,
+	Alphanumeric: dataModeAlphanumeric,
+	Byte:         dataModeByte,
+	Kanji:        dataModeKanji,
+}
+
+// segmentMode returns the SegmentMode of the data mode d.
+func segmentMode(d dataMode) SegmentMode {
+	for m, mode := range segmentDataModes {
+		if mode == d {
+			return SegmentMode(m)
+		}
+	}
+
+	return Byte
+}
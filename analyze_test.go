@@ -0,0 +1,71 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeContent(t *testing.T) {
+	a := AnalyzeContent("Hello123")
+
+	if a.Mode != Byte || !reflect.DeepEqual(a.Modes, []SegmentMode{Byte}) {
+		t.Errorf("got mode %d, modes %v, expected byte mode only", a.Mode, a.Modes)
+	}
+
+	q, err := New("Hello123", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if a.NumBits != q.DataBitsUsed() {
+		t.Errorf("got %d bits, expected %d", a.NumBits, q.DataBitsUsed())
+	}
+
+	// Upper case "HELLO123" is 4+9+44 bits in alphanumeric mode.
+	saving := a.NumBits - (4 + 9 + 44)
+	if len(a.Suggestions) != 1 || !strings.HasPrefix(a.Suggestions[0], "upper casing") ||
+		!strings.Contains(a.Suggestions[0], "alphanumeric mode") ||
+		!strings.HasSuffix(a.Suggestions[0], fmt.Sprintf("saving %d bits", saving)) {
+		t.Errorf("got suggestions %q, expected upper casing saving %d bits", a.Suggestions, saving)
+	}
+}
+
+func TestAnalyzeContentModes(t *testing.T) {
+	tests := []struct {
+		content        string
+		mode           SegmentMode
+		numSegments    int
+		numSuggestions int
+	}{
+		{"0123456789", Numeric, 1, 0},
+		{"HTTPS://EXAMPLE.ORG", Alphanumeric, 1, 0},
+		{"012345 ", Alphanumeric, 1, 1},
+		{" abc ", Byte, 1, 1},
+		{"grüße", Byte, 1, 0},
+		{"https://example.org/0123456789012345678901234567890123456789", Byte, 2, 1},
+		// "点茗" in Shift JIS.
+		{"\x93\x5f\xe4\xaa", Kanji, 1, 0},
+	}
+
+	for _, test := range tests {
+		a := AnalyzeContent(test.content)
+
+		if a.Mode != test.mode || a.Modes[len(a.Modes)-1] != Byte {
+			t.Errorf("%q: got mode %d, modes %v, expected mode %d", test.content, a.Mode, a.Modes, test.mode)
+		}
+		if len(a.Segments) != test.numSegments {
+			t.Errorf("%q: got segments %v, expected %d", test.content, a.Segments, test.numSegments)
+		}
+		if len(a.Suggestions) != test.numSuggestions {
+			t.Errorf("%q: got suggestions %q, expected %d", test.content, a.Suggestions, test.numSuggestions)
+		}
+	}
+
+	if a := AnalyzeContent(""); a.Modes != nil || a.Segments != nil || a.Suggestions != nil {
+		t.Errorf("empty content: got %+v, expected a zero ContentAnalysis", a)
+	}
+}
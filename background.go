@@ -0,0 +1,87 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// ImageWithBackground returns the QR Code as an image.Image, with bg showing
+// through its light modules.
+//
+// size is as for Image(). bg is scaled to cover the symbol (excluding the quiet
+// zone), and blended over the light modules at opacity, from 0 (invisible) to
+// 1 (fully opaque). Dark modules, the quiet zone, and the finder and timing
+// patterns are left as drawn by Image(), so the QR Code remains readable.
+//
+// Keep opacity low, e.g. 0.2-0.4, and bg light: A dark background reduces the
+// contrast of the light modules. An error occurs if bg is empty, or opacity is
+// outside 0-1.
+func (q *QRCode) ImageWithBackground(size int, bg image.Image, opacity float64) (image.Image, error) {
+	if bg == nil || bg.Bounds().Empty() {
+		return nil, errors.New("empty background image")
+	}
+
+	if math.IsNaN(opacity) || opacity < 0 || opacity > 1 {
+		return nil, fmt.Errorf("invalid background opacity %.2f (expected 0-1 inclusive)", opacity)
+	}
+
+	img := q.Image(size)
+	b := img.Bounds()
+
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	symbolBounds := q.symbolPixelBounds(b.Dx())
+	scaled := scaleImage(bg, symbolBounds.Dx(), symbolBounds.Dy())
+
+	bitmap := q.symbol.bitmap()
+	modulesPerPixel := float64(q.symbol.size) / float64(b.Dx())
+
+	for y := symbolBounds.Min.Y; y < symbolBounds.Max.Y; y++ {
+		my := int(float64(y) * modulesPerPixel)
+
+		for x := symbolBounds.Min.X; x < symbolBounds.Max.X; x++ {
+			mx := int(float64(x) * modulesPerPixel)
+
+			if bitmap[my][mx] || q.isFinderOrTimingModule(mx, my) {
+				continue
+			}
+
+			c := color.NRGBA64Model.Convert(scaled.At(x-symbolBounds.Min.X, y-symbolBounds.Min.Y)).(color.NRGBA64)
+			c.A = uint16(math.Round(float64(c.A) * opacity))
+
+			dst.Set(x, y, blendOver(c, dst.At(x, y)))
+		}
+	}
+
+	return dst, nil
+}
+
+// isFinderOrTimingModule returns true if the module at bitmap position (x, y)
+// is part of a finder pattern (including its separator), or a timing pattern.
+func (q *QRCode) isFinderOrTimingModule(x int, y int) bool {
+	border := q.symbol.quietZoneSize
+	size := q.symbol.symbolSize
+
+	x -= border
+	y -= border
+
+	inFinder := func(x0 int, y0 int) bool {
+		return x >= x0-1 && x <= x0+finderPatternSize && y >= y0-1 && y <= y0+finderPatternSize
+	}
+
+	if q.micro != nil {
+		return inFinder(0, 0) || x == 0 || y == 0
+	}
+
+	far := size - finderPatternSize
+
+	return inFinder(0, 0) || inFinder(far, 0) || inFinder(0, far) || x == 6 || y == 6
+}
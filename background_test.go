@@ -0,0 +1,108 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestImageWithBackground(t *testing.T) {
+	content := "https://example.org/background"
+
+	q, err := New(content, High)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bg := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	draw.Draw(bg, bg.Bounds(), &image.Uniform{color.RGBA{R: 0xff, A: 0xff}}, image.Point{}, draw.Src)
+
+	img, err := q.ImageWithBackground(-10, bg, 0.5)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bitmap := q.Bitmap()
+	border := q.symbol.quietZoneSize
+	if b := img.Bounds(); b.Dx() != len(bitmap)*10 {
+		t.Fatalf("got width %d, expected %d", b.Dx(), len(bitmap)*10)
+	}
+
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+	blended := color.RGBA{R: 0xff, G: 0x7f, B: 0x7f, A: 0xff}
+
+	at := func(mx int, my int) color.RGBA {
+		return color.RGBAModel.Convert(img.At(mx*10+5, my*10+5)).(color.RGBA)
+	}
+	near := func(a color.RGBA, b color.RGBA) bool {
+		d := func(x uint8, y uint8) bool { return x-y <= 1 || y-x <= 1 }
+		return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && a.A == b.A
+	}
+
+	var numLight, numDark int
+	for y := border; y < len(bitmap)-border; y++ {
+		for x := border; x < len(bitmap)-border; x++ {
+			c := at(x, y)
+
+			switch {
+			case bitmap[y][x]:
+				if c != black {
+					t.Fatalf("dark module (%d, %d): got %v, expected %v", x, y, c, black)
+				}
+				numDark++
+			case q.isFinderOrTimingModule(x, y):
+				if c != white {
+					t.Fatalf("pattern module (%d, %d): got %v, expected %v", x, y, c, white)
+				}
+			default:
+				if !near(c, blended) {
+					t.Fatalf("light module (%d, %d): got %v, expected %v", x, y, c, blended)
+				}
+				numLight++
+			}
+		}
+	}
+
+	if numLight == 0 || numDark == 0 {
+		t.Errorf("got %d light and %d dark modules", numLight, numDark)
+	}
+
+	// Quiet zone, and the light module separating the finder pattern.
+	if c := at(0, 0); c != white {
+		t.Errorf("quiet zone: got %v, expected %v", c, white)
+	}
+	if c := at(border+7, border+7); c != white {
+		t.Errorf("finder separator: got %v, expected %v", c, white)
+	}
+
+	// The light modules remain light enough to decode.
+	if s, err := Decode(img); err != nil || s != content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+}
+
+func TestImageWithBackgroundErrors(t *testing.T) {
+	q, err := New("background errors", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bg := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	if _, err := q.ImageWithBackground(-10, nil, 0.5); err == nil {
+		t.Errorf("nil background: got success, expected error")
+	}
+	if _, err := q.ImageWithBackground(-10, image.NewRGBA(image.Rectangle{}), 0.5); err == nil {
+		t.Errorf("empty background: got success, expected error")
+	}
+	for _, opacity := range []float64{-0.1, 1.1} {
+		if _, err := q.ImageWithBackground(-10, bg, opacity); err == nil {
+			t.Errorf("opacity %.1f: got success, expected error", opacity)
+		}
+	}
+}
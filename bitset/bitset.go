@@ -49,7 +49,10 @@ func New(v ...bool) *Bitset {
 
 // Clone returns a copy.
 func Clone(from *Bitset) *Bitset {
-	return &Bitset{numBits: from.numBits, bits: from.bits[:]}
+	bits := make([]byte, len(from.bits))
+	copy(bits, from.bits)
+
+	return &Bitset{numBits: from.numBits, bits: bits}
 }
 
 // Substr returns a substring, consisting of the bits from indexes start to end.
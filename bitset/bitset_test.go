@@ -319,3 +319,22 @@ func TestSubstr(t *testing.T) {
 		}
 	}
 }
+
+func TestClone(t *testing.T) {
+	b := New(b1, b0, b1, b1)
+	c := Clone(b)
+
+	if !c.Equals(b) {
+		t.Errorf("Got %s, expected %s", c.String(), b.String())
+	}
+
+	// Appending to the clone does not overwrite storage used when appending to
+	// the original.
+	c.AppendBools(b1, b1, b1, b1)
+	b.AppendBools(b0, b0, b0, b0)
+
+	expected := New(b1, b0, b1, b1, b0, b0, b0, b0)
+	if !b.Equals(expected) {
+		t.Errorf("Got %s, expected %s", b.String(), expected.String())
+	}
+}
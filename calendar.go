@@ -0,0 +1,112 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// A CalendarEvent describes an event, encoded as an iCalendar (RFC 5545)
+// VEVENT payload.
+//
+// Scanning the QR Code offers to add the event to the reader's calendar. Empty
+// fields are omitted.
+type CalendarEvent struct {
+	// Event title. Required.
+	Summary string
+
+	Location    string
+	Description string
+
+	// Start time. Required. Times are written in UTC.
+	Start time.Time
+
+	// End time, optional. For all day events, End is the last day of the event.
+	End time.Time
+
+	// All day event. Only the dates of Start and End are used, in their own
+	// time zones.
+	AllDay bool
+}
+
+const (
+	// iCalendar UTC date-time format.
+	iCalendarTimeFormat = "20060102T150405Z"
+
+	// iCalendar date format.
+	iCalendarDateFormat = "20060102"
+)
+
+// Encode constructs a QRCode containing the event.
+//
+// An error occurs if Summary or Start is empty, End is before Start, or the
+// event is too long.
+func (e CalendarEvent) Encode(level RecoveryLevel) (*QRCode, error) {
+	content, err := e.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// payload returns the event as a string.
+func (e CalendarEvent) payload() (string, error) {
+	if e.Summary == "" {
+		return "", errors.New("calendar event requires a summary")
+	}
+
+	if e.Start.IsZero() {
+		return "", errors.New("calendar event requires a start time")
+	}
+
+	if !e.End.IsZero() && e.End.Before(e.Start) {
+		return "", errors.New("calendar event ends before it starts")
+	}
+
+	var b strings.Builder
+
+	add := func(name string, value string) {
+		if value != "" {
+			b.WriteString(foldLine(name + ":" + value))
+		}
+	}
+
+	add("BEGIN", "VCALENDAR")
+	add("VERSION", "2.0")
+	add("PRODID", "-//skip2//go-qrcode//EN")
+	add("BEGIN", "VEVENT")
+
+	// Text values are escaped as vCard values.
+	add("SUMMARY", vCardEscaper.Replace(e.Summary))
+	add("LOCATION", vCardEscaper.Replace(e.Location))
+	add("DESCRIPTION", vCardEscaper.Replace(e.Description))
+
+	if e.AllDay {
+		start := e.Start
+		end := e.Start
+		if !e.End.IsZero() {
+			end = e.End
+		}
+
+		// DTEND is exclusive.
+		y, m, d := end.Date()
+		end = time.Date(y, m, d+1, 0, 0, 0, 0, end.Location())
+
+		add("DTSTART;VALUE=DATE", start.Format(iCalendarDateFormat))
+		add("DTEND;VALUE=DATE", end.Format(iCalendarDateFormat))
+	} else {
+		add("DTSTART", e.Start.UTC().Format(iCalendarTimeFormat))
+		if !e.End.IsZero() {
+			add("DTEND", e.End.UTC().Format(iCalendarTimeFormat))
+		}
+	}
+
+	add("END", "VEVENT")
+	add("END", "VCALENDAR")
+
+	return b.String(), nil
+}
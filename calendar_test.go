@@ -0,0 +1,121 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarEvent(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+
+	e := CalendarEvent{
+		Summary:     "Launch party",
+		Location:    "Main St, Springfield",
+		Description: "Food; drinks\nand " + strings.Repeat("music ", 15),
+		Start:       time.Date(2024, 6, 1, 19, 30, 0, 0, berlin),
+		End:         time.Date(2024, 6, 2, 1, 0, 0, 0, berlin),
+	}
+
+	payload, err := e.payload()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	properties := parseContentLines(t, payload)
+
+	expected := map[string][]string{
+		"BEGIN":       {"VCALENDAR", "VEVENT"},
+		"VERSION":     {"2.0"},
+		"PRODID":      {"-//skip2//go-qrcode//EN"},
+		"SUMMARY":     {"Launch party"},
+		"LOCATION":    {`Main St\, Springfield`},
+		"DESCRIPTION": {`Food\; drinks\nand ` + strings.Repeat("music ", 15)},
+		"DTSTART":     {"20240601T173000Z"},
+		"DTEND":       {"20240601T230000Z"},
+		"END":         {"VEVENT", "VCALENDAR"},
+	}
+
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("got %v, expected %v", properties, expected)
+	}
+
+	// The start and end times round trip.
+	for name, want := range map[string]time.Time{"DTSTART": e.Start, "DTEND": e.End} {
+		got, err := time.Parse(iCalendarTimeFormat, properties[name][0])
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !got.Equal(want) {
+			t.Errorf("%s: got %s, expected %s", name, got, want)
+		}
+	}
+
+	q, err := e.Encode(Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != payload {
+		t.Errorf("QR Code content does not match the payload")
+	}
+}
+
+func TestCalendarEventAllDay(t *testing.T) {
+	tests := []struct {
+		start time.Time
+		end   time.Time
+		dtend string
+	}{
+		{
+			time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			time.Time{},
+			"20250101",
+		},
+		{
+			time.Date(2024, 12, 30, 23, 0, 0, 0, time.FixedZone("EST", -5*60*60)),
+			time.Date(2024, 12, 31, 23, 0, 0, 0, time.FixedZone("EST", -5*60*60)),
+			"20250101",
+		},
+	}
+
+	for _, test := range tests {
+		e := CalendarEvent{Summary: "Holiday", Start: test.start, End: test.end, AllDay: true}
+
+		payload, err := e.payload()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		properties := parseContentLines(t, payload)
+
+		if got := properties["DTSTART;VALUE=DATE"]; len(got) != 1 || got[0] != test.start.Format(iCalendarDateFormat) {
+			t.Errorf("%s: got DTSTART %v", test.start, got)
+		}
+
+		if got := properties["DTEND;VALUE=DATE"]; len(got) != 1 || got[0] != test.dtend {
+			t.Errorf("%s: got DTEND %v, expected %s", test.start, got, test.dtend)
+		}
+	}
+}
+
+func TestCalendarEventInvalid(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	invalid := []CalendarEvent{
+		{Start: start},
+		{Summary: "No start"},
+		{Summary: "Backwards", Start: start, End: start.Add(-time.Hour)},
+	}
+
+	for _, e := range invalid {
+		if _, err := e.Encode(Medium); err == nil {
+			t.Errorf("%+v: got success, expected error", e)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"strings"
+)
+
+// maxCaptionLines is the number of lines a caption is wrapped onto before it is
+// truncated.
+const maxCaptionLines = 3
+
+// ImageWithCaption returns the QR Code as an image.Image, with caption drawn
+// centred beneath it, e.g. a human readable URL for signage.
+//
+// size is as for Image(), and sets the width of the image. The image is taller
+// than it is wide, to fit the caption. The caption is drawn in the
+// ForegroundColor on the BackgroundColor, using a built-in pixel font of upper
+// case letters, digits and common punctuation. Long captions are wrapped at
+// spaces onto up to three lines, and then truncated with an ellipsis.
+//
+// An error occurs if caption is empty.
+func (q *QRCode) ImageWithCaption(size int, caption string) (image.Image, error) {
+	caption = strings.Join(strings.Fields(caption), " ")
+	if caption == "" {
+		return nil, errors.New("empty caption")
+	}
+
+	img := q.Image(size)
+	b := img.Bounds()
+	width := b.Dx()
+
+	// The text is sized relative to the QR Code, with a margin of one
+	// character each side.
+	scale := max(1, width/128)
+	margin := (glyphWidth + glyphSpacing) * scale
+	lineHeight := (glyphHeight + 3) * scale
+
+	lines := wrapText(caption, scale, width-2*margin, maxCaptionLines)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, width+len(lines)*lineHeight+margin))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{q.BackgroundColor}, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(0, 0, width, width), img, b.Min, draw.Src)
+
+	for i, line := range lines {
+		p := image.Point{
+			X: (width - textWidth(line, scale)) / 2,
+			Y: width + i*lineHeight,
+		}
+		drawText(dst, line, p, scale, &image.Uniform{q.ForegroundColor})
+	}
+
+	return dst, nil
+}
+
+// wrapText splits text into at most maxLines lines, each no wider than width
+// pixels when drawn at scale. Lines are broken at spaces, or within words too
+// long for a line. Text which doesn't fit is truncated with an ellipsis.
+func wrapText(text string, scale int, width int, maxLines int) []string {
+	// Characters per line, at least one.
+	perLine := max(1, (width+glyphSpacing*scale)/((glyphWidth+glyphSpacing)*scale))
+
+	var lines []string
+	var line []rune
+
+	words := strings.Fields(text)
+	for i := 0; i < len(words); i++ {
+		word := []rune(words[i])
+
+		switch {
+		case len(line) == 0 && len(word) > perLine:
+			// Break a long word.
+			lines = append(lines, string(word[:perLine]))
+			words[i] = string(word[perLine:])
+			i--
+		case len(line) == 0:
+			line = word
+		case len(line)+1+len(word) <= perLine:
+			line = append(append(line, ' '), word...)
+		default:
+			lines = append(lines, string(line))
+			line = nil
+			i--
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+
+	if len(lines) > maxLines {
+		const ellipsis = "..."
+
+		last := []rune(lines[maxLines-1])
+		last = last[:min(len(last), max(0, perLine-len(ellipsis)))]
+		lines = append(lines[:maxLines-1], strings.TrimRight(string(last), " ")+ellipsis)
+	}
+
+	return lines
+}
@@ -0,0 +1,100 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImageWithCaption(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain := q.Image(256)
+	pb := plain.Bounds()
+
+	img, err := q.ImageWithCaption(256, "example.org")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b := img.Bounds()
+	if b.Dx() != pb.Dx() || b.Dy() <= pb.Dy() {
+		t.Fatalf("got size %v, expected %d wide and taller than %d", b.Size(), pb.Dx(), pb.Dy())
+	}
+
+	// The QR Code is unchanged.
+	for y := 0; y < pb.Dy(); y++ {
+		for x := 0; x < pb.Dx(); x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) != color.RGBAModel.Convert(plain.At(x, y)) {
+				t.Fatalf("pixel (%d, %d) differs from Image()", x, y)
+			}
+		}
+	}
+
+	// The caption is drawn beneath it.
+	dark := 0
+	for y := pb.Dy(); y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Errorf("caption not drawn")
+	}
+
+	// Long captions wrap onto more lines, up to a limit.
+	wrapped, err := q.ImageWithCaption(256, strings.Repeat("wrap ", 20))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	truncated, err := q.ImageWithCaption(256, strings.Repeat("truncate ", 100))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if wrapped.Bounds().Dy() <= b.Dy() || truncated.Bounds().Dy() != wrapped.Bounds().Dy() {
+		t.Errorf("got heights %d, %d, %d, expected increasing then capped",
+			b.Dy(), wrapped.Bounds().Dy(), truncated.Bounds().Dy())
+	}
+
+	if _, err := q.ImageWithCaption(256, " \t"); err == nil {
+		t.Errorf("empty caption: got success, expected error")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	// 10 characters per line at scale 1.
+	width := textWidth("0123456789", 1)
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"short", []string{"short"}},
+		{"two words", []string{"two words"}},
+		{"exactly 10 chars fit", []string{"exactly 10", "chars fit"}},
+		{"abcdefghijklmnop", []string{"abcdefghij", "klmnop"}},
+		{"one two three four five six seven eight", []string{"one two", "three four", "five si..."}},
+	}
+
+	for _, test := range tests {
+		got := wrapText(test.text, 1, width, 3)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: got %q, expected %q", test.text, got, test.expected)
+		}
+
+		for _, line := range got {
+			if textWidth(line, 1) > width {
+				t.Errorf("%q: line %q too wide", test.text, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// moduleType identifies the part of a QR Code symbol a module belongs to.
+type moduleType uint8
+
+const (
+	dataModule moduleType = iota
+	finderModule
+	timingModule
+	alignmentModule
+	formatInfoModule
+	versionInfoModule
+)
+
+// debugColors are the colours DebugImage() tints function patterns with, as
+// {dark, light} pairs indexed by moduleType.
+var debugColors = [...][2]color.RGBA{
+	finderModule:      {{R: 0xc0, G: 0x10, B: 0x10, A: 0xff}, {R: 0xff, G: 0xc8, B: 0xc8, A: 0xff}},
+	timingModule:      {{R: 0x10, G: 0x30, B: 0xc0, A: 0xff}, {R: 0xc8, G: 0xd4, B: 0xff, A: 0xff}},
+	alignmentModule:   {{R: 0x10, G: 0x90, B: 0x20, A: 0xff}, {R: 0xc8, G: 0xf0, B: 0xcc, A: 0xff}},
+	formatInfoModule:  {{R: 0xd0, G: 0x70, B: 0x00, A: 0xff}, {R: 0xff, G: 0xe0, B: 0xb0, A: 0xff}},
+	versionInfoModule: {{R: 0x80, G: 0x20, B: 0xa0, A: 0xff}, {R: 0xe8, G: 0xc8, B: 0xf4, A: 0xff}},
+}
+
+// DebugImage returns the QR Code as an image.Image, with the function patterns
+// tinted, to help diagnose codes which don't scan.
+//
+// size is as for Image(). The data modules are drawn as by Image(). The finder
+// patterns (including their separators) are tinted red, the timing patterns
+// blue, the alignment patterns green, the format information orange, and the
+// version information purple. Dark modules are drawn in a dark tint, and light
+// modules in a pale tint.
+//
+// The image is a diagnostic aid only, and is unlikely to scan.
+func (q *QRCode) DebugImage(size int) image.Image {
+	img := q.Image(size)
+	b := img.Bounds()
+
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	types := q.moduleTypes()
+	border := q.symbol.quietZoneSize
+	bitmap := q.symbol.bitmap()
+
+	symbolBounds := q.symbolPixelBounds(b.Dx())
+	modulesPerPixel := float64(q.symbol.size) / float64(b.Dx())
+
+	for y := symbolBounds.Min.Y; y < symbolBounds.Max.Y; y++ {
+		my := int(float64(y) * modulesPerPixel)
+
+		for x := symbolBounds.Min.X; x < symbolBounds.Max.X; x++ {
+			mx := int(float64(x) * modulesPerPixel)
+
+			t := types[my-border][mx-border]
+			if t == dataModule {
+				continue
+			}
+
+			if bitmap[my][mx] {
+				dst.SetRGBA(x, y, debugColors[t][0])
+			} else {
+				dst.SetRGBA(x, y, debugColors[t][1])
+			}
+		}
+	}
+
+	return dst
+}
+
+// FunctionModules returns which modules of the QR Code symbol are function
+// patterns, indexed as Matrix(): mask[y][x] is true if the module at (x, y) is
+// part of a finder pattern or its separator, a timing pattern, an alignment
+// pattern, or the format or version information (including the fixed dark
+// module). The other modules hold the data and error correction codewords.
+//
+// Custom renderers can use the mask to draw the function patterns specially,
+// e.g. keeping them square while styling the data modules, which aids
+// scanning.
+//
+// The returned mask is a copy, and may be freely modified.
+func (q *QRCode) FunctionModules() [][]bool {
+	types := q.moduleTypes()
+
+	mask := make([][]bool, len(types))
+	for y, row := range types {
+		mask[y] = make([]bool, len(row))
+		for x, t := range row {
+			mask[y][x] = t != dataModule
+		}
+	}
+
+	return mask
+}
+
+// moduleTypes returns the type of each module of the symbol, indexed [y][x],
+// excluding the quiet zone.
+func (q *QRCode) moduleTypes() [][]moduleType {
+	if q.micro != nil {
+		return microModuleTypes(q.micro.symbolSize())
+	}
+
+	size := q.version.symbolSize()
+
+	types := make([][]moduleType, size)
+	for i := range types {
+		types[i] = make([]moduleType, size)
+	}
+
+	// Add the function patterns in the same order as buildRegularSymbol(),
+	// recording the modules each step sets.
+	m := &regularSymbol{
+		version: q.version,
+		symbol:  newSymbol(size, 0),
+		size:    size,
+	}
+
+	steps := []struct {
+		add func()
+		t   moduleType
+	}{
+		{m.addFinderPatterns, finderModule},
+		{m.addAlignmentPatterns, alignmentModule},
+		{m.addTimingPatterns, timingModule},
+		{m.addFormatInfo, formatInfoModule},
+		{m.addVersionInfo, versionInfoModule},
+	}
+
+	for _, step := range steps {
+		step.add()
+
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if !m.symbol.empty(x, y) && types[y][x] == dataModule {
+					types[y][x] = step.t
+				}
+			}
+		}
+	}
+
+	return types
+}
+
+// microModuleTypes returns the type of each module of a Micro QR Code symbol
+// of width size, as laid out by buildMicroSymbol().
+func microModuleTypes(size int) [][]moduleType {
+	types := make([][]moduleType, size)
+
+	for y := range types {
+		types[y] = make([]moduleType, size)
+
+		for x := range types[y] {
+			switch {
+			case x <= finderPatternSize && y <= finderPatternSize:
+				// Including the separator.
+				types[y][x] = finderModule
+			case x == 0 || y == 0:
+				types[y][x] = timingModule
+			case x == finderPatternSize+1 && y <= finderPatternSize+1 ||
+				y == finderPatternSize+1 && x <= finderPatternSize+1:
+				types[y][x] = formatInfoModule
+			}
+		}
+	}
+
+	return types
+}
@@ -0,0 +1,174 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDebugImage(t *testing.T) {
+	q, err := New(strings.Repeat("debug image ", 20), Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Version() < 7 {
+		t.Fatalf("got version %d, expected 7+ for version information", q.Version())
+	}
+
+	img := q.DebugImage(-10)
+	b := img.Bounds()
+
+	colors := make(map[color.RGBA]bool)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			colors[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)] = true
+		}
+	}
+
+	if len(colors) <= 2 {
+		t.Errorf("got %d distinct colours, expected more than 2", len(colors))
+	}
+
+	border := q.symbol.quietZoneSize
+	size := q.symbol.symbolSize
+	center := alignmentPatternCenter[q.Version()][1]
+
+	at := func(x int, y int) color.RGBA {
+		return color.RGBAModel.Convert(img.At((border+x)*10+5, (border+y)*10+5)).(color.RGBA)
+	}
+
+	tests := []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{0, 0, debugColors[finderModule][0]},
+		{size - 1, 0, debugColors[finderModule][0]},
+		{0, size - 1, debugColors[finderModule][0]},
+		{7, 7, debugColors[finderModule][1]},
+		{8, 6, debugColors[timingModule][0]},
+		{6, 9, debugColors[timingModule][1]},
+		{center, center, debugColors[alignmentModule][0]},
+		{center + 1, center, debugColors[alignmentModule][1]},
+		{8, size - 8, debugColors[formatInfoModule][0]},
+	}
+
+	for _, test := range tests {
+		if c := at(test.x, test.y); c != test.expected {
+			t.Errorf("module (%d, %d): got %v, expected %v", test.x, test.y, c, test.expected)
+		}
+	}
+
+	if c := at(size-9, 0); c != debugColors[versionInfoModule][0] && c != debugColors[versionInfoModule][1] {
+		t.Errorf("version information: got %v", c)
+	}
+
+	// Data modules and the quiet zone are drawn as by Image().
+	plain := q.Image(-10)
+	for _, p := range [][2]int{{-1, -1}, {size - 1, size - 1}} {
+		x, y := (border+p[0])*10+5, (border+p[1])*10+5
+		if c, expected := img.At(x, y), plain.At(x, y); color.RGBAModel.Convert(c) != color.RGBAModel.Convert(expected) {
+			t.Errorf("module (%d, %d): got %v, expected %v", p[0], p[1], c, expected)
+		}
+	}
+}
+
+func TestDebugImageMicro(t *testing.T) {
+	q, err := NewMicro("12345", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	types := q.moduleTypes()
+
+	tests := []struct {
+		x, y     int
+		expected moduleType
+	}{
+		{0, 0, finderModule},
+		{7, 7, finderModule},
+		{9, 0, timingModule},
+		{0, 9, timingModule},
+		{8, 1, formatInfoModule},
+		{1, 8, formatInfoModule},
+		{9, 9, dataModule},
+	}
+
+	for _, test := range tests {
+		if got := types[test.y][test.x]; got != test.expected {
+			t.Errorf("module (%d, %d): got type %d, expected %d", test.x, test.y, got, test.expected)
+		}
+	}
+
+	// Smoke test.
+	q.DebugImage(-10)
+}
+
+func TestFunctionModules(t *testing.T) {
+	for _, version := range []int{1, 2, 7, 40} {
+		q, err := NewWithForcedVersion("modules", version, Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		mask := q.FunctionModules()
+		size := len(q.Matrix())
+		if len(mask) != size || len(mask[0]) != size {
+			t.Fatalf("version %d: got %dx%d mask, expected %dx%d", version, len(mask[0]), len(mask), size, size)
+		}
+
+		// The finder patterns, including their separators.
+		for _, corner := range [][2]int{{0, 0}, {size - 8, 0}, {0, size - 8}} {
+			for y := corner[1]; y < corner[1]+8; y++ {
+				for x := corner[0]; x < corner[0]+8; x++ {
+					if !mask[y][x] {
+						t.Errorf("version %d: finder module (%d, %d) not flagged", version, x, y)
+					}
+				}
+			}
+		}
+
+		// The alignment pattern centres. Those overlapping the finder patterns
+		// aren't drawn, but are finder modules.
+		centers := alignmentPatternCenter[version]
+		for _, cy := range centers {
+			for _, cx := range centers {
+				if !mask[cy][cx] {
+					t.Errorf("version %d: alignment centre (%d, %d) not flagged", version, cx, cy)
+				}
+			}
+		}
+		if version >= 2 && len(centers) == 0 {
+			t.Errorf("version %d: no alignment patterns", version)
+		}
+
+		// The remaining modules hold exactly the codewords and remainder bits.
+		numData := 0
+		for _, row := range mask {
+			for _, function := range row {
+				if !function {
+					numData++
+				}
+			}
+		}
+
+		numCodewords := 0
+		for _, b := range q.version.block {
+			numCodewords += b.numBlocks * b.numCodewords
+		}
+		if expected := numCodewords*8 + q.version.numRemainderBits; numData != expected {
+			t.Errorf("version %d: got %d data modules, expected %d", version, numData, expected)
+		}
+	}
+
+	m, err := NewMicro("12345", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if mask := m.FunctionModules(); len(mask) != len(m.Matrix()) || !mask[0][0] || mask[len(mask)-1][len(mask)-1] {
+		t.Errorf("Micro QR Code: got unexpected mask")
+	}
+}
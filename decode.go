@@ -0,0 +1,762 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
+)
+
+// Decoding.
+//
+// The decoder is intended to read back QR Codes produced by this package: clean,
+// axis-aligned and unrotated images, such as those returned by Image(). It
+// does not attempt to locate QR Codes in photographs.
+//
+// Decoding reverses each encoding step:
+//
+// - The image is binarised, and the symbol located by its dark bounding box.
+// - The number of modules is counted along the horizontal timing pattern, and
+//   each module sampled at its centre.
+// - The format information gives the recovery level and data mask.
+// - The data modules are read, unmasked, de-interleaved into blocks, and
+//   corrected using the Reed-Solomon error correction codewords.
+// - The segments of the resulting data are decoded.
+
+// Decode decodes a QR Code from img, and returns its content.
+//
+// img must contain a single, axis-aligned QR Code, such as produced by Image().
+// Light on dark (inverted) QR Codes are also decoded.
+//
+// An error occurs if no QR Code is found, or if the QR Code is too damaged to
+// be decoded.
+func Decode(img image.Image) (string, error) {
+	d, err := decodeImage(img)
+	if err != nil {
+		return "", err
+	}
+
+	return string(d.content), nil
+}
+
+// DecodePNG decodes a QR Code from a PNG image, such as produced by PNG(), and
+// returns its content.
+//
+// The image is decoded as by Decode(), which samples each module at its centre,
+// so images of any number of pixels per module are decoded. An error occurs if
+// data is not a valid PNG image, or as for Decode().
+func DecodePNG(data []byte) (string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid PNG: %w", err)
+	}
+
+	return Decode(img)
+}
+
+// DecodeStructuredAppend decodes a Structured Append sequence of QR Codes, one
+// per image, such as produced by EncodeStructuredAppend(), and returns the
+// reassembled content.
+//
+// The images may be in any order. Each is decoded as by Decode().
+//
+// An error occurs if an image cannot be decoded or has no Structured Append
+// header, the symbols disagree on the number of symbols or the parity byte, a
+// symbol is missing or duplicated, or the parity byte does not match the
+// reassembled content.
+func DecodeStructuredAppend(imgs []image.Image) (string, error) {
+	if len(imgs) == 0 {
+		return "", errors.New("no images to decode")
+	}
+
+	var first *structuredAppend
+	parts := make([][]byte, len(imgs))
+	found := make([]bool, len(imgs))
+
+	for i, img := range imgs {
+		d, err := decodeImage(img)
+		if err != nil {
+			return "", fmt.Errorf("image %d: %w", i, err)
+		}
+
+		s := d.structuredAppend
+		if s == nil {
+			return "", fmt.Errorf("image %d: no Structured Append header", i)
+		}
+
+		if first == nil {
+			first = s
+		}
+
+		switch {
+		case s.total != len(imgs):
+			return "", fmt.Errorf("image %d: symbol %d of %d, got %d images", i, s.index+1, s.total, len(imgs))
+		case s.index >= s.total:
+			return "", fmt.Errorf("image %d: invalid symbol %d of %d", i, s.index+1, s.total)
+		case s.parity != first.parity:
+			return "", fmt.Errorf("image %d: parity %#02x differs from %#02x", i, s.parity, first.parity)
+		case found[s.index]:
+			return "", fmt.Errorf("image %d: duplicate symbol %d of %d", i, s.index+1, s.total)
+		}
+
+		parts[s.index] = d.content
+		found[s.index] = true
+	}
+
+	// With no duplicates, and as many symbols as stated, none are missing.
+	content := bytes.Join(parts, nil)
+
+	var parity byte
+	for _, c := range content {
+		parity ^= c
+	}
+
+	if parity != first.parity {
+		return "", fmt.Errorf("parity %#02x of the content does not match %#02x", parity, first.parity)
+	}
+
+	return string(content), nil
+}
+
+// Verify checks the QR Code scans: It renders the QR Code as by Image(), with
+// the current drawing options (e.g. ModuleShape and ModuleGap), and checks it
+// as by VerifyImage().
+//
+// This is a safety net for styled QR Codes, and against encoder bugs. The
+// decoder is stricter than most readers: It locates the symbol by its finder
+// patterns, so those drawn in a ModuleShape other than Square, or with a
+// ModuleGap, fail verification unless SquareFinders is set. Images
+// modified after rendering, e.g. by ImageWithLogo(), are checked with
+// VerifyImage(), which returns the same errors.
+func (q *QRCode) Verify() error {
+	return q.VerifyImage(q.Image(-10))
+}
+
+// VerifyImage checks img, a rendering of the QR Code, scans: It decodes img,
+// and checks the decoded content is the Content. Use it to check images
+// obscured by a logo, or otherwise modified after rendering, e.g.:
+//
+//	img, err := q.ImageWithLogo(256, logo, 0.3)
+//	...
+//	err = q.VerifyImage(img)
+//
+// An error occurs if the image cannot be decoded, the decoded content differs,
+// or q is a Micro QR Code, which the decoder does not support.
+func (q *QRCode) VerifyImage(img image.Image) error {
+	if q.micro != nil {
+		return errors.New("cannot verify a Micro QR Code")
+	}
+
+	content, err := Decode(img)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+
+	if content != q.Content {
+		return fmt.Errorf("verify: decoded content %q, expected %q", content, q.Content)
+	}
+
+	return nil
+}
+
+// A decodedSymbol is the result of decoding a single QR Code symbol.
+type decodedSymbol struct {
+	version qrCodeVersion
+	mask    int
+
+	// Decoded content.
+	content []byte
+
+	// Structured Append header, or nil.
+	structuredAppend *structuredAppend
+}
+
+// decodeImage decodes the QR Code in img, trying both dark on light and light
+// on dark colourings.
+func decodeImage(img image.Image) (*decodedSymbol, error) {
+	dark, err := binarise(img)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *decodedSymbol
+	for _, inverted := range []bool{false, true} {
+		var matrix [][]bool
+		matrix, err = sampleModules(dark, inverted)
+		if err != nil {
+			continue
+		}
+
+		result, err = decodeMatrix(matrix)
+		if err == nil {
+			return result, nil
+		}
+	}
+
+	return nil, err
+}
+
+// binarise classifies each pixel of img as dark or light. Translucent pixels
+// are composited over white.
+//
+// dark[y][x] is true if the pixel at (x, y), relative to the image origin, is
+// dark.
+func binarise(img image.Image) ([][]bool, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, errors.New("no QR Code found: empty image")
+	}
+
+	luminance := make([][]uint32, b.Dy())
+	minLuminance := uint32(math.MaxUint32)
+	maxLuminance := uint32(0)
+
+	for y := 0; y < b.Dy(); y++ {
+		luminance[y] = make([]uint32, b.Dx())
+
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+
+			// The colour is alpha-premultiplied, so add the white background.
+			r += 0xffff - a
+			g += 0xffff - a
+			bl += 0xffff - a
+
+			// ITU-R BT.601 luma, as used by color.GrayModel.
+			l := (19595*r + 38470*g + 7471*bl + 1<<15) >> 16
+
+			luminance[y][x] = l
+			if l < minLuminance {
+				minLuminance = l
+			}
+			if l > maxLuminance {
+				maxLuminance = l
+			}
+		}
+	}
+
+	if minLuminance == maxLuminance {
+		return nil, errors.New("no QR Code found: image has a single colour")
+	}
+
+	threshold := (minLuminance + maxLuminance) / 2
+
+	dark := make([][]bool, b.Dy())
+	for y := range dark {
+		dark[y] = make([]bool, b.Dx())
+
+		for x := range dark[y] {
+			dark[y][x] = luminance[y][x] < threshold
+		}
+	}
+
+	return dark, nil
+}
+
+// sampleModules locates the QR Code symbol in the binarised image, and returns
+// the value of each of its modules. The quiet zone is not included.
+//
+// If inverted is true, light pixels are treated as dark modules.
+func sampleModules(dark [][]bool, inverted bool) ([][]bool, error) {
+	isDark := func(x int, y int) bool {
+		return dark[y][x] != inverted
+	}
+
+	height := len(dark)
+	width := len(dark[0])
+
+	// The three finder patterns touch the left, top, right and bottom edges of
+	// the symbol, so the bounding box of all dark pixels is the symbol.
+	minX, minY, maxX, maxY := width, height, -1, -1
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !isDark(x, y) {
+				continue
+			}
+
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+
+	if maxX < 0 {
+		return nil, errors.New("no QR Code found")
+	}
+
+	symbolWidth := maxX - minX + 1
+	symbolHeight := maxY - minY + 1
+
+	// The top edge of the top left finder pattern is 7 modules wide.
+	finderWidth := 0
+	for x := minX; x <= maxX && isDark(x, minY); x++ {
+		finderWidth++
+	}
+
+	pitch := float64(finderWidth) / float64(finderPatternSize)
+	if pitch < 1 {
+		return nil, errors.New("no QR Code found: modules too small")
+	}
+
+	// Count the dark runs along the horizontal timing pattern (module row 6).
+	// This consists of the two finder patterns, plus the (size-15)/2 dark
+	// timing modules.
+	timingY := minY + int(6.5*pitch)
+	if timingY > maxY {
+		return nil, errors.New("no QR Code found")
+	}
+
+	numDarkRuns := 0
+	for x := minX; x <= maxX; x++ {
+		if isDark(x, timingY) && (x == minX || !isDark(x-1, timingY)) {
+			numDarkRuns++
+		}
+	}
+
+	size := 2*numDarkRuns + 11
+	if size < 21 || size > 177 {
+		return nil, fmt.Errorf("no QR Code found: %d modules wide", size)
+	}
+
+	// Sample the centre of each module.
+	moduleWidth := float64(symbolWidth) / float64(size)
+	moduleHeight := float64(symbolHeight) / float64(size)
+
+	matrix := make([][]bool, size)
+	for y := range matrix {
+		matrix[y] = make([]bool, size)
+		py := minY + int((float64(y)+0.5)*moduleHeight)
+
+		for x := range matrix[y] {
+			px := minX + int((float64(x)+0.5)*moduleWidth)
+
+			matrix[y][x] = isDark(px, py)
+		}
+	}
+
+	return matrix, nil
+}
+
+// decodeMatrix decodes a QR Code symbol. matrix[y][x] is true if the module at
+// (x, y) is dark. The matrix does not include the quiet zone.
+func decodeMatrix(matrix [][]bool) (*decodedSymbol, error) {
+	size := len(matrix)
+	if size < 21 || size > 177 || (size-17)%4 != 0 {
+		return nil, fmt.Errorf("invalid QR Code size %d", size)
+	}
+
+	level, mask, err := readFormatInfo(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	version := getQRCodeVersion(level, (size-17)/4)
+	if version == nil {
+		return nil, errors.New("cannot find QR Code version")
+	}
+
+	// A symbol containing only the function patterns. The remaining (empty)
+	// modules hold the data.
+	s, err := buildRegularSymbol(*version, mask, bitset.New(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	codewords := bitset.New()
+	walkDataModules(s, s.numEmptyModules(), true, func(x int, y int) {
+		codewords.AppendBools(matrix[y][x] != dataMask(mask, x, y))
+	})
+
+	data, err := decodeBlocks(*version, codewords)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &decodedSymbol{
+		version: *version,
+		mask:    mask,
+	}
+
+	err = result.decodeSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// readFormatInfo reads the 15-bit Format Information value from either of its
+// two copies, and returns the recovery level and data mask it states.
+//
+// Up to 3 bit errors are tolerated.
+func readFormatInfo(matrix [][]bool) (RecoveryLevel, int, error) {
+	size := len(matrix)
+	fpSize := finderPatternSize
+
+	// Bit i of the Format Information value is at coordinates[i] on either
+	// side of the symbol. See regularSymbol.addFormatInfo().
+	var primary, secondary [formatInfoLengthBits][2]int
+	for i := 0; i <= 5; i++ {
+		primary[i] = [2]int{fpSize + 1, i}
+	}
+	primary[6] = [2]int{fpSize + 1, fpSize}
+	primary[7] = [2]int{fpSize + 1, fpSize + 1}
+	primary[8] = [2]int{fpSize, fpSize + 1}
+	for i := 9; i <= 14; i++ {
+		primary[i] = [2]int{14 - i, fpSize + 1}
+	}
+
+	for i := 0; i <= 7; i++ {
+		secondary[i] = [2]int{size - i - 1, fpSize + 1}
+	}
+	for i := 8; i <= 14; i++ {
+		secondary[i] = [2]int{fpSize + 1, size - fpSize + i - 8}
+	}
+
+	bestFormatID := -1
+	bestDistance := formatInfoLengthBits
+
+	for _, coordinates := range [][formatInfoLengthBits][2]int{primary, secondary} {
+		var value uint32
+		for i, c := range coordinates {
+			if matrix[c[1]][c[0]] {
+				value |= 1 << uint(i)
+			}
+		}
+
+		for formatID, f := range formatBitSequence {
+			distance := hammingDistance(value, f.regular)
+			if distance < bestDistance {
+				bestFormatID = formatID
+				bestDistance = distance
+			}
+		}
+	}
+
+	if bestDistance > 3 {
+		return 0, 0, errors.New("cannot read QR Code format information")
+	}
+
+	var level RecoveryLevel
+	switch bestFormatID &^ 0x7 {
+	case 0x08:
+		level = Low
+	case 0x00:
+		level = Medium
+	case 0x18:
+		level = High
+	case 0x10:
+		level = Highest
+	}
+
+	return level, bestFormatID & 0x7, nil
+}
+
+// hammingDistance returns the number of bits which differ between a and b.
+func hammingDistance(a uint32, b uint32) int {
+	distance := 0
+	for v := a ^ b; v != 0; v &= v - 1 {
+		distance++
+	}
+
+	return distance
+}
+
+// decodeBlocks reverses encodeBlocks(): codewords are de-interleaved into
+// blocks, errors corrected, and the data codewords returned.
+func decodeBlocks(version qrCodeVersion, codewords *bitset.Bitset) (*bitset.Bitset, error) {
+	type dataBlock struct {
+		numCodewords     int
+		numDataCodewords int
+		data             *bitset.Bitset
+	}
+
+	var blocks []dataBlock
+	numCodewords := 0
+	for _, b := range version.block {
+		for j := 0; j < b.numBlocks; j++ {
+			blocks = append(blocks, dataBlock{
+				numCodewords:     b.numCodewords,
+				numDataCodewords: b.numDataCodewords,
+				data:             bitset.New(),
+			})
+			numCodewords += b.numCodewords
+		}
+	}
+
+	if codewords.Len() < numCodewords*8 {
+		return nil, errors.New("too few data modules")
+	}
+
+	// Data codewords, then error correction codewords, are interleaved across
+	// the blocks.
+	offset := 0
+	for _, ecPhase := range []bool{false, true} {
+		working := true
+		for i := 0; working; i++ {
+			working = false
+
+			for j, b := range blocks {
+				n := b.numDataCodewords
+				if ecPhase {
+					n = b.numCodewords - b.numDataCodewords
+				}
+
+				if i >= n {
+					continue
+				}
+
+				blocks[j].data.Append(codewords.Substr(offset, offset+8))
+				offset += 8
+
+				working = true
+			}
+		}
+	}
+
+	result := bitset.New()
+	for _, b := range blocks {
+		data, err := reedsolomon.Decode(b.data, b.numCodewords-b.numDataCodewords)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Append(data)
+	}
+
+	return result, nil
+}
+
+// decodeSegments decodes the segments of the error corrected data codewords.
+func (d *decodedSymbol) decodeSegments(data *bitset.Bitset) error {
+	encoder := newDataEncoder(d.version.dataEncoderType)
+	r := &bitReader{bits: data}
+
+	for r.remaining() >= 4 {
+		mode, _ := r.read(4)
+
+		var err error
+		switch mode {
+		case 0x0:
+			// Terminator.
+			return nil
+		case 0x1:
+			err = d.decodeNumeric(r, encoder.numNumericCharCountBits)
+		case 0x2:
+			err = d.decodeAlphanumeric(r, encoder.numAlphanumericCharCountBits)
+		case 0x3:
+			err = d.decodeStructuredAppend(r)
+		case 0x4:
+			err = d.decodeByte(r, encoder.numByteCharCountBits)
+		case 0x7:
+			// The ECI designator is skipped: the content is returned as is.
+			_, err = decodeECI(r)
+		case 0x8:
+			err = d.decodeKanji(r, encoder.numKanjiCharCountBits)
+		default:
+			err = fmt.Errorf("unsupported data mode %#x", mode)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *decodedSymbol) decodeNumeric(r *bitReader, charCountBits int) error {
+	n, err := r.read(charCountBits)
+	if err != nil {
+		return err
+	}
+
+	// Groups of 3 digits are 10 bits long. A final group of 2 or 1 digits is 7
+	// or 4 bits long.
+	for n > 0 {
+		numDigits := uint32(3)
+		if n < 3 {
+			numDigits = n
+		}
+
+		v, err := r.read(int(3*numDigits + 1))
+		if err != nil {
+			return err
+		}
+
+		if v >= uint32(math.Pow10(int(numDigits))) {
+			return errors.New("invalid numeric data")
+		}
+
+		d.content = append(d.content, fmt.Sprintf("%0*d", numDigits, v)...)
+		n -= numDigits
+	}
+
+	return nil
+}
+
+// alphanumericCharacters maps QR Code encoded alphanumeric values (0-44) to
+// characters.
+const alphanumericCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+func (d *decodedSymbol) decodeAlphanumeric(r *bitReader, charCountBits int) error {
+	n, err := r.read(charCountBits)
+	if err != nil {
+		return err
+	}
+
+	for ; n >= 2; n -= 2 {
+		v, err := r.read(11)
+		if err != nil {
+			return err
+		}
+
+		if v >= 45*45 {
+			return errors.New("invalid alphanumeric data")
+		}
+
+		d.content = append(d.content, alphanumericCharacters[v/45],
+			alphanumericCharacters[v%45])
+	}
+
+	if n == 1 {
+		v, err := r.read(6)
+		if err != nil {
+			return err
+		}
+
+		if v >= 45 {
+			return errors.New("invalid alphanumeric data")
+		}
+
+		d.content = append(d.content, alphanumericCharacters[v])
+	}
+
+	return nil
+}
+
+func (d *decodedSymbol) decodeByte(r *bitReader, charCountBits int) error {
+	n, err := r.read(charCountBits)
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		v, err := r.read(8)
+		if err != nil {
+			return err
+		}
+
+		d.content = append(d.content, byte(v))
+	}
+
+	return nil
+}
+
+func (d *decodedSymbol) decodeKanji(r *bitReader, charCountBits int) error {
+	n, err := r.read(charCountBits)
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		v, err := r.read(13)
+		if err != nil {
+			return err
+		}
+
+		// Reverse encodeKanjiCharacter().
+		c := (v/0xc0)<<8 | v%0xc0
+		if c < 0x1f00 {
+			c += 0x8140
+		} else {
+			c += 0xc140
+		}
+
+		d.content = append(d.content, byte(c>>8), byte(c))
+	}
+
+	return nil
+}
+
+func (d *decodedSymbol) decodeStructuredAppend(r *bitReader) error {
+	v, err := r.read(16)
+	if err != nil {
+		return err
+	}
+
+	d.structuredAppend = &structuredAppend{
+		index:  int(v >> 12),
+		total:  int(v>>8&0xf) + 1,
+		parity: byte(v),
+	}
+
+	return nil
+}
+
+// decodeECI reads an ECI designator, and returns its assignment number.
+func decodeECI(r *bitReader) (uint32, error) {
+	v, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case v&0x80 == 0:
+		return v, nil
+	case v&0xc0 == 0x80:
+		w, err := r.read(8)
+		return (v&0x3f)<<8 | w, err
+	case v&0xe0 == 0xc0:
+		w, err := r.read(16)
+		return (v&0x1f)<<16 | w, err
+	}
+
+	return 0, errors.New("invalid ECI designator")
+}
+
+// bitReader reads values from a Bitset.
+type bitReader struct {
+	bits *bitset.Bitset
+	pos  int
+}
+
+// remaining returns the number of unread bits.
+func (r *bitReader) remaining() int {
+	return r.bits.Len() - r.pos
+}
+
+// read reads an n bit (n <= 32) value.
+func (r *bitReader) read(n int) (uint32, error) {
+	if n > r.remaining() {
+		return 0, errors.New("unexpected end of data")
+	}
+
+	var v uint32
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if r.bits.At(r.pos) {
+			v |= 1
+		}
+		r.pos++
+	}
+
+	return v, nil
+}
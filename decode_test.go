@@ -0,0 +1,320 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	tests := []string{
+		"A",
+		"01234567",
+		"HELLO WORLD",
+		"https://example.org/path?query=1",
+		"Grüße aus Köln",
+		"\x93\x5f\xe4\xaa",
+		strings.Repeat("0123456789", 50),
+		strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20),
+	}
+
+	for _, content := range tests {
+		for _, level := range []RecoveryLevel{Low, Medium, High, Highest} {
+			q, err := New(content, level)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			s, err := Decode(q.Image(-3))
+			if err != nil {
+				t.Errorf("%q level %d: got error %s", content, level, err.Error())
+				continue
+			}
+
+			if s != content {
+				t.Errorf("%q level %d: got %q", content, level, s)
+			}
+		}
+	}
+}
+
+func TestDecodeAllVersions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestDecodeAllVersions")
+	}
+
+	for version := 1; version <= 40; version++ {
+		level := RecoveryLevel(version % 4)
+		content := fmt.Sprintf("v-%d l-%d", version, level)
+
+		q, err := NewWithForcedVersion(content, version, level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// Fixed size images have modules of uneven pixel widths.
+		s, err := Decode(q.Image(256))
+		if err != nil {
+			t.Errorf("version %d: got error %s", version, err.Error())
+			continue
+		}
+
+		if s != content {
+			t.Errorf("version %d: got %q, expected %q", version, s, content)
+		}
+	}
+}
+
+func TestDecodeColorsAndBorder(t *testing.T) {
+	content := "https://example.org"
+
+	q, err := New(content, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.DisableBorder = true
+	q.ForegroundColor = color.RGBA{R: 0x33, G: 0x33, B: 0x66, A: 0xff}
+	q.BackgroundColor = color.RGBA{R: 0xef, G: 0xef, B: 0xef, A: 0xff}
+
+	if s, err := Decode(q.Image(-2)); err != nil || s != content {
+		t.Errorf("borderless: got %q, %v", s, err)
+	}
+
+	// Light on dark.
+	q.DisableBorder = false
+	q.ForegroundColor, q.BackgroundColor = q.BackgroundColor, q.ForegroundColor
+
+	if s, err := Decode(q.Image(-2)); err != nil || s != content {
+		t.Errorf("inverted: got %q, %v", s, err)
+	}
+}
+
+func TestDecodeErrorCorrection(t *testing.T) {
+	content := "error correction test"
+
+	q, err := New(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Overwrite a block of data modules near the bottom right corner.
+	img := q.Image(-4)
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, image.Point{}, draw.Src)
+
+	n := len(q.Bitmap())
+	border := q.symbol.quietZoneSize
+	scale := img.Bounds().Dx() / n
+	min := (n - border - 4) * scale
+	max := (n - border) * scale
+	draw.Draw(dst, image.Rect(min, min, max, max), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	s, err := Decode(dst)
+	if err != nil {
+		t.Fatalf("got error %s", err.Error())
+	}
+
+	if s != content {
+		t.Errorf("got %q, expected %q", s, content)
+	}
+}
+
+func TestDecodePNG(t *testing.T) {
+	content := "https://example.org/decode-png"
+
+	q, err := New(content, High)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, size := range []int{256, 1000, -15} {
+		data, err := q.PNG(size)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if s, err := DecodePNG(data); err != nil || s != content {
+			t.Errorf("size %d: got %q, %v, expected %q", size, s, err, content)
+		}
+	}
+
+	if _, err := DecodePNG([]byte("not a PNG")); err == nil {
+		t.Errorf("invalid PNG: got success, expected error")
+	}
+}
+
+// structuredAppendImages returns images of a Structured Append sequence of
+// chunks, with the given parity byte.
+func structuredAppendImages(t *testing.T, chunks []string, parity byte) []image.Image {
+	imgs := make([]image.Image, len(chunks))
+	for i, chunk := range chunks {
+		q, err := newWithOptions(chunk, Medium, encodeOptions{
+			structuredAppend: &structuredAppend{index: i, total: len(chunks), parity: parity},
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		imgs[i] = q.Image(-3)
+	}
+
+	return imgs
+}
+
+func TestDecodeStructuredAppend(t *testing.T) {
+	chunks := []string{"Structured ", "Append ", "grüße"}
+	content := strings.Join(chunks, "")
+
+	var parity byte
+	for i := 0; i < len(content); i++ {
+		parity ^= content[i]
+	}
+
+	imgs := structuredAppendImages(t, chunks, parity)
+
+	// The images may be in any order.
+	reversed := []image.Image{imgs[2], imgs[1], imgs[0]}
+
+	decoded, err := DecodeStructuredAppend(reversed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if decoded != content {
+		t.Errorf("got %q, expected %q", decoded, content)
+	}
+
+	plain, err := New("no header", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	replace := func(i int, img image.Image) []image.Image {
+		r := append([]image.Image{}, imgs...)
+		r[i] = img
+		return r
+	}
+
+	tests := []struct {
+		name string
+		imgs []image.Image
+	}{
+		{"no images", nil},
+		{"missing symbol", imgs[1:]},
+		{"duplicate symbol", replace(0, imgs[1])},
+		{"no header", replace(1, plain.Image(-3))},
+		{"undecodable image", replace(0, image.NewGray(image.Rect(0, 0, 50, 50)))},
+		{"mixed sequences", replace(0, structuredAppendImages(t, chunks, parity^1)[0])},
+		{"wrong parity", structuredAppendImages(t, chunks, parity^1)},
+	}
+
+	for _, test := range tests {
+		if _, err := DecodeStructuredAppend(test.imgs); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+	}
+}
+
+func TestQRCodeVerify(t *testing.T) {
+	q, err := New("https://example.org/verify", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := q.Verify(); err != nil {
+		t.Errorf("plain: %v", err)
+	}
+
+	q.ModuleShape = Circle
+	q.ModuleGap = 0.1
+	if err := q.Verify(); err == nil {
+		t.Errorf("styled finder patterns: got success, expected error")
+	}
+
+	q.SquareFinders = true
+	if err := q.Verify(); err != nil {
+		t.Errorf("styled: %v", err)
+	}
+	q.ModuleShape = Square
+	q.ModuleGap = 0
+
+	// Cover the bottom right quarter of the symbol, beyond the error
+	// correction capacity.
+	b := q.Image(-10).Bounds()
+	img := image.NewRGBA(b)
+	draw.Draw(img, b, q.Image(-10), b.Min, draw.Src)
+	damage := image.Rect(b.Dx()/2, b.Dy()/2, b.Max.X-40, b.Max.Y-40)
+	draw.Draw(img, damage, &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	if err := q.VerifyImage(img); err == nil {
+		t.Errorf("corrupted: got success, expected error")
+	}
+
+	// A symbol which decodes, to other content.
+	other, err := New("https://example.org/other", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := q.VerifyImage(other.Image(-10)); err == nil || !strings.Contains(err.Error(), "decoded content") {
+		t.Errorf("other content: got %v, expected content mismatch", err)
+	}
+
+	m, err := NewMicro("12345", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := m.Verify(); err == nil {
+		t.Errorf("Micro QR Code: got success, expected error")
+	}
+}
+
+func TestQRCodeVerifyImageWithLogo(t *testing.T) {
+	// A checkered logo, obscuring dark and light modules alike.
+	logo := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x/8+y/8)%2 == 0 {
+				logo.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+			} else {
+				logo.Set(x, y, color.White)
+			}
+		}
+	}
+
+	q, err := New("https://example.org/verify/logo", Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// The largest logo allowed, allowing for the square root rounding up.
+	scale := math.Nextafter(math.Sqrt(maxLogoCoverage(q.Level)), 0)
+	img, err := q.ImageWithLogo(-10, logo, scale)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := q.VerifyImage(img); err != nil {
+		t.Errorf("scale %.2f: %v", scale, err)
+	}
+}
+
+func TestDecodeNoQRCode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	if _, err := Decode(img); err == nil {
+		t.Errorf("blank image: got success, expected error")
+	}
+
+	draw.Draw(img, image.Rect(10, 10, 90, 90), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	if _, err := Decode(img); err == nil {
+		t.Errorf("black square: got success, expected error")
+	}
+}
@@ -5,7 +5,9 @@ package qrcode
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"unicode/utf8"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
 )
@@ -15,7 +17,7 @@ import (
 // The main data portion of a QR Code consists of one or more segments of data.
 // A segment consists of:
 //
-// - The segment Data Mode: numeric, alphanumeric, or byte.
+// - The segment Data Mode: numeric, alphanumeric, byte, or kanji.
 // - The length of segment in bits.
 // - Encoded data.
 //
@@ -33,10 +35,22 @@ import (
 //
 // Starting a new segment (to use a different Data Mode) has a cost, the bits to
 // state the new segment Data Mode and length. To minimise each QR Code's symbol
-// size, an optimisation routine coalesces segment types where possible, to
-// reduce the encoded data length.
+// size, an optimisation routine chooses the sequence of segments with the
+// shortest encoded data length.
 //
-// There are several other data modes available (e.g. Kanji mode) which are not
+// Kanji mode encodes Shift JIS double-byte characters at 13 bits each. It is
+// only considered when the content is not valid UTF-8, since UTF-8 encoded
+// Japanese text frequently contains byte pairs which are also valid Shift JIS.
+//
+// A Structured Append header may precede the segments when the content is
+// split across several linked symbols. It states the symbol's position in the
+// sequence, the number of symbols, and a parity byte of the complete content.
+//
+// An optional ECI (Extended Channel Interpretation) header may precede the
+// segments. It states the character set used to interpret byte mode data, e.g.
+// 26 for UTF-8, or 3 for ISO-8859-1.
+//
+// There are several other data modes available (e.g. FNC1 mode) which are not
 // implemented here.
 
 // A segment encoding mode.
@@ -53,6 +67,11 @@ const (
 	dataModeNumeric
 	dataModeAlphanumeric
 	dataModeByte
+
+	// dataModeKanji is the exception to the ordering above: Kanji characters
+	// can also be represented in dataModeByte, but no other characters can be
+	// represented in dataModeKanji. See widerDataMode().
+	dataModeKanji
 )
 
 // dataModeString returns d as a short printable string.
@@ -66,11 +85,32 @@ func dataModeString(d dataMode) string {
 		return "alphanumeric"
 	case dataModeByte:
 		return "byte"
+	case dataModeKanji:
+		return "kanji"
 	}
 
 	return "unknown"
 }
 
+// widerDataMode returns the narrowest dataMode able to represent data of both
+// dataMode a and dataMode b.
+func widerDataMode(a dataMode, b dataMode) dataMode {
+	switch {
+	case a == b:
+		return a
+	case a == dataModeNone:
+		return b
+	case b == dataModeNone:
+		return a
+	case a == dataModeKanji || b == dataModeKanji:
+		return dataModeByte
+	case a > b:
+		return a
+	}
+
+	return b
+}
+
 type dataEncoderType uint8
 
 const (
@@ -86,6 +126,10 @@ type segment struct {
 
 	// segment data (e.g. "abc").
 	data []byte
+
+	// Optional ECI assignment number, written immediately before the segment.
+	eci    uint32
+	hasECI bool
 }
 
 // A dataEncoder encodes data for a particular QR Code version.
@@ -98,15 +142,37 @@ type dataEncoder struct {
 	numericModeIndicator      *bitset.Bitset
 	alphanumericModeIndicator *bitset.Bitset
 	byteModeIndicator         *bitset.Bitset
+	kanjiModeIndicator        *bitset.Bitset
+	eciModeIndicator          *bitset.Bitset
+	structuredAppendIndicator *bitset.Bitset
 
 	// Character count lengths.
 	numNumericCharCountBits      int
 	numAlphanumericCharCountBits int
 	numByteCharCountBits         int
+	numKanjiCharCountBits        int
 
 	// The raw input data.
 	data []byte
 
+	// True if Shift JIS Kanji characters in data may use Kanji mode.
+	kanji bool
+
+	// Data mode to encode all data in, as a single segment, skipping data mode
+	// optimisation. Zero (the default) chooses the data modes automatically.
+	singleMode dataMode
+
+	// Segments to encode as is, skipping data mode optimisation, or nil to
+	// segment the data automatically.
+	segments []segment
+
+	// Optional ECI assignment number, written before the data segments.
+	eci    uint32
+	hasECI bool
+
+	// Optional Structured Append header, written first.
+	structuredAppend *structuredAppend
+
 	// The data classified into unoptimised segments.
 	actual []segment
 
@@ -126,9 +192,13 @@ func newDataEncoder(t dataEncoderType) *dataEncoder {
 			numericModeIndicator:         bitset.New(b0, b0, b0, b1),
 			alphanumericModeIndicator:    bitset.New(b0, b0, b1, b0),
 			byteModeIndicator:            bitset.New(b0, b1, b0, b0),
+			kanjiModeIndicator:           bitset.New(b1, b0, b0, b0),
+			eciModeIndicator:             bitset.New(b0, b1, b1, b1),
+			structuredAppendIndicator:    bitset.New(b0, b0, b1, b1),
 			numNumericCharCountBits:      10,
 			numAlphanumericCharCountBits: 9,
 			numByteCharCountBits:         8,
+			numKanjiCharCountBits:        8,
 		}
 	case dataEncoderType10To26:
 		d = &dataEncoder{
@@ -137,9 +207,13 @@ func newDataEncoder(t dataEncoderType) *dataEncoder {
 			numericModeIndicator:         bitset.New(b0, b0, b0, b1),
 			alphanumericModeIndicator:    bitset.New(b0, b0, b1, b0),
 			byteModeIndicator:            bitset.New(b0, b1, b0, b0),
+			kanjiModeIndicator:           bitset.New(b1, b0, b0, b0),
+			eciModeIndicator:             bitset.New(b0, b1, b1, b1),
+			structuredAppendIndicator:    bitset.New(b0, b0, b1, b1),
 			numNumericCharCountBits:      12,
 			numAlphanumericCharCountBits: 11,
 			numByteCharCountBits:         16,
+			numKanjiCharCountBits:        10,
 		}
 	case dataEncoderType27To40:
 		d = &dataEncoder{
@@ -148,9 +222,13 @@ func newDataEncoder(t dataEncoderType) *dataEncoder {
 			numericModeIndicator:         bitset.New(b0, b0, b0, b1),
 			alphanumericModeIndicator:    bitset.New(b0, b0, b1, b0),
 			byteModeIndicator:            bitset.New(b0, b1, b0, b0),
+			kanjiModeIndicator:           bitset.New(b1, b0, b0, b0),
+			eciModeIndicator:             bitset.New(b0, b1, b1, b1),
+			structuredAppendIndicator:    bitset.New(b0, b0, b1, b1),
 			numNumericCharCountBits:      14,
 			numAlphanumericCharCountBits: 13,
 			numByteCharCountBits:         16,
+			numKanjiCharCountBits:        12,
 		}
 	default:
 		log.Panic("Unknown dataEncoderType")
@@ -163,71 +241,132 @@ func newDataEncoder(t dataEncoderType) *dataEncoder {
 //
 // The returned data does not include the terminator bit sequence.
 func (d *dataEncoder) encode(data []byte) (*bitset.Bitset, error) {
-	d.data = data
-	d.actual = nil
-	d.optimised = nil
+	if err := d.segmentData(data); err != nil {
+		return nil, err
+	}
 
-	if len(data) == 0 {
-		return nil, errors.New("no data to encode")
+	// Encode data.
+	encoded := bitset.New()
+	if d.structuredAppend != nil {
+		d.encodeStructuredAppend(*d.structuredAppend, encoded)
 	}
 
-	// Classify data into unoptimised segments.
-	highestRequiredMode := d.classifyDataModes()
+	if d.hasECI {
+		err := d.encodeECI(d.eci, encoded)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// Optimise segments.
-	err := d.optimiseDataModes()
-	if err != nil {
-		return nil, err
+	for _, s := range d.optimised {
+		if s.hasECI {
+			if err := d.encodeECI(s.eci, encoded); err != nil {
+				return nil, err
+			}
+		}
+
+		d.encodeDataRaw(s.data, s.dataMode, encoded)
+	}
+
+	return encoded, nil
+}
+
+// encodedBits returns the length in bits of the data returned by encode(),
+// without encoding it.
+func (d *dataEncoder) encodedBits(data []byte) (int, error) {
+	if err := d.segmentData(data); err != nil {
+		return 0, err
+	}
+
+	headers := bitset.New()
+	if d.structuredAppend != nil {
+		d.encodeStructuredAppend(*d.structuredAppend, headers)
+	}
+
+	if d.hasECI {
+		if err := d.encodeECI(d.eci, headers); err != nil {
+			return 0, err
+		}
 	}
 
-	// Check if a single byte encoded segment would be more efficient.
-	optimizedLength := 0
+	for _, s := range d.optimised {
+		if s.hasECI {
+			if err := d.encodeECI(s.eci, headers); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	numBits := headers.Len()
 	for _, s := range d.optimised {
 		length, err := d.encodedLength(s.dataMode, len(s.data))
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-		optimizedLength += length
+		numBits += length
 	}
 
-	singleByteSegmentLength, err := d.encodedLength(highestRequiredMode, len(d.data))
-	if err != nil {
-		return nil, err
-	}
+	return numBits, nil
+}
 
-	if singleByteSegmentLength <= optimizedLength {
-		d.optimised = []segment{segment{dataMode: highestRequiredMode, data: d.data}}
+// segmentData splits data into the segments to encode, d.optimised.
+func (d *dataEncoder) segmentData(data []byte) error {
+	d.data = data
+	d.kanji = d.singleMode == 0 && d.segments == nil && !d.hasECI && !utf8.Valid(data)
+	d.actual = nil
+	d.optimised = nil
+
+	if len(data) == 0 {
+		return errors.New("no data to encode")
 	}
 
-	// Encode data.
-	encoded := bitset.New()
-	for _, s := range d.optimised {
-		d.encodeDataRaw(s.data, s.dataMode, encoded)
+	switch {
+	case d.segments != nil:
+		for _, s := range d.segments {
+			if d.segmentLength(s) > d.maxSegmentLength(s.dataMode) {
+				return ErrContentTooLong
+			}
+		}
+
+		d.optimised = d.segments
+	case d.singleMode != 0:
+		if len(data) > d.maxSegmentLength(d.singleMode) {
+			return ErrContentTooLong
+		}
+
+		d.optimised = []segment{{dataMode: d.singleMode, data: data}}
+	default:
+		// Classify data into unoptimised segments.
+		d.classifyDataModes()
+
+		// Optimise segments.
+		if err := d.optimiseDataModes(); err != nil {
+			return err
+		}
 	}
 
-	return encoded, nil
+	return nil
 }
 
 // classifyDataModes classifies the raw data into unoptimised segments.
 // e.g. "123ZZ#!#!" =>
 // [numeric, 3, "123"] [alphanumeric, 2, "ZZ"] [byte, 4, "#!#!"].
 //
-// Returns the highest data mode needed to encode the data. e.g. for a mixed
-// numeric/alphanumeric input, the highest is alphanumeric.
-//
-// dataModeNone < dataModeNumeric < dataModeAlphanumeric < dataModeByte
-func (d *dataEncoder) classifyDataModes() dataMode {
+// Each character is classified in the narrowest data mode able to represent
+// it.
+func (d *dataEncoder) classifyDataModes() {
 	var start int
 	mode := dataModeNone
-	highestRequiredMode := mode
 
-	for i, v := range d.data {
+	for i := 0; i < len(d.data); i++ {
+		v := d.data[i]
 		newMode := dataModeNone
 		switch {
-		case v >= 0x30 && v <= 0x39:
+		case d.kanji && i+1 < len(d.data) && isKanjiCharacter(v, d.data[i+1]):
+			newMode = dataModeKanji
+		case isNumericCharacter(v):
 			newMode = dataModeNumeric
-		case v == 0x20 || v == 0x24 || v == 0x25 || v == 0x2a || v == 0x2b || v ==
-			0x2d || v == 0x2e || v == 0x2f || v == 0x3a || (v >= 0x41 && v <= 0x5a):
+		case isAlphanumericCharacter(v):
 			newMode = dataModeAlphanumeric
 		default:
 			newMode = dataModeByte
@@ -243,80 +382,157 @@ func (d *dataEncoder) classifyDataModes() dataMode {
 			mode = newMode
 		}
 
-		if newMode > highestRequiredMode {
-			highestRequiredMode = newMode
+		if newMode == dataModeKanji {
+			// Kanji characters are two bytes long.
+			i++
 		}
 	}
 
 	d.actual = append(d.actual, segment{dataMode: mode, data: d.data[start:len(d.data)]})
-
-	return highestRequiredMode
 }
 
-// optimiseDataModes optimises the list of segments to reduce the overall output
-// encoded data length.
+// optimiseDataModes optimises the list of segments to minimise the overall
+// output encoded data length.
 //
-// The algorithm coalesces adjacent segments. segments are only coalesced when
-// the Data Modes are compatible, and when the coalesced segment has a shorter
-// encoded length than separate segments.
+// Each character may be encoded in its classified data mode, or any wider data
+// mode, but starting a new segment costs the segment header bits. The optimal
+// sequence of segments is found by dynamic programming (ISO/IEC 18004:2006
+// Annex J): For each character in turn, the shortest encoding of the data so
+// far is tracked for each data mode the character may end in.
 //
-// Multiple segments may be coalesced. For example a string of alternating
-// alphanumeric/numeric segments ANANANANA can be optimised to just A.
+// For example a string of alternating alphanumeric/numeric segments ANANANANA
+// is optimised to just A, whereas a long run of digits within alphanumeric
+// data is given its own numeric segment.
+//
+// Segments too long for the character count field are split.
 func (d *dataEncoder) optimiseDataModes() error {
-	for i := 0; i < len(d.actual); {
-		mode := d.actual[i].dataMode
-		numChars := len(d.actual[i].data)
+	modes := [...]dataMode{dataModeNumeric, dataModeAlphanumeric, dataModeByte, dataModeKanji}
 
-		j := i + 1
-		for j < len(d.actual) {
-			nextNumChars := len(d.actual[j].data)
-			nextMode := d.actual[j].dataMode
+	// Segment header length of each data mode, or -1 if unavailable.
+	var headerLength [len(modes)]int
+	for j, mode := range modes {
+		length, err := d.encodedLength(mode, 0)
+		if err != nil {
+			length = -1
+		}
+		headerLength[j] = length
+	}
 
-			if nextMode > mode {
-				break
-			}
+	// Split the segments into characters. Kanji characters are two bytes long.
+	var chars []segment
+	for _, s := range d.actual {
+		n := 1
+		if s.dataMode == dataModeKanji {
+			n = 2
+		}
 
-			coalescedLength, err := d.encodedLength(mode, numChars+nextNumChars)
+		for i := 0; i < len(s.data); i += n {
+			chars = append(chars, segment{dataMode: s.dataMode, data: s.data[i : i+n]})
+		}
+	}
 
-			if err != nil {
-				return err
-			}
+	// cost[k][j] is the shortest encoded length of the first k characters,
+	// ending in a segment of modes[j], or -1 if impossible. Lengths are in
+	// sixths of a bit, since numeric and alphanumeric characters are 3 1/3
+	// and 5 1/2 bits long. from[k][j] is the data mode of the character
+	// before.
+	cost := make([][len(modes)]int, len(chars)+1)
+	from := make([][len(modes)]int, len(chars)+1)
 
-			seperateLength1, err := d.encodedLength(mode, numChars)
+	for k, c := range chars {
+		for j, mode := range modes {
+			cost[k+1][j] = -1
 
-			if err != nil {
-				return err
+			if headerLength[j] < 0 || widerDataMode(c.dataMode, mode) != mode {
+				continue
 			}
 
-			seperateLength2, err := d.encodedLength(nextMode, nextNumChars)
+			var charLength int
+			switch mode {
+			case dataModeNumeric:
+				charLength = 20
+			case dataModeAlphanumeric:
+				charLength = 33
+			case dataModeByte:
+				charLength = 48 * len(c.data)
+			case dataModeKanji:
+				charLength = 78
+			}
 
-			if err != nil {
-				return err
+			if k == 0 {
+				cost[1][j] = headerLength[j]*6 + charLength
+				continue
 			}
 
-			if coalescedLength < seperateLength1+seperateLength2 {
-				j++
-				numChars += nextNumChars
-			} else {
-				break
+			for i := range modes {
+				length := cost[k][i]
+				if length < 0 {
+					continue
+				}
+
+				// A new segment starts on a whole bit.
+				if i != j {
+					length = (length+5)/6*6 + headerLength[j]*6
+				}
+				length += charLength
+
+				if cost[k+1][j] < 0 || length < cost[k+1][j] {
+					cost[k+1][j] = length
+					from[k+1][j] = i
+				}
 			}
 		}
+	}
 
-		optimised := segment{dataMode: mode,
-			data: make([]byte, 0, numChars)}
-
-		for k := i; k < j; k++ {
-			optimised.data = append(optimised.data, d.actual[k].data...)
+	best := -1
+	for j := range modes {
+		if cost[len(chars)][j] >= 0 && (best < 0 || cost[len(chars)][j] < cost[len(chars)][best]) {
+			best = j
 		}
+	}
 
-		d.optimised = append(d.optimised, optimised)
+	if best < 0 {
+		return errors.New("data cannot be encoded in the available data modes")
+	}
 
-		i = j
+	// Trace back the data mode of each character.
+	charModes := make([]dataMode, len(chars))
+	for k, j := len(chars), best; k > 0; k-- {
+		charModes[k-1] = modes[j]
+		j = from[k][j]
+	}
+
+	d.optimised = nil
+	for k, c := range chars {
+		mode := charModes[k]
+
+		if n := len(d.optimised); n > 0 && d.optimised[n-1].dataMode == mode &&
+			d.segmentLength(d.optimised[n-1]) < d.maxSegmentLength(mode) {
+			d.optimised[n-1].data = append(d.optimised[n-1].data, c.data...)
+			continue
+		}
+
+		d.optimised = append(d.optimised, segment{dataMode: mode, data: append([]byte{}, c.data...)})
 	}
 
 	return nil
 }
 
+// segmentLength returns the number of characters in segment s.
+func (d *dataEncoder) segmentLength(s segment) int {
+	if s.dataMode == dataModeKanji {
+		return len(s.data) / 2
+	}
+
+	return len(s.data)
+}
+
+// maxSegmentLength returns the maximum number of characters in a segment of
+// type dataMode, as limited by the character count field.
+func (d *dataEncoder) maxSegmentLength(dataMode dataMode) int {
+	return (1 << uint8(d.charCountBits(dataMode))) - 1
+}
+
 // encodeDataRaw encodes data in dataMode. The encoded data is appended to
 // encoded.
 func (d *dataEncoder) encodeDataRaw(data []byte, dataMode dataMode, encoded *bitset.Bitset) {
@@ -327,7 +543,11 @@ func (d *dataEncoder) encodeDataRaw(data []byte, dataMode dataMode, encoded *bit
 	encoded.Append(modeIndicator)
 
 	// Append character count.
-	encoded.AppendUint32(uint32(len(data)), charCountBits)
+	numChars := len(data)
+	if dataMode == dataModeKanji {
+		numChars /= 2
+	}
+	encoded.AppendUint32(uint32(numChars), charCountBits)
 
 	// Append data.
 	switch dataMode {
@@ -366,9 +586,60 @@ func (d *dataEncoder) encodeDataRaw(data []byte, dataMode dataMode, encoded *bit
 		for _, b := range data {
 			encoded.AppendByte(b, 8)
 		}
+	case dataModeKanji:
+		for i := 0; i+1 < len(data); i += 2 {
+			encoded.AppendUint32(encodeKanjiCharacter(data[i], data[i+1]), 13)
+		}
 	}
 }
 
+// structuredAppend is the Structured Append header of a single symbol in a
+// sequence of linked symbols.
+type structuredAppend struct {
+	// Position of the symbol in the sequence (0-15 inclusive).
+	index int
+
+	// Total number of symbols in the sequence (1-16 inclusive).
+	total int
+
+	// XOR of every byte of the complete content.
+	parity byte
+}
+
+// encodeStructuredAppend encodes the Structured Append header s. The encoded
+// header is appended to encoded.
+func (d *dataEncoder) encodeStructuredAppend(s structuredAppend, encoded *bitset.Bitset) {
+	encoded.Append(d.structuredAppendIndicator)
+	encoded.AppendUint32(uint32(s.index), 4)
+	encoded.AppendUint32(uint32(s.total-1), 4)
+	encoded.AppendByte(s.parity, 8)
+}
+
+// encodeECI encodes an ECI header for the ECI assignment number eci. The
+// encoded header is appended to encoded.
+//
+// The ECI designator is 8, 16 or 24 bits long, depending on the value of eci.
+// An error is returned if eci is larger than 999999.
+func (d *dataEncoder) encodeECI(eci uint32, encoded *bitset.Bitset) error {
+	encoded.Append(d.eciModeIndicator)
+
+	switch {
+	case eci <= 127:
+		// 0bbbbbbb
+		encoded.AppendUint32(eci, 8)
+	case eci <= 16383:
+		// 10bbbbbb bbbbbbbb
+		encoded.AppendUint32(0x8000|eci, 16)
+	case eci <= 999999:
+		// 110bbbbb bbbbbbbb bbbbbbbb
+		encoded.AppendUint32(0xc00000|eci, 24)
+	default:
+		return fmt.Errorf("invalid ECI assignment number %d (expected 0-999999 inclusive)", eci)
+	}
+
+	return nil
+}
+
 // modeIndicator returns the segment header bits for a segment of type dataMode.
 func (d *dataEncoder) modeIndicator(dataMode dataMode) *bitset.Bitset {
 	switch dataMode {
@@ -378,6 +649,8 @@ func (d *dataEncoder) modeIndicator(dataMode dataMode) *bitset.Bitset {
 		return d.alphanumericModeIndicator
 	case dataModeByte:
 		return d.byteModeIndicator
+	case dataModeKanji:
+		return d.kanjiModeIndicator
 	default:
 		log.Panic("Unknown data mode")
 	}
@@ -395,6 +668,8 @@ func (d *dataEncoder) charCountBits(dataMode dataMode) int {
 		return d.numAlphanumericCharCountBits
 	case dataModeByte:
 		return d.numByteCharCountBits
+	case dataModeKanji:
+		return d.numKanjiCharCountBits
 	default:
 		log.Panic("Unknown data mode")
 	}
@@ -402,8 +677,8 @@ func (d *dataEncoder) charCountBits(dataMode dataMode) int {
 	return 0
 }
 
-// encodedLength returns the number of bits required to encode n symbols in
-// dataMode.
+// encodedLength returns the number of bits required to encode n bytes of data
+// in dataMode. Each Kanji mode character is two bytes long.
 //
 // The number of bits required is affected by:
 //	- QR code type - Mode Indicator length.
@@ -423,7 +698,12 @@ func (d *dataEncoder) encodedLength(dataMode dataMode, n int) (int, error) {
 
 	maxLength := (1 << uint8(charCountBits)) - 1
 
-	if n > maxLength {
+	numChars := n
+	if dataMode == dataModeKanji {
+		numChars = n / 2
+	}
+
+	if numChars > maxLength {
 		return 0, errors.New("length too long to be represented")
 	}
 
@@ -441,11 +721,25 @@ func (d *dataEncoder) encodedLength(dataMode dataMode, n int) (int, error) {
 		length += 6 * (n % 2)
 	case dataModeByte:
 		length += 8 * n
+	case dataModeKanji:
+		length += 13 * numChars
 	}
 
 	return length, nil
 }
 
+// isNumericCharacter returns true if v is a digit, encodable in numeric mode.
+func isNumericCharacter(v byte) bool {
+	return v >= 0x30 && v <= 0x39
+}
+
+// isAlphanumericCharacter returns true if v is encodable in alphanumeric mode,
+// but not in numeric mode.
+func isAlphanumericCharacter(v byte) bool {
+	return v == 0x20 || v == 0x24 || v == 0x25 || v == 0x2a || v == 0x2b || v ==
+		0x2d || v == 0x2e || v == 0x2f || v == 0x3a || (v >= 0x41 && v <= 0x5a)
+}
+
 // encodeAlphanumericChar returns the QR Code encoded value of v.
 //
 // v must be a QR Code defined alphanumeric character: 0-9, A-Z, SP, $%*+-./ or
@@ -484,3 +778,42 @@ func encodeAlphanumericCharacter(v byte) uint32 {
 
 	return 0
 }
+
+// isKanjiCharacter returns true if the byte pair (a, b) is a Shift JIS double
+// byte character encodable in Kanji mode. These are the characters in the
+// ranges 0x8140-0x9ffc and 0xe040-0xebbf.
+func isKanjiCharacter(a byte, b byte) bool {
+	if b < 0x40 || b > 0xfc || b == 0x7f {
+		return false
+	}
+
+	switch {
+	case a >= 0x81 && a <= 0x9f:
+		return true
+	case a >= 0xe0 && a <= 0xea:
+		return true
+	case a == 0xeb:
+		return b <= 0xbf
+	}
+
+	return false
+}
+
+// encodeKanjiCharacter returns the QR Code encoded 13-bit value of the Shift
+// JIS character (a, b).
+//
+// (a, b) must be a Kanji mode character, see isKanjiCharacter().
+func encodeKanjiCharacter(a byte, b byte) uint32 {
+	c := uint32(a)<<8 | uint32(b)
+
+	switch {
+	case c >= 0x8140 && c <= 0x9ffc:
+		c -= 0x8140
+	case c >= 0xe040 && c <= 0xebbf:
+		c -= 0xc140
+	default:
+		log.Panicf("encodeKanjiCharacter() with non kanji char %#x.", c)
+	}
+
+	return (c>>8)*0xc0 + c&0xff
+}
@@ -6,6 +6,7 @@ package qrcode
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
@@ -23,8 +24,8 @@ func TestClassifyDataMode(t *testing.T) {
 			[]byte{0x30},
 			[]segment{
 				{
-					dataModeNumeric,
-					[]byte{0x30},
+					dataMode: dataModeNumeric,
+					data:     []byte{0x30},
 				},
 			},
 		},
@@ -32,20 +33,20 @@ func TestClassifyDataMode(t *testing.T) {
 			[]byte{0x30, 0x41, 0x42, 0x43, 0x20, 0x00, 0xf0, 0xf1, 0xf2, 0x31},
 			[]segment{
 				{
-					dataModeNumeric,
-					[]byte{0x30},
+					dataMode: dataModeNumeric,
+					data:     []byte{0x30},
 				},
 				{
-					dataModeAlphanumeric,
-					[]byte{0x41, 0x42, 0x43, 0x20},
+					dataMode: dataModeAlphanumeric,
+					data:     []byte{0x41, 0x42, 0x43, 0x20},
 				},
 				{
-					dataModeByte,
-					[]byte{0x00, 0xf0, 0xf1, 0xf2},
+					dataMode: dataModeByte,
+					data:     []byte{0x00, 0xf0, 0xf1, 0xf2},
 				},
 				{
-					dataModeNumeric,
-					[]byte{0x31},
+					dataMode: dataModeNumeric,
+					data:     []byte{0x31},
 				},
 			},
 		},
@@ -123,6 +124,13 @@ func TestSingleModeEncodings(t *testing.T) {
 			"123",
 			bitset.NewFromBase2String("0100 00000000 00000011 00110001 00110010 00110011"),
 		},
+		// ISO/IEC 18004:2006 Section 6.4.6 example, "点茗" in Shift JIS.
+		{
+			dataEncoderType1To9,
+			dataModeKanji,
+			"\x93\x5f\xe4\xaa",
+			bitset.NewFromBase2String("1000 00000010 0110110011111 1101010101010"),
+		},
 	}
 
 	for _, test := range tests {
@@ -222,7 +230,8 @@ func TestOptimiseEncoding(t *testing.T) {
 		},
 		// https://www.google.com/123
 		// BBBBBAAABBBABBBBBBABBBANNN
-		// Small segments are inefficient because of additional metadata.
+		// Small segments are inefficient because of additional metadata, but
+		// the trailing alphanumeric characters are worth a segment.
 		{
 			dataEncoderType1To9,
 			[]testModeSegment{
@@ -236,7 +245,9 @@ func TestOptimiseEncoding(t *testing.T) {
 				{dataModeNumeric, 3},
 			},
 			[]testModeSegment{
-				{dataModeByte, 26},
+				{dataModeByte, 18},        // length = 4 + 8 + 144 = 156.
+				{dataModeAlphanumeric, 8}, // length = 4 + 9 + 44 = 57.
+				// total = 213 bits, a single byte segment is 220 bits.
 			},
 		},
 		// HTTPS://WWW.GOOGLE.COM/123
@@ -408,3 +419,256 @@ func segmentsString(segments []segment) string {
 
 	return result
 }
+
+func TestClassifyKanjiDataMode(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		actual []segment
+	}{
+		// Shift JIS "点茗" followed by "AB", then an incomplete double byte
+		// character.
+		{
+			[]byte{0x93, 0x5f, 0xe4, 0xaa, 0x41, 0x42, 0x93},
+			[]segment{
+				{
+					dataMode: dataModeKanji,
+					data:     []byte{0x93, 0x5f, 0xe4, 0xaa},
+				},
+				{
+					dataMode: dataModeAlphanumeric,
+					data:     []byte{0x41, 0x42},
+				},
+				{
+					dataMode: dataModeByte,
+					data:     []byte{0x93},
+				},
+			},
+		},
+		// UTF-8 "あ" (0xe3 0x81 0x82) is a valid Shift JIS pair followed by 0x82,
+		// but valid UTF-8 content never uses Kanji mode.
+		{
+			[]byte("あ"),
+			[]segment{
+				{
+					dataMode: dataModeByte,
+					data:     []byte("あ"),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		encoder := newDataEncoder(dataEncoderType1To9)
+		encoder.encode(test.data)
+
+		if !reflect.DeepEqual(test.actual, encoder.actual) {
+			t.Errorf("Got %v, expected %v", encoder.actual, test.actual)
+		}
+	}
+}
+
+func TestKanjiCapacity(t *testing.T) {
+	// "点" in Shift JIS.
+	kanji := strings.Repeat("\x93\x5f", 100)
+
+	k, err := New(kanji, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if k.encoder.optimised[0].dataMode != dataModeKanji {
+		t.Errorf("got %s, expected kanji mode",
+			dataModeString(k.encoder.optimised[0].dataMode))
+	}
+
+	b, err := New(strings.Repeat("#", len(kanji)), Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if k.VersionNumber >= b.VersionNumber {
+		t.Errorf("100 kanji got version %d, expected smaller than byte mode version %d",
+			k.VersionNumber, b.VersionNumber)
+	}
+}
+
+func TestECIEncodings(t *testing.T) {
+	tests := []struct {
+		eci      uint32
+		expected *bitset.Bitset
+	}{
+		{
+			3,
+			bitset.NewFromBase2String("0111 00000011"),
+		},
+		{
+			26,
+			bitset.NewFromBase2String("0111 00011010"),
+		},
+		{
+			9000,
+			bitset.NewFromBase2String("0111 10100011 00101000"),
+		},
+		{
+			999999,
+			bitset.NewFromBase2String("0111 11001111 01000010 00111111"),
+		},
+	}
+
+	for _, test := range tests {
+		encoder := newDataEncoder(dataEncoderType1To9)
+		encoded := bitset.New()
+
+		if err := encoder.encodeECI(test.eci, encoded); err != nil {
+			t.Errorf("ECI %d: got error %s", test.eci, err.Error())
+			continue
+		}
+
+		if !test.expected.Equals(encoded) {
+			t.Errorf("ECI %d: got %s, expected %s", test.eci, encoded.String(),
+				test.expected.String())
+		}
+	}
+
+	encoder := newDataEncoder(dataEncoderType1To9)
+	if err := encoder.encodeECI(1000000, bitset.New()); err == nil {
+		t.Errorf("ECI 1000000: got success, expected error")
+	}
+}
+
+func TestEncodedBits(t *testing.T) {
+	for _, content := range []string{"1", "HELLO 123", "hello", "0123456789012345ABCDEFabc", "\x93\xfa\x96\x7b"} {
+		for _, opts := range []encodeOptions{
+			{},
+			{eci: 26, hasECI: true},
+			{structuredAppend: &structuredAppend{index: 1, total: 3, parity: 0x55}},
+			{singleMode: dataModeByte},
+		} {
+			d := newDataEncoder(dataEncoderType1To9)
+			opts.apply(d)
+
+			encoded, err := d.encode([]byte(content))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			numBits, err := d.encodedBits([]byte(content))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if numBits != encoded.Len() {
+				t.Errorf("%q: got %d bits, expected %d", content, numBits, encoded.Len())
+			}
+		}
+	}
+}
+
+func TestNewWithECI(t *testing.T) {
+	q, err := NewWithECI("123", Low, 26)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := bitset.NewFromBase2String("0111 00011010 0001 0000000011 0001111011")
+	if !expected.Equals(q.data) {
+		t.Errorf("got %s, expected %s", q.data.String(), expected.String())
+	}
+
+	// Latin-1 byte pairs are never treated as Shift JIS when an ECI is given.
+	q, err = NewWithECI("\xe9A", Low, 3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.encoder.optimised[0].dataMode != dataModeByte {
+		t.Errorf("got %s, expected byte mode",
+			dataModeString(q.encoder.optimised[0].dataMode))
+	}
+
+	if _, err := NewWithECI("123", Low, 1000000); err == nil {
+		t.Errorf("got success, expected invalid ECI error")
+	}
+}
+
+func TestOptimalSegmentation(t *testing.T) {
+	tests := []struct {
+		content         string
+		level           RecoveryLevel
+		previousVersion int // With the previous segment coalescing algorithm.
+		expectedVersion int
+		expectedBits    int
+	}{
+		{"Order 12345678901234567890123456789012 shipped", Low, 3, 2, 257},
+		{"Invoice 4711: EUR 1234567890.00 paid", Low, 3, 2, 263},
+		{"Serial ABCDEFGHIJKLMNOPQRSTUV lot 123456789012345", Low, 3, 3, 313},
+		{"ID: 12345678901234567890123456789012345678901234567890 ok", Medium, 3, 3, 252},
+		{"HTTP://EXAMPLE.COM/123456", Low, 1, 1, 151},
+	}
+
+	for _, test := range tests {
+		q, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if q.VersionNumber != test.expectedVersion || q.data.Len() != test.expectedBits {
+			t.Errorf("%q: got version %d (%d bits), expected version %d (%d bits), previously version %d",
+				test.content, q.VersionNumber, q.data.Len(), test.expectedVersion, test.expectedBits,
+				test.previousVersion)
+		}
+
+		if s, err := Decode(q.Image(-3)); err != nil || s != test.content {
+			t.Errorf("%q: decode got %q, %v", test.content, s, err)
+		}
+	}
+}
+
+func TestOptimalSegmentationLength(t *testing.T) {
+	alphabet := "0123456789ABCXYZ $%*+-./:abc#=?"
+
+	// Deterministic pseudo random content.
+	seed := uint32(1)
+	next := func() int {
+		seed = seed*1664525 + 1013904223
+		return int(seed >> 16)
+	}
+
+	for i := 0; i < 500; i++ {
+		data := make([]byte, 1+next()%60)
+		for j := range data {
+			// Runs of characters from a narrow or wide alphabet.
+			data[j] = alphabet[next()%(10+next()%(len(alphabet)-9))]
+		}
+
+		encoder := newDataEncoder(dataEncoderType1To9)
+		encoded, err := encoder.encode(data)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// No worse than a single byte mode segment, or the classified
+		// segments.
+		single, _ := encoder.encodedLength(dataModeByte, len(data))
+
+		classified := 0
+		for _, s := range encoder.actual {
+			length, _ := encoder.encodedLength(s.dataMode, len(s.data))
+			classified += length
+		}
+
+		if encoded.Len() > single || encoded.Len() > classified {
+			t.Errorf("%q: got %d bits, single segment %d, classified segments %d",
+				data, encoded.Len(), single, classified)
+		}
+
+		// The segments reassemble to the data.
+		var joined []byte
+		for _, s := range encoder.optimised {
+			joined = append(joined, s.data...)
+		}
+		if string(joined) != string(data) {
+			t.Errorf("%q: segments reassemble to %q", data, joined)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"unicode"
+)
+
+func TestGlyphs(t *testing.T) {
+	for r, glyph := range glyphs {
+		if unicode.IsLower(r) {
+			t.Errorf("%q: lower case glyphs are unused", r)
+		}
+
+		for y, row := range glyph {
+			if len(row) != glyphWidth {
+				t.Errorf("%q row %d: got width %d, expected %d", r, y, len(row), glyphWidth)
+			}
+		}
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	const scale = 2
+
+	text := "1/4"
+	w := textWidth(text, scale)
+	if w != (3*glyphWidth+2*glyphSpacing)*scale {
+		t.Errorf("got text width %d", w)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, glyphHeight*scale))
+	drawText(img, "1/4", image.Point{}, scale, &image.Uniform{color.Black})
+
+	// The first glyph pixel set in '1' is at (2, 0).
+	for _, p := range []image.Point{{2 * scale, 0}, {2*scale + 1, 1}} {
+		if _, _, _, a := img.At(p.X, p.Y).RGBA(); a != 0xffff {
+			t.Errorf("pixel %v not set", p)
+		}
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("pixel (0, 0) set")
+	}
+}
@@ -0,0 +1,126 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// GIF returns the QR Code as a GIF image.
+//
+// The image has a two colour palette of the BackgroundColor and
+// ForegroundColor (plus the BorderColor and FinderColor, if set). size is both
+// the image width and height in pixels. If size is too small then a larger
+// image is silently returned, or an error if StrictSize is set. Negative values
+// for size cause a variable sized image to be returned: See the documentation
+// for Image().
+func (q *QRCode) GIF(size int) ([]byte, error) {
+	var b bytes.Buffer
+	err := q.WriteGIF(size, &b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteGIF writes the QR Code as a GIF image to io.Writer.
+//
+// size is both the image width and height in pixels. If size is too small then
+// a larger image is silently written. Negative values for size cause a
+// variable sized image to be written: See the documentation for Image().
+func (q *QRCode) WriteGIF(size int, out io.Writer) error {
+	if err := q.checkImage(size); err != nil {
+		return err
+	}
+
+	return gif.Encode(out, q.palettedImage(size), nil)
+}
+
+// palettedImage returns the QR Code as an image with a two colour palette of
+// the BackgroundColor and ForegroundColor, in that order, and the BorderColor
+// and FinderColor if set.
+func (q *QRCode) palettedImage(size int) *image.Paletted {
+	img := q.Image(size)
+
+	if p, ok := img.(*image.Paletted); ok {
+		return p
+	}
+
+	// Translucent colours produce a full colour image, which only contains the
+	// two palette colours.
+	p := image.NewPaletted(img.Bounds(), q.palette())
+	draw.Draw(p, p.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return p
+}
+
+// AnimatedGIF returns a looping animated GIF image showing each of the QR Codes
+// in turn, e.g. to display the output of EncodeMulti() on a single screen.
+//
+// Each frame is shown for delayHundredths hundredths of a second. All frames
+// are size x size pixels: The QR Codes are drawn with the same module size,
+// sized so that the largest QR Code fills the frame, and centred. A negative
+// size sets the module size in pixels instead, as for Image().
+//
+// An error occurs if codes is empty.
+func AnimatedGIF(codes []*QRCode, size int, delayHundredths int) ([]byte, error) {
+	if len(codes) == 0 {
+		return nil, errors.New("no QR Codes to animate")
+	}
+
+	bitmaps := make([][][]bool, len(codes))
+	maxModules := 0
+	for i, q := range codes {
+		bitmaps[i] = q.Bitmap()
+		maxModules = max(maxModules, len(bitmaps[i]))
+	}
+
+	var pitch int
+	if size < 0 {
+		pitch = -size
+	} else {
+		pitch = max(1, size/maxModules)
+	}
+	size = max(size, pitch*maxModules)
+
+	anim := &gif.GIF{LoopCount: 0}
+
+	for i, q := range codes {
+		p := color.Palette{q.BackgroundColor, q.ForegroundColor}
+		frame := image.NewPaletted(image.Rect(0, 0, size, size), p)
+
+		offset := (size - len(bitmaps[i])*pitch) / 2
+		for y, row := range bitmaps[i] {
+			for x, v := range row {
+				if !v {
+					continue
+				}
+
+				module := image.Rect(offset+x*pitch, offset+y*pitch,
+					offset+(x+1)*pitch, offset+(y+1)*pitch)
+				draw.Draw(frame, module, &image.Uniform{q.ForegroundColor}, image.Point{}, draw.Src)
+			}
+		}
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delayHundredths)
+	}
+
+	var b bytes.Buffer
+	err := gif.EncodeAll(&b, anim)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+func TestGIF(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, bg := range []color.Color{color.White, color.NRGBA{0xff, 0xff, 0xff, 0x00}} {
+		q.BackgroundColor = bg
+
+		data, err := q.GIF(-10)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		img, err := gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		size := len(q.Bitmap()) * 10
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("got size %v, expected %dx%d", b.Size(), size, size)
+		}
+
+		p, ok := img.ColorModel().(color.Palette)
+		if !ok || len(p) != 2 {
+			t.Fatalf("got color model %v, expected a two colour palette", img.ColorModel())
+		}
+
+		// The corner pixel is in the quiet zone, and the top left finder
+		// pattern is dark.
+		border := q.symbol.quietZoneSize * 10
+		if c := img.At(0, 0); c != p[0] {
+			t.Errorf("got quiet zone colour %v, expected %v", c, p[0])
+		}
+		if c := img.At(border+1, border+1); c != p[1] {
+			t.Errorf("got finder pattern colour %v, expected %v", c, p[1])
+		}
+	}
+
+	var b bytes.Buffer
+	if err := q.WriteGIF(2048, &b); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	config, err := gif.DecodeConfig(&b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if config.Width != 2048 || config.Height != 2048 {
+		t.Errorf("got size %dx%d, expected 2048x2048", config.Width, config.Height)
+	}
+}
+
+func TestAnimatedGIF(t *testing.T) {
+	contents := []string{"short", strings.Repeat("longer content ", 10), "third"}
+
+	var codes []*QRCode
+	for _, content := range contents {
+		q, err := New(content, Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		codes = append(codes, q)
+	}
+
+	data, err := AnimatedGIF(codes, 600, 150)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(anim.Image) != len(codes) {
+		t.Fatalf("got %d frames, expected %d", len(anim.Image), len(codes))
+	}
+
+	if anim.LoopCount != 0 {
+		t.Errorf("got loop count %d, expected 0 (infinite)", anim.LoopCount)
+	}
+
+	for i, frame := range anim.Image {
+		if anim.Delay[i] != 150 {
+			t.Errorf("frame %d: got delay %d, expected 150", i, anim.Delay[i])
+		}
+
+		if b := frame.Bounds(); b.Dx() != 600 || b.Dy() != 600 {
+			t.Errorf("frame %d: got size %v, expected 600x600", i, b.Size())
+		}
+
+		s, err := Decode(frame)
+		if err != nil {
+			t.Errorf("frame %d: decode failed: %s", i, err.Error())
+		} else if s != contents[i] {
+			t.Errorf("frame %d: decoded %q, expected %q", i, s, contents[i])
+		}
+	}
+
+	if _, err := AnimatedGIF(nil, 600, 150); err == nil {
+		t.Errorf("no codes: got success, expected error")
+	}
+}
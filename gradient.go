@@ -0,0 +1,105 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+)
+
+// A GradientDirection is the direction of a Gradient.
+type GradientDirection int
+
+const (
+	// Vertical gradients run from the top edge to the bottom edge.
+	Vertical GradientDirection = iota
+
+	// Horizontal gradients run from the left edge to the right edge.
+	Horizontal
+
+	// Diagonal gradients run from the top left corner to the bottom right
+	// corner.
+	Diagonal
+)
+
+// A Gradient is a linear gradient fill of the dark modules of a QR Code. The
+// gradient spans the QR Code symbol, excluding the quiet zone: The first
+// module in the Direction is drawn in the Start colour, and the last in the End
+// colour.
+type Gradient struct {
+	Start     color.Color
+	End       color.Color
+	Direction GradientDirection
+}
+
+// at returns the gradient's colour at the module (x, y) of a symbol of size x
+// size modules.
+func (g *Gradient) at(x int, y int, size int) color.NRGBA {
+	var t float64
+	if last := float64(size - 1); last > 0 {
+		switch g.Direction {
+		case Vertical:
+			t = float64(y) / last
+		case Horizontal:
+			t = float64(x) / last
+		case Diagonal:
+			t = float64(x+y) / (2 * last)
+		}
+	}
+
+	start := color.NRGBAModel.Convert(g.Start).(color.NRGBA)
+	end := color.NRGBAModel.Convert(g.End).(color.NRGBA)
+
+	lerp := func(a uint8, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+
+	return color.NRGBA{
+		R: lerp(start.R, end.R),
+		G: lerp(start.G, end.G),
+		B: lerp(start.B, end.B),
+		A: lerp(start.A, end.A),
+	}
+}
+
+// ImageWithColorFunc returns the QR Code as an image.Image, as Image(), with
+// each dark module drawn in the colour returned by darkFn, e.g. for radial or
+// patterned fills.
+//
+// darkFn is called once per dark module of the symbol, with its coordinates
+// (x, y), from 0 to n-1 excluding the quiet zone, and the symbol width n in
+// modules. darkFn is used in place of the ForegroundColor and Gradient; a nil
+// colour selects the ForegroundColor. The FinderColor, if set, still takes
+// precedence. Light modules are drawn in the BackgroundColor.
+//
+// Keep the colours dark, so the QR Code remains readable. If darkFn is nil,
+// the image is as Image().
+func (q *QRCode) ImageWithColorFunc(size int, darkFn func(x int, y int, n int) color.Color) image.Image {
+	return q.renderImage(size, darkFn)
+}
+
+// darkModuleColors returns the darkFn colour of each dark module of the
+// symbol, indexed [y*n+x], excluding the quiet zone.
+func (q *QRCode) darkModuleColors(darkFn func(x int, y int, n int) color.Color) []color.NRGBA {
+	n := q.symbol.symbolSize
+	border := q.symbol.quietZoneSize
+	bitmap := q.symbol.bitmap()
+
+	colors := make([]color.NRGBA, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if !bitmap[border+y][border+x] {
+				continue
+			}
+
+			c := darkFn(x, y, n)
+			if c == nil {
+				c = q.ForegroundColor
+			}
+			colors[y*n+x] = color.NRGBAModel.Convert(c).(color.NRGBA)
+		}
+	}
+
+	return colors
+}
@@ -0,0 +1,194 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+)
+
+func TestGradient(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	start := color.NRGBA{R: 0xff, A: 0xff}
+	end := color.NRGBA{B: 0xff, A: 0xff}
+
+	const scale = 10
+	n := q.version.symbolSize()
+
+	for _, direction := range []GradientDirection{Vertical, Horizontal, Diagonal} {
+		q.Gradient = &Gradient{Start: start, End: end, Direction: direction}
+
+		img := q.Image(-scale)
+		border := q.symbol.quietZoneSize * scale
+
+		// The top left and bottom right dark modules (of the finder patterns).
+		topLeft := color.NRGBAModel.Convert(img.At(border, border)).(color.NRGBA)
+		if direction != Horizontal && topLeft != start {
+			t.Errorf("direction %d: got top left colour %v, expected %v", direction, topLeft, start)
+		}
+
+		// The dark module nearest the bottom right corner.
+		qz := q.symbol.quietZoneSize
+		bitmap := q.Bitmap()
+		bx, by := 0, 0
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				if bitmap[qz+y][qz+x] && x+y > bx+by {
+					bx, by = x, y
+				}
+			}
+		}
+		if bx+by < 2*n-5 {
+			t.Fatalf("no dark module near the bottom right corner")
+		}
+
+		bottomRight := color.NRGBAModel.Convert(img.At(border+bx*scale, border+by*scale)).(color.NRGBA)
+		if bottomRight.B < 0xc0 || bottomRight.R > 0x40 {
+			t.Errorf("direction %d: got bottom right colour %v, expected near %v", direction, bottomRight, end)
+		}
+
+		// The background is unaffected.
+		if c := color.NRGBAModel.Convert(img.At(0, 0)); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Errorf("direction %d: got background colour %v", direction, c)
+		}
+	}
+
+	// Without a gradient, a paletted image is returned as before.
+	q.Gradient = nil
+	if _, ok := q.Image(-scale).(*image.Paletted); !ok {
+		t.Errorf("no gradient: expected a paletted image")
+	}
+}
+
+func TestGradientAt(t *testing.T) {
+	g := &Gradient{Start: color.Black, End: color.White, Direction: Diagonal}
+
+	tests := []struct {
+		x, y     int
+		expected uint8
+	}{
+		{0, 0, 0x00},
+		{10, 10, 0xff},
+		{0, 10, 0x80},
+		{10, 0, 0x80},
+	}
+
+	for _, test := range tests {
+		if got := g.at(test.x, test.y, 11); got.R != test.expected {
+			t.Errorf("(%d, %d): got %#02x, expected %#02x", test.x, test.y, got.R, test.expected)
+		}
+	}
+}
+
+func TestImageWithColorFunc(t *testing.T) {
+	q, err := New("https://example.org/colours", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	left := color.NRGBA{R: 0x80, A: 0xff}
+	right := color.NRGBA{B: 0x80, A: 0xff}
+
+	numCalls := 0
+	byColumn := func(x int, y int, n int) color.Color {
+		numCalls++
+		if n != q.version.symbolSize() {
+			t.Fatalf("got n=%d, expected %d", n, q.version.symbolSize())
+		}
+
+		if x < n/2 {
+			return left
+		}
+		return right
+	}
+
+	const scale = 10
+	img := q.ImageWithColorFunc(-scale, byColumn)
+	border := q.symbol.quietZoneSize
+	n := q.symbol.symbolSize
+	bitmap := q.Bitmap()
+
+	numDark := 0
+	background := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			c := color.NRGBAModel.Convert(img.At((border+x)*scale+scale/2, (border+y)*scale+scale/2))
+
+			expected := background
+			switch {
+			case !bitmap[border+y][border+x]:
+			case x < n/2:
+				expected = left
+				numDark++
+			default:
+				expected = right
+				numDark++
+			}
+
+			if c != expected {
+				t.Fatalf("module (%d, %d): got %v, expected %v", x, y, c, expected)
+			}
+		}
+	}
+
+	if numCalls != numDark {
+		t.Errorf("got %d calls, expected one per dark module (%d)", numCalls, numDark)
+	}
+
+	// The colour function applies only to the one image.
+	if c := color.NRGBAModel.Convert(q.Image(-scale).At(border*scale, border*scale)); c != (color.NRGBA{A: 0xff}) {
+		t.Errorf("Image: got colour %v, expected black", c)
+	}
+}
+
+func TestImageWithColorFuncConcurrent(t *testing.T) {
+	q, err := New("https://example.org/colours", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Encode before sharing the QR Code.
+	q.Image(0)
+
+	const scale = 10
+	border := q.symbol.quietZoneSize
+	red := color.NRGBA{R: 0x80, A: 0xff}
+	black := color.NRGBA{A: 0xff}
+
+	// The top left finder pattern module is always dark.
+	corner := func(img image.Image) color.Color {
+		return color.NRGBAModel.Convert(img.At(border*scale, border*scale))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			img := q.ImageWithColorFunc(-scale, func(x int, y int, n int) color.Color { return red })
+			if c := corner(img); c != red {
+				errs <- "ImageWithColorFunc: unexpected colour"
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if c := corner(q.Image(-scale)); c != black {
+				errs <- "Image: unexpected colour"
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for e := range errs {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,48 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"io"
+)
+
+// JPEG returns the QR Code as a JPEG image, encoded at quality (1-100).
+//
+// JPEG compression artifacts blur the module edges, which can make QR Codes
+// difficult to scan, especially at small sizes. A quality of 90 or above is
+// recommended. PNG() is the better choice where possible.
+//
+// size is both the image width and height in pixels. If size is too small then
+// a larger image is silently returned, or an error if StrictSize is set.
+// Negative values for size cause a variable sized image to be returned: See the
+// documentation for Image().
+//
+// An error occurs if quality is out of range.
+func (q *QRCode) JPEG(size int, quality int) ([]byte, error) {
+	var b bytes.Buffer
+	err := q.WriteJPEG(size, quality, &b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteJPEG writes the QR Code as a JPEG image, encoded at quality (1-100), to
+// io.Writer. See the documentation for JPEG().
+func (q *QRCode) WriteJPEG(size int, quality int, out io.Writer) error {
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("invalid JPEG quality %d (expected 1-100)", quality)
+	}
+
+	if err := q.checkImage(size); err != nil {
+		return err
+	}
+
+	return jpeg.Encode(out, q.Image(size), &jpeg.Options{Quality: quality})
+}
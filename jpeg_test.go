@@ -0,0 +1,47 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJPEG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := q.JPEG(-10, 95)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Start of image marker.
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		t.Errorf("got prefix % x, expected ff d8", data[:2])
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	size := len(q.Bitmap()) * 10
+	if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+		t.Errorf("got size %v, expected %dx%d", b.Size(), size, size)
+	}
+
+	if s, err := Decode(img); err != nil || s != q.Content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	for _, quality := range []int{0, 101, -1} {
+		if _, err := q.JPEG(-10, quality); err == nil {
+			t.Errorf("quality %d: got success, expected error", quality)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// maxLogoCoverage returns the largest fraction of the symbol area a logo may
+// cover at a recovery level.
+//
+// This is half of the level's recovery capacity, leaving the remainder to
+// correct other damage (e.g. dirt, or printing defects).
+func maxLogoCoverage(level RecoveryLevel) float64 {
+	switch level {
+	case Low:
+		return 0.07 / 2
+	case Medium:
+		return 0.15 / 2
+	case High:
+		return 0.25 / 2
+	case Highest:
+		return 0.30 / 2
+	}
+
+	return 0
+}
+
+// ImageWithLogo returns the QR Code as an image.Image, with logo composited
+// over its centre.
+//
+// size is as for Image(). scale is the fraction of the symbol width (excluding
+// the quiet zone) the logo may occupy, e.g. 0.2. The logo is resized to fit,
+// preserving its aspect ratio. Translucent logo pixels are blended over the QR
+// Code.
+//
+// The logo obscures modules, which the QR Code's error recovery information
+// must compensate for. An error occurs if the logo would cover more than half
+// of the recovery capacity of the QR Code's RecoveryLevel: Use a higher
+// RecoveryLevel to allow a larger logo.
+func (q *QRCode) ImageWithLogo(size int, logo image.Image, scale float64) (image.Image, error) {
+	if logo == nil || logo.Bounds().Empty() {
+		return nil, errors.New("empty logo image")
+	}
+
+	coverage := maxLogoCoverage(q.Level)
+	if scale <= 0 || scale*scale > coverage {
+		return nil, fmt.Errorf("logo scale %.2f is too large for the recovery level (maximum %.2f); use a higher RecoveryLevel",
+			scale, math.Sqrt(coverage))
+	}
+
+	img := q.Image(size)
+	b := img.Bounds()
+
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	// Fit the logo in a square box of side scale * the symbol width.
+	symbolWidth := float64(b.Dx()*q.symbol.symbolSize) / float64(q.symbol.size)
+	box := scale * symbolWidth
+
+	lb := logo.Bounds()
+	ratio := math.Min(box/float64(lb.Dx()), box/float64(lb.Dy()))
+	w := int(math.Max(1, math.Round(float64(lb.Dx())*ratio)))
+	h := int(math.Max(1, math.Round(float64(lb.Dy())*ratio)))
+
+	x := b.Min.X + (b.Dx()-w)/2
+	y := b.Min.Y + (b.Dy()-h)/2
+
+	draw.Draw(dst, image.Rect(x, y, x+w, y+h), scaleImage(logo, w, h), image.Point{}, draw.Over)
+
+	return dst, nil
+}
+
+// scaleImage returns src resized to w x h pixels, using nearest neighbour
+// sampling. The returned image's bounds start at (0, 0).
+func scaleImage(src image.Image, w int, h int) *image.RGBA {
+	sb := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		sy := sb.Min.Y + (2*y+1)*sb.Dy()/(2*h)
+
+		for x := 0; x < w; x++ {
+			sx := sb.Min.X + (2*x+1)*sb.Dx()/(2*w)
+
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,97 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestImageWithLogo(t *testing.T) {
+	content := "https://example.org/logo"
+
+	q, err := New(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A 40x20 red logo.
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	draw.Draw(logo, logo.Bounds(), &image.Uniform{red}, image.Point{}, draw.Src)
+
+	img, err := q.ImageWithLogo(-1, logo, 0.3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain := q.Image(-1)
+	if img.Bounds() != plain.Bounds() {
+		t.Fatalf("got bounds %v, expected %v", img.Bounds(), plain.Bounds())
+	}
+
+	// Find the red region.
+	b := img.Bounds()
+	minX, minY, maxX, maxY := b.Max.X, b.Max.Y, b.Min.X, b.Min.Y
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) != red {
+				continue
+			}
+
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x+1), max(maxY, y+1)
+		}
+	}
+
+	symbolWidth := float64(b.Dx()*q.symbol.symbolSize) / float64(q.symbol.size)
+	expectedWidth := int(math.Round(0.3 * symbolWidth))
+	expectedHeight := int(math.Round(0.3 * symbolWidth / 2))
+
+	if maxX-minX != expectedWidth || maxY-minY != expectedHeight {
+		t.Errorf("logo is %dx%d, expected %dx%d", maxX-minX, maxY-minY,
+			expectedWidth, expectedHeight)
+	}
+
+	// Centred to within a pixel.
+	if d := (minX - b.Min.X) - (b.Max.X - maxX); d < -1 || d > 1 {
+		t.Errorf("logo not horizontally centred: offset %d", d)
+	}
+	if d := (minY - b.Min.Y) - (b.Max.Y - maxY); d < -1 || d > 1 {
+		t.Errorf("logo not vertically centred: offset %d", d)
+	}
+
+	s, err := Decode(img)
+	if err != nil {
+		t.Fatalf("decode failed: %s", err.Error())
+	}
+
+	if s != content {
+		t.Errorf("decoded %q, expected %q", s, content)
+	}
+}
+
+func TestImageWithLogoScaleTooLarge(t *testing.T) {
+	q, err := New("https://example.org/logo", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	if _, err := q.ImageWithLogo(256, logo, 0.3); err == nil {
+		t.Errorf("scale 0.3 at Low: got success, expected error")
+	}
+
+	if _, err := q.ImageWithLogo(256, logo, 0.15); err != nil {
+		t.Errorf("scale 0.15 at Low: got error %s", err.Error())
+	}
+
+	if _, err := q.ImageWithLogo(256, logo, 0); err == nil {
+		t.Errorf("scale 0: got success, expected error")
+	}
+}
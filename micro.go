@@ -0,0 +1,350 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
+)
+
+// Micro QR Codes.
+//
+// A Micro QR Code is a smaller variant of the QR Code, with a single finder
+// pattern in the top left corner. There are four versions, M1-M4, of 11x11 to
+// 17x17 modules.
+//
+// Micro QR Codes hold at most 35 numeric digits (M4 at the Low recovery
+// level). Not every data mode and recovery level is available in every
+// version:
+//
+//	Version  Data modes                             Recovery levels
+//	M1       numeric                                (error detection only)
+//	M2       numeric, alphanumeric                  Low, Medium
+//	M3       numeric, alphanumeric, byte, kanji     Low, Medium
+//	M4       numeric, alphanumeric, byte, kanji     Low, Medium, High
+//
+// The Highest recovery level is not available. M1 symbols, which can only
+// detect errors, are used at the Low recovery level.
+
+// microVersion describes a Micro QR Code version at a recovery level.
+type microVersion struct {
+	// Version number, 1-4 for M1-M4.
+	version int
+
+	level RecoveryLevel
+
+	// Symbol number, stated in the format information.
+	symbolNumber int
+
+	// Number of data bits. M1 and M3 symbols end with a 4-bit data codeword.
+	numDataBits int
+
+	// Number of error correction codewords.
+	numECCodewords int
+}
+
+var microVersions = []microVersion{
+	{1, Low, 0, 20, 2},
+	{2, Low, 1, 40, 5},
+	{2, Medium, 2, 32, 6},
+	{3, Low, 3, 84, 6},
+	{3, Medium, 4, 68, 8},
+	{4, Low, 5, 128, 8},
+	{4, Medium, 6, 112, 10},
+	{4, High, 7, 80, 14},
+}
+
+const (
+	// Micro QR Codes need a quiet zone of only two modules.
+	microQuietZoneSize = 2
+
+	// Number of Micro QR Code data masks.
+	numMicroMasks = 4
+
+	// XOR mask applied to the Micro QR Code format information.
+	microFormatInfoMask = 0x4445
+)
+
+// microDataMasks maps each Micro QR Code data mask to the equivalent QR Code
+// data mask.
+var microDataMasks = [numMicroMasks]int{1, 4, 6, 7}
+
+// symbolSize returns the size of the Micro QR Code symbol in modules, excluding
+// the quiet zone.
+func (v microVersion) symbolSize() int {
+	return 9 + v.version*2
+}
+
+// numTerminatorBits returns the length of the terminator bit sequence.
+func (v microVersion) numTerminatorBits() int {
+	return 1 + v.version*2
+}
+
+// formatInfo returns the 15-bit format information for the Micro QR Code with
+// the data mask mask.
+func (v microVersion) formatInfo(mask int) *bitset.Bitset {
+	data := uint32(v.symbolNumber<<2 | mask)
+
+	// BCH(15,5) code, generator polynomial x^10+x^8+x^5+x^4+x^2+x+1.
+	bch := data << 10
+	for i := 14; i >= 10; i-- {
+		if bch&(1<<uint(i)) != 0 {
+			bch ^= 0x537 << uint(i-10)
+		}
+	}
+
+	result := bitset.New()
+	result.AppendUint32((data<<10|bch)^microFormatInfoMask, formatInfoLengthBits)
+
+	return result
+}
+
+// newMicroDataEncoder constructs a dataEncoder for Micro QR Code version M1-M4.
+// Data modes not available in the version have a nil mode indicator.
+func newMicroDataEncoder(version int) *dataEncoder {
+	d := &dataEncoder{}
+
+	switch version {
+	case 1:
+		d = &dataEncoder{
+			numericModeIndicator:    bitset.New(),
+			numNumericCharCountBits: 3,
+		}
+	case 2:
+		d = &dataEncoder{
+			numericModeIndicator:         bitset.New(b0),
+			alphanumericModeIndicator:    bitset.New(b1),
+			numNumericCharCountBits:      4,
+			numAlphanumericCharCountBits: 3,
+		}
+	case 3:
+		d = &dataEncoder{
+			numericModeIndicator:         bitset.New(b0, b0),
+			alphanumericModeIndicator:    bitset.New(b0, b1),
+			byteModeIndicator:            bitset.New(b1, b0),
+			kanjiModeIndicator:           bitset.New(b1, b1),
+			numNumericCharCountBits:      5,
+			numAlphanumericCharCountBits: 4,
+			numByteCharCountBits:         4,
+			numKanjiCharCountBits:        3,
+		}
+	case 4:
+		d = &dataEncoder{
+			numericModeIndicator:         bitset.New(b0, b0, b0),
+			alphanumericModeIndicator:    bitset.New(b0, b0, b1),
+			byteModeIndicator:            bitset.New(b0, b1, b0),
+			kanjiModeIndicator:           bitset.New(b0, b1, b1),
+			numNumericCharCountBits:      6,
+			numAlphanumericCharCountBits: 5,
+			numByteCharCountBits:         5,
+			numKanjiCharCountBits:        4,
+		}
+	default:
+		log.Panicf("Unknown Micro QR Code version %d", version)
+	}
+
+	d.minVersion = version
+	d.maxVersion = version
+
+	return d
+}
+
+// NewMicro constructs a Micro QR Code.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewMicro("12345", qrcode.Low)
+//
+// The smallest Micro QR Code version (M1-M4) able to hold the content is used.
+// VersionNumber is set to the Micro QR Code version number, 1-4.
+//
+// An error occurs if the content is too long, or contains characters which
+// cannot be encoded in any Micro QR Code version (at the recovery level), or
+// if level is Highest.
+func NewMicro(content string, level RecoveryLevel) (*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
+	if level == Highest {
+		return nil, errors.New("the Highest recovery level is not available in Micro QR Codes")
+	}
+
+	for _, v := range microVersions {
+		if v.level != level {
+			continue
+		}
+
+		encoder := newMicroDataEncoder(v.version)
+		encoded, err := encoder.encode([]byte(content))
+
+		if err != nil || encoded.Len() > v.numDataBits {
+			continue
+		}
+
+		version := v
+
+		q := &QRCode{
+			Content: content,
+
+			Level:         level,
+			VersionNumber: v.version,
+
+			ForegroundColor: color.Black,
+			BackgroundColor: color.White,
+
+			ForcedMask: -1,
+
+			encoder: encoder,
+			data:    encoded,
+			micro:   &version,
+		}
+
+		return q, nil
+	}
+
+	return nil, fmt.Errorf("%w in a Micro QR Code", ErrContentTooLong)
+}
+
+// IsMicro returns true if the QR Code is a Micro QR Code, as constructed by
+// NewMicro().
+func (q *QRCode) IsMicro() bool {
+	return q.micro != nil
+}
+
+// encodeMicro completes the steps required to encode a Micro QR Code, as
+// encode().
+func (q *QRCode) encodeMicro() {
+	v := q.micro
+
+	q.addMicroPadding()
+
+	// A final 4-bit data codeword occupies the high bits of a byte for error
+	// correction purposes.
+	data := bitset.Clone(q.data)
+	data.AppendNumBools((8-data.Len()%8)%8, false)
+
+	ecc := reedsolomon.Encode(data, v.numECCodewords)
+
+	encoded := bitset.Clone(q.data)
+	encoded.Append(ecc.Substr(data.Len(), ecc.Len()))
+
+	score := 0
+	q.symbol = nil
+	q.penalty = 0
+
+	for mask := 0; mask < numMicroMasks; mask++ {
+		if q.ForcedMask >= 0 && q.ForcedMask < numMicroMasks && mask != q.ForcedMask {
+			continue
+		}
+
+		s := buildMicroSymbol(*v, mask, encoded, q.quietZoneSize())
+
+		numEmptyModules := s.numEmptyModules()
+		if numEmptyModules != 0 {
+			log.Panicf("bug: numEmptyModules is %d (expected 0) (version=M%d)",
+				numEmptyModules, v.version)
+		}
+
+		p := microMaskScore(s)
+
+		if q.symbol == nil || p > score {
+			q.symbol = s
+			q.mask = mask
+			score = p
+		}
+	}
+}
+
+// addMicroPadding adds the terminator bits and padding to the encoded data of
+// a Micro QR Code.
+func (q *QRCode) addMicroPadding() {
+	v := q.micro
+
+	q.data.AppendNumBools(min(v.numTerminatorBits(), v.numDataBits-q.data.Len()), false)
+
+	// Pad to the nearest codeword boundary. The final 4-bit codeword of M1 and
+	// M3 symbols is zero filled.
+	q.data.AppendNumBools(min((8-q.data.Len()%8)%8, v.numDataBits-q.data.Len()), false)
+
+	padding := [2]*bitset.Bitset{
+		bitset.New(true, true, true, false, true, true, false, false),
+		bitset.New(false, false, false, true, false, false, false, true),
+	}
+
+	i := 0
+	for v.numDataBits-q.data.Len() >= 8 {
+		q.data.Append(padding[i])
+
+		i = 1 - i // Alternate between 0 and 1.
+	}
+
+	q.data.AppendNumBools(v.numDataBits-q.data.Len(), false)
+}
+
+// buildMicroSymbol builds the Micro QR Code symbol of version v, with the data
+// mask mask applied to data, and a quiet zone of quietZoneSize modules.
+func buildMicroSymbol(v microVersion, mask int, data *bitset.Bitset, quietZoneSize int) *symbol {
+	size := v.symbolSize()
+	s := newSymbol(size, quietZoneSize)
+
+	// Finder pattern, with a separator on the right and bottom sides.
+	s.set2dPattern(0, 0, finderPattern)
+	s.set2dPattern(0, finderPatternSize, finderPatternHorizontalBorder)
+	s.set2dPattern(finderPatternSize, 0, finderPatternVerticalBorder)
+
+	// Timing patterns, along the top and left edges.
+	for i := finderPatternSize + 1; i < size; i++ {
+		s.set(i, 0, i%2 == 0)
+		s.set(0, i, i%2 == 0)
+	}
+
+	// Format information, right of and under the finder pattern. Bits 0-7 run
+	// downwards from (8, 1), bits 14-7 run rightwards from (1, 8).
+	f := v.formatInfo(mask)
+	l := formatInfoLengthBits - 1
+
+	for i := 0; i <= 7; i++ {
+		s.set(finderPatternSize+1, i+1, f.At(l-i))
+		s.set(i+1, finderPatternSize+1, f.At(i))
+	}
+
+	i := 0
+	walkDataModules(s, data.Len(), false, func(x int, y int) {
+		// != is equivalent to XOR.
+		s.set(x, y, dataMask(microDataMasks[mask], x, y) != data.At(i))
+		i++
+	})
+
+	return s
+}
+
+// microMaskScore returns the score of the masked Micro QR Code symbol s. The
+// mask with the highest score is preferred.
+//
+// The score favours dark modules along the right and bottom edges of the
+// symbol, which would otherwise be difficult to locate.
+func microMaskScore(s *symbol) int {
+	sum1 := 0
+	sum2 := 0
+
+	for i := 1; i < s.symbolSize; i++ {
+		if s.get(s.symbolSize-1, i) {
+			sum1++
+		}
+		if s.get(i, s.symbolSize-1) {
+			sum2++
+		}
+	}
+
+	if sum1 <= sum2 {
+		return sum1*16 + sum2
+	}
+
+	return sum2*16 + sum1
+}
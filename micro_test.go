@@ -0,0 +1,185 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"strings"
+	"testing"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
+)
+
+func TestNewMicroVersion(t *testing.T) {
+	tests := []struct {
+		content  string
+		level    RecoveryLevel
+		expected int
+	}{
+		{"1", Low, 1},
+		{"12345", Low, 1},
+		{"123456", Low, 2},
+		{"1234567890", Low, 2},
+		{"12345678", Medium, 2},
+		{"123456789", Medium, 3},
+		{strings.Repeat("1", 23), Low, 3},
+		{strings.Repeat("1", 24), Low, 4},
+		{strings.Repeat("1", 35), Low, 4},
+		{strings.Repeat("1", 21), High, 4},
+		{"HELLO", Low, 2},
+		{"hello", Low, 3},
+		{strings.Repeat("a", 15), Low, 4},
+	}
+
+	for _, test := range tests {
+		q, err := NewMicro(test.content, test.level)
+		if err != nil {
+			t.Errorf("%q level %d: got error %s", test.content, test.level, err.Error())
+			continue
+		}
+
+		if !q.IsMicro() || q.Version() != test.expected {
+			t.Errorf("%q level %d: got version M%d, expected M%d", test.content,
+				test.level, q.Version(), test.expected)
+		}
+
+		size := 9 + test.expected*2
+		if got := len(q.Matrix()); got != size {
+			t.Errorf("%q: got %d modules, expected %d", test.content, got, size)
+		}
+
+		if got := len(q.Bitmap()); got != size+2*microQuietZoneSize {
+			t.Errorf("%q: got bitmap size %d, expected %d", test.content, got, size+2*microQuietZoneSize)
+		}
+
+		if b := q.Image(-10).Bounds(); b.Dx() != (size+2*microQuietZoneSize)*10 {
+			t.Errorf("%q: got image size %v", test.content, b.Size())
+		}
+	}
+
+	fails := []struct {
+		content string
+		level   RecoveryLevel
+	}{
+		{strings.Repeat("1", 36), Low},
+		{strings.Repeat("1", 22), High},
+		{strings.Repeat("a", 16), Low},
+		{"1", Highest},
+		{"", Low},
+	}
+
+	for _, test := range fails {
+		if _, err := NewMicro(test.content, test.level); err == nil {
+			t.Errorf("%q level %d: got success, expected error", test.content, test.level)
+		}
+	}
+}
+
+func TestMicroCodewords(t *testing.T) {
+	// ISO/IEC 18004:2006 Annex I.3.
+	q, err := NewMicro("01234567", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Version() != 2 {
+		t.Fatalf("got version M%d, expected M2", q.Version())
+	}
+
+	q.encode()
+
+	data := bitset.Clone(q.data)
+	ecc := reedsolomon.Encode(data, q.micro.numECCodewords)
+
+	expected := []byte{0x40, 0x18, 0xac, 0xc3, 0x00, 0x86, 0x0d, 0x22, 0xae, 0x30}
+	for i, b := range expected {
+		if got := ecc.ByteAt(i * 8); got != b {
+			t.Errorf("codeword %d: got %#02x, expected %#02x", i, got, b)
+		}
+	}
+}
+
+func TestMicroSymbol(t *testing.T) {
+	tests := []struct {
+		content string
+		level   RecoveryLevel
+	}{
+		{"12345", Low},
+		{"01234567", Low},
+		{"HELLO", Medium},
+		{"hello", Low},
+		{"micro qr", Medium},
+		{strings.Repeat("1", 21), High},
+	}
+
+	for _, test := range tests {
+		q, err := NewMicro(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		for mask := 0; mask < numMicroMasks; mask++ {
+			q.ForcedMask = mask
+			q.DisableBorder = true
+
+			if q.Mask() != mask {
+				t.Errorf("%q: got mask %d, expected %d", test.content, q.Mask(), mask)
+			}
+
+			m := q.Matrix()
+			size := len(m)
+
+			// Timing patterns.
+			for i := finderPatternSize + 1; i < size; i++ {
+				if m[0][i] != (i%2 == 0) || m[i][0] != (i%2 == 0) {
+					t.Errorf("%q: bad timing pattern at %d", test.content, i)
+				}
+			}
+
+			// Format information.
+			f := bitset.New()
+			for i := 0; i <= 7; i++ {
+				f.AppendBools(m[8][i+1])
+			}
+			for i := 6; i >= 0; i-- {
+				f.AppendBools(m[i+1][8])
+			}
+			expectedFormat := q.micro.formatInfo(mask)
+			if !f.Equals(expectedFormat) {
+				t.Errorf("%q mask %d: got format info %s, expected %s", test.content,
+					mask, f.String(), expectedFormat.String())
+			}
+
+			// Read back the data and error correction codewords.
+			empty := buildMicroSymbol(*q.micro, mask, bitset.New(), 0)
+			numBits := empty.numEmptyModules()
+
+			read := bitset.New()
+			walkDataModules(empty, numBits, false, func(x int, y int) {
+				read.AppendBools(m[y][x] != dataMask(microDataMasks[mask], x, y))
+			})
+
+			numDataBits := q.micro.numDataBits
+			if numBits != numDataBits+q.micro.numECCodewords*8 {
+				t.Fatalf("%q: got %d data modules, expected %d", test.content, numBits,
+					numDataBits+q.micro.numECCodewords*8)
+			}
+
+			// The final 4-bit data codeword is zero extended.
+			codewords := read.Substr(0, numDataBits)
+			codewords.AppendNumBools((8-numDataBits%8)%8, false)
+			codewords.Append(read.Substr(numDataBits, numBits))
+
+			corrected, err := reedsolomon.Decode(codewords, q.micro.numECCodewords)
+			if err != nil {
+				t.Errorf("%q mask %d: %s", test.content, mask, err.Error())
+				continue
+			}
+
+			if !corrected.Substr(0, numDataBits).Equals(q.data) {
+				t.Errorf("%q mask %d: data read back does not match", test.content, mask)
+			}
+		}
+	}
+}
@@ -5,16 +5,32 @@ package qrcode
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/png"
 	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
 )
 
 // EncodeMulti encodes content that may exceed single QR code capacity.
 // Returns a slice of QRCode objects, one per chunk.
+//
+// Content is split at rune boundaries as by SplitContentUTF8(): Each chunk is
+// the longest prefix of the remaining content which New() accepts, found by
+// binary search, so the number of QR codes is minimal.
 func EncodeMulti(content string, level RecoveryLevel) ([]*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
 	chunks := SplitContentUTF8(content, level)
 	codes := make([]*QRCode, 0, len(chunks))
 	for _, chunk := range chunks {
@@ -27,11 +43,331 @@ func EncodeMulti(content string, level RecoveryLevel) ([]*QRCode, error) {
 	return codes, nil
 }
 
+// EncodeMultiContext encodes content that may exceed single QR code capacity,
+// as EncodeMulti, stopping early if ctx is cancelled.
+//
+// ctx is checked before the content is split, and between encoding each
+// chunk. If it is cancelled (or its deadline passes), the QR codes encoded so
+// far are discarded and ctx.Err() is returned.
+func EncodeMultiContext(ctx context.Context, content string, level RecoveryLevel) ([]*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	chunks := SplitContentUTF8(content, level)
+	codes := make([]*QRCode, 0, len(chunks))
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		q, err := New(chunk, level)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, q)
+	}
+	return codes, nil
+}
+
+// EstimateChunkCount returns the number of QR codes EncodeMulti() would encode
+// content in, at the recovery level, e.g. to size a progress bar before
+// encoding a long document.
+//
+// The content is split as by EncodeMulti(), but each candidate chunk is only
+// measured, by the length of its optimal data encoding, rather than encoded: No
+// QR Codes or images are constructed. The count is exact. Zero is returned for
+// empty content, or an invalid level.
+func EstimateChunkCount(content string, level RecoveryLevel) int {
+	if checkRecoveryLevel(level) != nil {
+		return 0
+	}
+
+	encoders := newDataEncoders()
+	fits := func(chunk string) bool {
+		_, err := versionFor([]byte(chunk), level, encoders)
+		return err == nil
+	}
+
+	return len(splitContent(content, fits, maxEncodablePrefix))
+}
+
+// EncodeMultiConcurrent encodes content that may exceed single QR code
+// capacity, as EncodeMulti, encoding the chunks using up to workers goroutines.
+//
+// The QR codes are returned in chunk order. If a chunk fails to encode, the
+// remaining chunks are abandoned and the first error is returned. With
+// workers <= 1, EncodeMultiConcurrent is equivalent to EncodeMulti.
+func EncodeMultiConcurrent(content string, level RecoveryLevel, workers int) ([]*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
+	if workers <= 1 {
+		return EncodeMulti(content, level)
+	}
+
+	chunks := SplitContentUTF8(content, level)
+	codes := make([]*QRCode, len(chunks))
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	done := make(chan struct{})
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range chunks {
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				q, err := New(chunks[i], level)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+					return
+				}
+				codes[i] = q
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return codes, nil
+}
+
+// EncodeMultiStream encodes content that may exceed single QR code capacity,
+// as EncodeMulti, calling fn with the PNG image of each chunk in turn.
+//
+// Only one chunk is encoded at a time, so memory use is bounded regardless of
+// the content length. size is as for Encode(). If fn returns an error,
+// encoding stops and the error is returned.
+func EncodeMultiStream(content string, level RecoveryLevel, size int,
+	fn func(index int, png []byte) error) error {
+	if err := checkRecoveryLevel(level); err != nil {
+		return err
+	}
+
+	for i, chunk := range SplitContentUTF8(content, level) {
+		png, err := Encode(chunk, level, size)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(i, png); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxStructuredAppendSymbols is the maximum number of symbols in a Structured
+// Append sequence.
+const maxStructuredAppendSymbols = 16
+
+// EncodeStructuredAppend encodes content that may exceed single QR code
+// capacity as a sequence of up to 16 linked QR codes.
+//
+// Each QR Code carries a Structured Append header stating its position in the
+// sequence, the number of codes, and a parity byte of the complete content. A
+// compliant reader scanning all the codes reassembles them into the original
+// content, in any scanning order.
+//
+// Content is split at rune boundaries. An error occurs if the content does not
+// fit in 16 QR codes.
+func EncodeStructuredAppend(content string, level RecoveryLevel) ([]*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
+	var parity byte
+	for i := 0; i < len(content); i++ {
+		parity ^= content[i]
+	}
+
+	// The header has a fixed length, so the sequence position is irrelevant
+	// when sizing chunks.
+	header := &structuredAppend{total: maxStructuredAppendSymbols, parity: parity}
+	fits := func(chunk string) bool {
+		_, err := newWithOptions(chunk, level, encodeOptions{structuredAppend: header})
+		return err == nil
+	}
+
+	var chunks []string
+	for len(content) > 0 {
+		end := maxEncodablePrefix(content, fits)
+		if end == 0 || len(chunks) == maxStructuredAppendSymbols {
+			return nil, fmt.Errorf("%w in a structured append sequence", ErrContentTooLong)
+		}
+
+		chunks = append(chunks, content[:end])
+		content = content[end:]
+	}
+
+	codes := make([]*QRCode, 0, len(chunks))
+	for i, chunk := range chunks {
+		opts := encodeOptions{
+			structuredAppend: &structuredAppend{
+				index:  i,
+				total:  len(chunks),
+				parity: parity,
+			},
+		}
+
+		q, err := newWithOptions(chunk, level, opts)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, q)
+	}
+	return codes, nil
+}
+
 // GridImage arranges multiple QR code images into a single grid image.
 // size is the pixel size per individual QR code.
 // cols specifies the number of columns; 0 means auto (square-ish layout).
+//
+// An empty image is returned for invalid arguments, see GridImageChecked().
 func GridImage(codes []*QRCode, size int, cols int) image.Image {
-	n := len(codes)
+	img, err := GridImageChecked(codes, size, cols)
+	if err != nil {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	return img
+}
+
+// GridImageChecked arranges multiple QR code images into a single grid image,
+// as GridImage.
+//
+// An error occurs if codes is empty or contains nil QR Codes, or size is not
+// positive.
+func GridImageChecked(codes []*QRCode, size int, cols int) (image.Image, error) {
+	if len(codes) == 0 {
+		return nil, errors.New("no QR Codes to arrange in a grid")
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid grid cell size %d (expected > 0)", size)
+	}
+
+	for i, q := range codes {
+		if q == nil {
+			return nil, fmt.Errorf("QR Code %d is nil", i)
+		}
+	}
+
+	return GridImageWithOptions(codes, size, GridImageOptions{Cols: cols}), nil
+}
+
+// GridImageOptions are the layout options of GridImageWithOptions.
+type GridImageOptions struct {
+	// Number of columns; 0 means auto (square-ish layout).
+	Cols int
+
+	// Pixels of background space between adjacent cells, and around the outer
+	// edge of the grid. Separating the cells stops neighbouring quiet zones
+	// merging.
+	Gutter int
+
+	// Draw the codes without their quiet zones, and a single shared quiet zone
+	// around the whole grid instead. The shared quiet zone is as wide as the
+	// widest of the codes' quiet zones (set by QuietZone and DisableBorder), at
+	// the module size of the code. This packs the codes tighter.
+	SharedBorder bool
+}
+
+// GridImageWithOptions arranges multiple QR code images into a single grid
+// image, as GridImage, using the layout options opts.
+//
+// The grid image is cols*size + (cols+1)*opts.Gutter pixels wide, and
+// similarly high, plus twice the shared quiet zone width with
+// opts.SharedBorder.
+func GridImageWithOptions(codes []*QRCode, size int, opts GridImageOptions) image.Image {
+	imgs := make([]image.Image, len(codes))
+	border := 0
+
+	for i, q := range codes {
+		if !opts.SharedBorder {
+			imgs[i] = cropImage(q.Image(size), size)
+			continue
+		}
+
+		// Draw a borderless copy, leaving q unchanged: Encoding pads the data.
+		c := *q
+		c.data = bitset.Clone(q.data)
+		c.symbol = nil
+		c.DisableBorder = true
+
+		symbolSize := len(c.Bitmap())
+		border = max(border, q.quietZoneSize()*size/symbolSize)
+
+		imgs[i] = cropImage(c.Image(size), size)
+	}
+
+	return padImage(GridOf(imgs, opts.Cols, opts.Gutter, color.White), border, color.White)
+}
+
+// padImage returns img surrounded by border pixels of bg on each side.
+func padImage(img image.Image, border int, bg color.Color) image.Image {
+	if border <= 0 {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()+2*border, b.Dy()+2*border))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+	draw.Draw(dst, b.Sub(b.Min).Add(image.Point{border, border}), img, b.Min, draw.Over)
+
+	return dst
+}
+
+// cropImage returns the top left size x size pixels of img. Images are returned
+// unchanged if they are not larger, or cannot be cropped.
+func cropImage(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= size && b.Dy() <= size {
+		return img
+	}
+
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(image.Rectangle{Min: b.Min, Max: b.Min.Add(image.Point{size, size})})
+	}
+
+	return img
+}
+
+// GridOf arranges multiple images into a single grid image.
+//
+// The cells of the grid are all the size of the largest image (the maximum
+// width and height), and each image is centred in its cell. cols specifies the
+// number of columns; 0 means auto (square-ish layout). gutter is the pixels of
+// background space between adjacent cells, and around the outer edge of the
+// grid. The background is filled with bg.
+func GridOf(imgs []image.Image, cols int, gutter int, bg color.Color) image.Image {
+	n := len(imgs)
 	if n == 0 {
 		return image.NewRGBA(image.Rect(0, 0, 0, 0))
 	}
@@ -40,20 +376,183 @@ func GridImage(codes []*QRCode, size int, cols int) image.Image {
 	}
 	rows := (n + cols - 1) / cols
 
-	totalW := cols * size
-	totalH := rows * size
+	cellW, cellH := 0, 0
+	for _, img := range imgs {
+		cellW = max(cellW, img.Bounds().Dx())
+		cellH = max(cellH, img.Bounds().Dy())
+	}
+
+	gutter = max(0, gutter)
+
+	totalW := cols*(cellW+gutter) + gutter
+	totalH := rows*(cellH+gutter) + gutter
 
 	dst := image.NewRGBA(image.Rect(0, 0, totalW, totalH))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+
+	for i, img := range imgs {
+		r := i / cols
+		c := i % cols
+		b := img.Bounds()
+		dp := image.Point{
+			X: gutter + c*(cellW+gutter) + (cellW-b.Dx())/2,
+			Y: gutter + r*(cellH+gutter) + (cellH-b.Dy())/2,
+		}
+		draw.Draw(dst, image.Rectangle{Min: dp, Max: dp.Add(b.Size())}, img, b.Min, draw.Over)
+	}
+	return dst
+}
+
+// GridImageUniformPitch arranges multiple QR code images into a single grid
+// image, with every module modulePixels pixels in size (see ImageExact()).
+//
+// Unlike GridImage, codes of higher versions are drawn larger, so the printed
+// module size, and hence the scanning distance, is the same for every code.
+// The cells of the grid are all the size of the largest code, and smaller
+// codes are centred in their cells. cols is as for GridImage.
+//
+// An empty image is returned if codes is empty or contains nil QR Codes, or
+// modulePixels is less than 1.
+func GridImageUniformPitch(codes []*QRCode, modulePixels int, cols int) image.Image {
+	if len(codes) == 0 || modulePixels < 1 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	imgs := make([]image.Image, len(codes))
+	for i, q := range codes {
+		if q == nil {
+			return image.NewRGBA(image.Rect(0, 0, 0, 0))
+		}
+
+		imgs[i] = q.ImageExact(modulePixels)
+	}
+
+	return GridOf(imgs, cols, 0, color.White)
+}
+
+// GridImageWithLabels arranges multiple QR code images into a single grid
+// image, as GridImage, with a text label drawn below each code.
+//
+// labels[i] is the label of codes[i]. If labels is empty, the codes are
+// numbered in scanning order as "1/4", "2/4", etc. An error occurs if codes
+// contains nil QR Codes, or labels is non-empty and is not the same length as
+// codes.
+//
+// Labels are drawn using a small built-in font supporting digits, letters (in
+// upper case only), and common punctuation. Labels wider than size are clipped.
+func GridImageWithLabels(codes []*QRCode, size int, cols int, labels []string) (image.Image, error) {
+	n := len(codes)
+	if len(labels) != 0 && len(labels) != n {
+		return nil, fmt.Errorf("got %d labels for %d codes", len(labels), n)
+	}
+	for i, q := range codes {
+		if q == nil {
+			return nil, fmt.Errorf("QR Code %d is nil", i)
+		}
+	}
+	if n == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0)), nil
+	}
+	if len(labels) == 0 {
+		labels = make([]string, n)
+		for i := range labels {
+			labels[i] = fmt.Sprintf("%d/%d", i+1, n)
+		}
+	}
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	rows := (n + cols - 1) / cols
+
+	// The label strip is sized relative to the codes.
+	scale := max(1, size/128)
+	labelHeight := (glyphHeight + 4) * scale
+	cellH := size + labelHeight
+
+	dst := image.NewRGBA(image.Rect(0, 0, cols*size, rows*cellH))
 	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
 
 	for i, q := range codes {
 		r := i / cols
 		c := i % cols
 		img := q.Image(size)
-		dp := image.Point{c * size, r * size}
+		dp := image.Point{c * size, r * cellH}
 		rect := image.Rect(dp.X, dp.Y, dp.X+size, dp.Y+size)
 		draw.Draw(dst, rect, img, image.Point{}, draw.Over)
+
+		// Shrink long labels where possible, then centre them in the strip.
+		labelScale := scale
+		for labelScale > 1 && textWidth(labels[i], labelScale) > size {
+			labelScale--
+		}
+
+		label := dst.SubImage(image.Rect(dp.X, dp.Y+size, dp.X+size, dp.Y+cellH)).(*image.RGBA)
+		p := image.Point{
+			X: dp.X + (size-textWidth(labels[i], labelScale))/2,
+			Y: dp.Y + size + (labelHeight-glyphHeight*labelScale)/2,
+		}
+		drawText(label, labels[i], p, labelScale, &image.Uniform{color.Black})
+	}
+	return dst, nil
+}
+
+// ContactSheet arranges multiple QR code images into a single printable
+// "contact sheet" image, e.g. of the output of EncodeMulti(), with each code
+// numbered and a title banner across the top.
+//
+// Each cell is as drawn by GridImageWithLabels(), a size pixel QR Code above
+// its label "1", "2", etc. The cells are separated by a gutter of size/16
+// pixels (see GridImageOptions), and cols is as for GridImage. The title is
+// drawn centred at twice the label scale, wrapped onto up to three lines. An
+// empty title omits the banner.
+//
+// An empty image is returned if codes is empty or contains nil QR Codes, or
+// size is not positive.
+func ContactSheet(codes []*QRCode, size int, cols int, title string) image.Image {
+	if len(codes) == 0 || size <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	cells := make([]image.Image, len(codes))
+	for i, q := range codes {
+		if q == nil {
+			return image.NewRGBA(image.Rect(0, 0, 0, 0))
+		}
+
+		cell, err := GridImageWithLabels([]*QRCode{q}, size, 1, []string{strconv.Itoa(i + 1)})
+		if err != nil {
+			return image.NewRGBA(image.Rect(0, 0, 0, 0))
+		}
+		cells[i] = cell
 	}
+
+	grid := GridOf(cells, cols, size/16, color.White)
+
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return grid
+	}
+
+	width := grid.Bounds().Dx()
+	scale := 2 * max(1, size/128)
+	margin := (glyphWidth + glyphSpacing) * scale
+	lineHeight := (glyphHeight + 3) * scale
+
+	lines := wrapText(title, scale, width-2*margin, maxCaptionLines)
+	banner := margin + len(lines)*lineHeight
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, banner+grid.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, grid.Bounds().Add(image.Point{0, banner}), grid, image.Point{}, draw.Src)
+
+	for i, line := range lines {
+		p := image.Point{
+			X: (width - textWidth(line, scale)) / 2,
+			Y: margin + i*lineHeight,
+		}
+		drawText(dst, line, p, scale, &image.Uniform{color.Black})
+	}
+
 	return dst
 }
 
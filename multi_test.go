@@ -0,0 +1,736 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+)
+
+func TestEncodeMultiMinimalChunks(t *testing.T) {
+	// Lower case letters are encoded in byte mode, so chunk capacity is
+	// exactly MaxByteCapacity.
+	letters := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteByte(byte('a' + i%26))
+		}
+		return b.String()
+	}
+
+	for _, level := range []RecoveryLevel{Low, Medium, High, Highest} {
+		capacity := MaxByteCapacity(level)
+
+		for _, n := range []int{2000, 2 * capacity} {
+			content := letters(n)
+
+			codes, err := EncodeMulti(content, level)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			// A safety margin of 50 bytes per chunk would need more codes for
+			// exactly 2 * capacity bytes.
+			minimum := (n + capacity - 1) / capacity
+			margin := (n + capacity - 51) / (capacity - 50)
+			if len(codes) != minimum || (n == 2*capacity && len(codes) >= margin) {
+				t.Errorf("level %d, %d bytes: got %d codes, expected %d", level, n, len(codes), minimum)
+			}
+
+			var joined string
+			for i, q := range codes {
+				joined += q.Content
+
+				// Each chunk except the last is the longest that fits.
+				if i < len(codes)-1 {
+					if _, err := New(q.Content+content[len(joined):len(joined)+1], level); err == nil {
+						t.Errorf("level %d, %d bytes: chunk %d could be longer", level, n, i)
+					}
+				}
+			}
+
+			if joined != content {
+				t.Errorf("level %d, %d bytes: chunks do not reassemble to the content", level, n)
+			}
+		}
+	}
+
+	// Rune boundaries are preserved.
+	content := strings.Repeat("é", MaxByteCapacity(Highest))
+	codes, err := EncodeMulti(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i, q := range codes {
+		if !utf8.ValidString(q.Content) {
+			t.Errorf("chunk %d splits a rune", i)
+		}
+	}
+}
+
+func TestEstimateChunkCount(t *testing.T) {
+	payloads := []string{
+		"",
+		"short",
+		strings.Repeat("0123456789", 2000),
+		strings.Repeat("HELLO WORLD 123 ", 800),
+		strings.Repeat("lower case letters ", 700),
+		strings.Repeat("Mixed: ABC123 grüße, 茗荷! ", 300),
+		strings.Repeat("\x93\xfa\x96\x7b", 1500),
+	}
+
+	for _, level := range []RecoveryLevel{Low, Medium, High, Highest} {
+		for _, content := range payloads {
+			codes, err := EncodeMulti(content, level)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if got := EstimateChunkCount(content, level); got != len(codes) {
+				t.Errorf("level %d, %d bytes: got estimate %d, expected %d", level, len(content), got, len(codes))
+			}
+		}
+	}
+
+	if got := EstimateChunkCount("content", RecoveryLevel(7)); got != 0 {
+		t.Errorf("invalid level: got %d, expected 0", got)
+	}
+}
+
+func TestEncodeStructuredAppend(t *testing.T) {
+	content := strings.Repeat("Structured append test, ", 300)
+
+	var parity byte
+	for i := 0; i < len(content); i++ {
+		parity ^= content[i]
+	}
+
+	codes, err := EncodeStructuredAppend(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(codes) < 2 {
+		t.Fatalf("got %d codes, expected at least 2", len(codes))
+	}
+
+	var joined string
+	for i, q := range codes {
+		joined += q.Content
+
+		// Mode indicator 0011, index, total-1, parity.
+		header := q.data.Substr(0, 20)
+
+		if got := header.Substr(0, 4); !got.Equals(bitset.New(b0, b0, b1, b1)) {
+			t.Errorf("code %d: got mode indicator %s, expected 0011", i, got.String())
+		}
+
+		if got := int(header.ByteAt(4) >> 4); got != i {
+			t.Errorf("code %d: got sequence index %d", i, got)
+		}
+
+		if got := int(header.ByteAt(8)>>4) + 1; got != len(codes) {
+			t.Errorf("code %d: got total %d, expected %d", i, got, len(codes))
+		}
+
+		if got := header.ByteAt(12); got != parity {
+			t.Errorf("code %d: got parity %#x, expected %#x", i, got, parity)
+		}
+	}
+
+	if joined != content {
+		t.Errorf("chunks do not reassemble to the original content")
+	}
+}
+
+func TestEncodeStructuredAppendTooLong(t *testing.T) {
+	content := strings.Repeat("#", MaxByteCapacity(Highest)*maxStructuredAppendSymbols)
+
+	if _, err := EncodeStructuredAppend(content, Highest); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("got %v, expected content too long error", err)
+	}
+}
+
+func TestGridImageWithLabels(t *testing.T) {
+	var codes []*QRCode
+	for _, content := range []string{"one", "two", "three"} {
+		q, err := New(content, Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		codes = append(codes, q)
+	}
+
+	const size = 256
+
+	img, err := GridImageWithLabels(codes, size, 2, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain := GridImage(codes, size, 2)
+
+	b := img.Bounds()
+	if b.Dx() != plain.Bounds().Dx() {
+		t.Errorf("got width %d, expected %d", b.Dx(), plain.Bounds().Dx())
+	}
+
+	cellH := b.Dy() / 2
+	if b.Dy()%2 != 0 || cellH <= size {
+		t.Fatalf("got height %d, expected two rows taller than %d", b.Dy(), size)
+	}
+
+	// Each label strip has some dark pixels, and the auto-numbered labels
+	// differ.
+	strip := func(i int) []bool {
+		x0, y0 := (i%2)*size, (i/2)*cellH+size
+		var pixels []bool
+		for y := y0; y < y0+cellH-size; y++ {
+			for x := x0; x < x0+size; x++ {
+				r, _, _, _ := img.At(x, y).RGBA()
+				pixels = append(pixels, r < 0x8000)
+			}
+		}
+		return pixels
+	}
+
+	strips := [][]bool{strip(0), strip(1), strip(2)}
+	for i, s := range strips {
+		numDark := 0
+		for _, v := range s {
+			if v {
+				numDark++
+			}
+		}
+		if numDark == 0 {
+			t.Errorf("label %d is blank", i)
+		}
+	}
+
+	different := false
+	for i := range strips[0] {
+		if strips[0][i] != strips[1][i] {
+			different = true
+		}
+	}
+	if !different {
+		t.Errorf("labels 1/3 and 2/3 are identical")
+	}
+
+	if _, err := GridImageWithLabels(codes, size, 2, []string{"a", "b"}); err == nil {
+		t.Errorf("mismatched labels: got success, expected error")
+	}
+
+	if _, err := GridImageWithLabels(codes, size, 2, []string{"a", "b", "c"}); err != nil {
+		t.Errorf("custom labels: got error %s", err.Error())
+	}
+
+	if _, err := GridImageWithLabels([]*QRCode{codes[0], nil}, size, 2, nil); err == nil {
+		t.Errorf("nil QR Code: got success, expected error")
+	}
+}
+
+func TestContactSheet(t *testing.T) {
+	var codes []*QRCode
+	for _, content := range []string{"one", "two", "three"} {
+		q, err := New(content, Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		codes = append(codes, q)
+	}
+
+	const size = 256
+	const gutter = size / 16
+
+	// Cells are the code above its label strip.
+	cell, err := GridImageWithLabels(codes[:1], size, 1, []string{"1"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cellH := cell.Bounds().Dy()
+	if cellH <= size {
+		t.Fatalf("got cell height %d, expected more than %d for the label", cellH, size)
+	}
+
+	untitled := ContactSheet(codes, size, 2, "")
+	expected := image.Point{2*(size+gutter) + gutter, 2*(cellH+gutter) + gutter}
+	if got := untitled.Bounds().Size(); got != expected {
+		t.Errorf("untitled: got size %v, expected %v", got, expected)
+	}
+
+	img := ContactSheet(codes, size, 2, "Backup codes")
+	b := img.Bounds()
+	banner := b.Dy() - expected.Y
+	if b.Dx() != expected.X || banner <= 0 {
+		t.Fatalf("got size %v, expected width %d and a title banner above %d", b.Size(), expected.X, expected.Y)
+	}
+
+	// The title is drawn in the banner, and the grid below it is unchanged.
+	numDark := 0
+	for y := 0; y < banner; y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				numDark++
+			}
+		}
+	}
+	if numDark == 0 {
+		t.Errorf("title banner is blank")
+	}
+
+	for y := 0; y < expected.Y; y += 7 {
+		for x := 0; x < expected.X; x += 7 {
+			if img.At(x, y+banner) != untitled.At(x, y) {
+				t.Fatalf("pixel (%d, %d) differs from the untitled sheet", x, y)
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		name  string
+		codes []*QRCode
+		size  int
+	}{
+		{"empty", nil, size},
+		{"zero size", codes, 0},
+		{"nil code", []*QRCode{codes[0], nil}, size},
+	} {
+		if b := ContactSheet(test.codes, test.size, 2, "title").Bounds(); !b.Empty() {
+			t.Errorf("%s: got bounds %v, expected empty", test.name, b)
+		}
+	}
+}
+
+func TestGridImageWithOptions(t *testing.T) {
+	var codes []*QRCode
+	for i := 0; i < 6; i++ {
+		q, err := New(fmt.Sprintf("code %d", i), Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		codes = append(codes, q)
+	}
+
+	const size = 256
+	const gutter = 10
+
+	img := GridImageWithOptions(codes, size, GridImageOptions{Cols: 3, Gutter: gutter})
+
+	// 3x2 cells, 2 inner and 2 outer gutters horizontally, 1 inner and 2 outer
+	// vertically.
+	if got, expected := img.Bounds().Dx(), 3*size+4*gutter; got != expected {
+		t.Errorf("got width %d, expected %d", got, expected)
+	}
+	if got, expected := img.Bounds().Dy(), 2*size+3*gutter; got != expected {
+		t.Errorf("got height %d, expected %d", got, expected)
+	}
+
+	// The gutters are background coloured.
+	for _, p := range []image.Point{{0, 0}, {gutter + size, gutter}, {gutter, gutter + size}} {
+		if r, _, _, _ := img.At(p.X, p.Y).RGBA(); r != 0xffff {
+			t.Errorf("gutter pixel %v is not white", p)
+		}
+	}
+
+	// A zero gutter matches GridImage.
+	plain := GridImage(codes, size, 3)
+	zero := GridImageWithOptions(codes, size, GridImageOptions{Cols: 3})
+	if !bytes.Equal(plain.(*image.RGBA).Pix, zero.(*image.RGBA).Pix) {
+		t.Errorf("zero gutter grid differs from GridImage")
+	}
+}
+
+func TestGridOf(t *testing.T) {
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+	grey := color.RGBA{0x80, 0x80, 0x80, 0xff}
+
+	tile := func(w, h int, c color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+		return img
+	}
+
+	q, err := New("grid of", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A QR Code, a wide label tile, and a tall tile with a non-zero origin.
+	code := q.Image(-10)
+	tall := tile(50, 700, blue).(*image.RGBA).SubImage(image.Rect(0, 100, 50, 700))
+	imgs := []image.Image{code, tile(800, 40, red), tall}
+
+	const gutter = 5
+	img := GridOf(imgs, 2, gutter, grey)
+
+	// Cells are 800x610: 2 columns, 2 rows.
+	cellW, cellH := 800, code.Bounds().Dy()
+	if got, expected := img.Bounds().Dx(), 2*cellW+3*gutter; got != expected {
+		t.Errorf("got width %d, expected %d", got, expected)
+	}
+	if got, expected := img.Bounds().Dy(), 2*cellH+3*gutter; got != expected {
+		t.Errorf("got height %d, expected %d", got, expected)
+	}
+
+	tests := []struct {
+		p        image.Point
+		expected color.Color
+	}{
+		// Gutter.
+		{image.Point{0, 0}, grey},
+		// The QR Code is centred horizontally, its quiet zone is white.
+		{image.Point{gutter + (cellW-code.Bounds().Dx())/2, gutter}, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{image.Point{gutter + 10, gutter}, grey},
+		// The label tile is centred vertically.
+		{image.Point{2*gutter + cellW, gutter + cellH/2}, red},
+		{image.Point{2*gutter + cellW, gutter}, grey},
+		// The tall tile is drawn from its bounds.
+		{image.Point{gutter + cellW/2, 2*gutter + cellH + cellH/2}, blue},
+		{image.Point{gutter + cellW/2 - 30, 2*gutter + cellH + cellH/2}, grey},
+		// The empty cell.
+		{image.Point{2*gutter + cellW + 10, 2*gutter + cellH + 10}, grey},
+	}
+
+	for _, test := range tests {
+		if got := color.RGBAModel.Convert(img.At(test.p.X, test.p.Y)); got != test.expected {
+			t.Errorf("pixel %v: got %v, expected %v", test.p, got, test.expected)
+		}
+	}
+
+	if b := GridOf(nil, 2, gutter, grey).Bounds(); !b.Empty() {
+		t.Errorf("no images: got bounds %v, expected empty", b)
+	}
+}
+
+func TestGridImageSharedBorder(t *testing.T) {
+	var codes []*QRCode
+	for i := 0; i < 4; i++ {
+		q, err := New(fmt.Sprintf("shared border %d", i), Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.QuietZone = 4
+		codes = append(codes, q)
+	}
+
+	symbolSize := len(codes[0].Matrix())
+	size := symbolSize * 12
+	border := 4 * 12
+
+	perCell := GridImageWithOptions(codes, size, GridImageOptions{Cols: 2})
+	shared := GridImageWithOptions(codes, size, GridImageOptions{Cols: 2, SharedBorder: true})
+
+	if got, expected := perCell.Bounds().Size(), (image.Point{2 * size, 2 * size}); got != expected {
+		t.Errorf("per cell borders: got size %v, expected %v", got, expected)
+	}
+	if got, expected := shared.Bounds().Size(), (image.Point{2*size + 2*border, 2*size + 2*border}); got != expected {
+		t.Errorf("shared border: got size %v, expected %v", got, expected)
+	}
+
+	dark := func(img image.Image, x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r < 0x8000
+	}
+
+	// With a shared border, the cells start with their finder patterns, and
+	// their modules are larger.
+	for _, p := range []image.Point{{border, border}, {border + size, border}, {border, border + size}} {
+		if !dark(shared, p.X, p.Y) {
+			t.Errorf("shared border: pixel %v is not a finder pattern", p)
+		}
+	}
+	if dark(shared, border-1, border) || dark(perCell, 0, 0) {
+		t.Errorf("quiet zone is not light")
+	}
+
+	// The codes themselves are unchanged.
+	for _, q := range codes {
+		if q.DisableBorder || len(q.Bitmap()) != symbolSize+8 {
+			t.Errorf("code modified by GridImageWithOptions")
+		}
+	}
+
+	// The data of codes not yet encoded is not shared with the copies.
+	fresh, err := New("shared border data", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	dataLen := fresh.data.Len()
+	GridImageWithOptions([]*QRCode{fresh}, size, GridImageOptions{Cols: 1, SharedBorder: true})
+	if fresh.data.Len() != dataLen || fresh.symbol != nil {
+		t.Errorf("code data modified by GridImageWithOptions")
+	}
+
+	// Codes without quiet zones need no shared border.
+	for _, q := range codes {
+		q.DisableBorder = true
+	}
+	if got := GridImageWithOptions(codes, size, GridImageOptions{Cols: 2, SharedBorder: true}).Bounds().Dx(); got != 2*size {
+		t.Errorf("borderless codes: got width %d, expected %d", got, 2*size)
+	}
+}
+
+func TestGridImageChecked(t *testing.T) {
+	q, err := New("checked", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		codes []*QRCode
+		size  int
+	}{
+		{"empty", nil, 256},
+		{"zero size", []*QRCode{q}, 0},
+		{"negative size", []*QRCode{q}, -10},
+		{"nil code", []*QRCode{q, nil}, 256},
+	}
+
+	for _, test := range tests {
+		if _, err := GridImageChecked(test.codes, test.size, 2); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+
+		// GridImage returns an empty image instead.
+		if b := GridImage(test.codes, test.size, 2).Bounds(); !b.Empty() {
+			t.Errorf("%s: GridImage got bounds %v, expected empty", test.name, b)
+		}
+	}
+
+	img, err := GridImageChecked([]*QRCode{q, q, q}, 256, 2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got := img.Bounds().Size(); got != (image.Point{512, 512}) {
+		t.Errorf("got size %v, expected 512x512", got)
+	}
+}
+
+func TestGridImageUniformPitch(t *testing.T) {
+	const modulePixels = 3
+
+	var codes []*QRCode
+	for _, content := range []string{"small", strings.Repeat("larger content ", 10), "x"} {
+		q, err := New(content, Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		codes = append(codes, q)
+	}
+
+	if codes[0].VersionNumber == codes[1].VersionNumber {
+		t.Fatalf("got version %d for both codes, expected different versions", codes[0].VersionNumber)
+	}
+
+	cell := 0
+	for _, q := range codes {
+		cell = max(cell, len(q.Bitmap())*modulePixels)
+	}
+
+	img := GridImageUniformPitch(codes, modulePixels, 2)
+	if got := img.Bounds().Size(); got != (image.Point{2 * cell, 2 * cell}) {
+		t.Fatalf("got size %v, expected %dx%d", got, 2*cell, 2*cell)
+	}
+
+	for i, q := range codes {
+		bitmap := q.Bitmap()
+		width := len(bitmap) * modulePixels
+
+		// Each code is centred in its cell, at modulePixels per module.
+		origin := image.Point{
+			X: (i%2)*cell + (cell-width)/2,
+			Y: (i/2)*cell + (cell-width)/2,
+		}
+
+		for y, row := range bitmap {
+			for x, dark := range row {
+				p := origin.Add(image.Point{x*modulePixels + modulePixels/2, y*modulePixels + modulePixels/2})
+				r, _, _, _ := img.At(p.X, p.Y).RGBA()
+
+				if got := r == 0; got != dark {
+					t.Fatalf("code %d module (%d, %d): got dark=%t, expected %t", i, x, y, got, dark)
+				}
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		name         string
+		codes        []*QRCode
+		modulePixels int
+	}{
+		{"empty", nil, modulePixels},
+		{"zero module size", codes, 0},
+		{"nil code", []*QRCode{codes[0], nil}, modulePixels},
+	} {
+		if b := GridImageUniformPitch(test.codes, test.modulePixels, 2).Bounds(); !b.Empty() {
+			t.Errorf("%s: got bounds %v, expected empty", test.name, b)
+		}
+	}
+}
+
+// cancelAfterContext is a context which is cancelled once Err() has been
+// called n times.
+type cancelAfterContext struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestEncodeMultiContext(t *testing.T) {
+	content := strings.Repeat("Cancellable content. ", 300)
+
+	expected, err := EncodeMulti(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(expected) < 3 {
+		t.Fatalf("got %d codes, expected 3 or more", len(expected))
+	}
+
+	codes, err := EncodeMultiContext(context.Background(), content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(codes) != len(expected) {
+		t.Fatalf("got %d codes, expected %d", len(codes), len(expected))
+	}
+	for i, q := range codes {
+		if q.Content != expected[i].Content {
+			t.Errorf("code %d: got content %q, expected %q", i, q.Content, expected[i].Content)
+		}
+	}
+
+	// Cancelled after the split and the first chunk.
+	ctx := &cancelAfterContext{Context: context.Background(), n: 2}
+	if codes, err := EncodeMultiContext(ctx, content, Highest); !errors.Is(err, context.Canceled) || codes != nil {
+		t.Errorf("cancelled partway: got %d codes, %v, expected %v", len(codes), err, context.Canceled)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EncodeMultiContext(cancelled, content, Highest); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestEncodeMultiStream(t *testing.T) {
+	content := strings.Repeat("Streamed content. ", 300)
+
+	codes, err := EncodeMulti(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var indices []int
+	var joined string
+	err = EncodeMultiStream(content, Highest, -2, func(index int, data []byte) error {
+		indices = append(indices, index)
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		s, err := Decode(img)
+		if err != nil {
+			return err
+		}
+		joined += s
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(indices) != len(codes) {
+		t.Fatalf("got %d chunks, expected %d", len(indices), len(codes))
+	}
+	for i, index := range indices {
+		if index != i {
+			t.Errorf("got chunk index %d, expected %d", index, i)
+		}
+	}
+
+	if joined != content {
+		t.Errorf("chunks do not reassemble to the original content")
+	}
+
+	// Errors from fn stop encoding.
+	stop := errors.New("stop")
+	calls := 0
+	err = EncodeMultiStream(content, Highest, -2, func(index int, data []byte) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("got error %v after %d calls, expected stop after 1 call", err, calls)
+	}
+
+	// An invalid level is an error, without calling fn.
+	calls = 0
+	err = EncodeMultiStream(content, RecoveryLevel(9), -2, func(index int, data []byte) error {
+		calls++
+		return nil
+	})
+	if err == nil || calls != 0 {
+		t.Errorf("invalid level: got error %v after %d calls, expected error after 0 calls", err, calls)
+	}
+}
+
+func TestEncodeMultiConcurrent(t *testing.T) {
+	content := strings.Repeat("Concurrently encoded content. ", 1000)
+
+	serial, err := EncodeMulti(content, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(serial) < 10 {
+		t.Fatalf("got %d chunks, expected at least 10", len(serial))
+	}
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		codes, err := EncodeMultiConcurrent(content, Highest, workers)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(codes) != len(serial) {
+			t.Fatalf("%d workers: got %d codes, expected %d", workers, len(codes), len(serial))
+		}
+
+		for i, q := range codes {
+			if q.Content != serial[i].Content || q.VersionNumber != serial[i].VersionNumber {
+				t.Errorf("%d workers: code %d differs from EncodeMulti", workers, i)
+			}
+
+			if q.ToSmallString(false) != serial[i].ToSmallString(false) {
+				t.Errorf("%d workers: code %d symbol differs from EncodeMulti", workers, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Payload builders.
+//
+// QR Code readers recognise several de facto standard content formats, and
+// offer an action for each (e.g. join a WiFi network). The functions below
+// build these payloads, taking care of the escaping rules of each format.
+
+// WiFiAuthType is the authentication type of a WiFi network.
+type WiFiAuthType int
+
+const (
+	// WPA/WPA2 authentication.
+	WPA WiFiAuthType = iota
+
+	// WEP authentication.
+	WEP
+
+	// An open network.
+	NoPassword
+)
+
+// wifiEscaper escapes the special characters of WIFI: payload fields.
+var wifiEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	`:`, `\:`,
+	`"`, `\"`,
+)
+
+// EncodeWiFi constructs a QRCode which joins a WiFi network when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeWiFi("my network", "my password", qrcode.WPA, false, qrcode.Medium)
+//
+// The password is ignored for NoPassword networks. An error occurs if ssid is
+// empty.
+func EncodeWiFi(ssid, password string, auth WiFiAuthType, hidden bool, level RecoveryLevel) (*QRCode, error) {
+	content, err := wifiPayload(ssid, password, auth, hidden)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// wifiPayload returns the WIFI: payload for a network, e.g.
+// "WIFI:T:WPA;S:ssid;P:password;;".
+func wifiPayload(ssid, password string, auth WiFiAuthType, hidden bool) (string, error) {
+	if ssid == "" {
+		return "", errors.New("empty WiFi SSID")
+	}
+
+	var b strings.Builder
+	b.WriteString("WIFI:")
+
+	switch auth {
+	case WPA:
+		b.WriteString("T:WPA;")
+	case WEP:
+		b.WriteString("T:WEP;")
+	case NoPassword:
+		b.WriteString("T:nopass;")
+	default:
+		return "", errors.New("invalid WiFi authentication type")
+	}
+
+	b.WriteString("S:" + wifiEscaper.Replace(ssid) + ";")
+
+	if auth != NoPassword {
+		b.WriteString("P:" + wifiEscaper.Replace(password) + ";")
+	}
+
+	if hidden {
+		b.WriteString("H:true;")
+	}
+
+	b.WriteString(";")
+
+	return b.String(), nil
+}
+
+// EncodeURL constructs a QRCode which opens a URL when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeURL("https://example.org", qrcode.Medium)
+//
+// An error occurs if u is not an absolute URL, e.g. "example.org".
+func EncodeURL(u string, level RecoveryLevel) (*QRCode, error) {
+	content, err := urlPayload(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// urlPayload validates the URL u, which is used as the payload unchanged.
+func urlPayload(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Scheme == "" || (parsed.Host == "" && parsed.Opaque == "") {
+		return "", fmt.Errorf("%q is not an absolute URL", u)
+	}
+
+	return u, nil
+}
+
+// EncodeMailto constructs a QRCode which composes an email when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeMailto("info@example.org", "Hello", "", qrcode.Medium)
+//
+// The subject and body are optional. An error occurs if to is not an email
+// address.
+func EncodeMailto(to, subject, body string, level RecoveryLevel) (*QRCode, error) {
+	content, err := mailtoPayload(to, subject, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// mailtoPayload returns the mailto: payload for an email, e.g.
+// "mailto:info@example.org?subject=Hello%20world".
+func mailtoPayload(to, subject, body string) (string, error) {
+	address, err := mail.ParseAddress(to)
+	if err != nil || address.Address != to {
+		return "", fmt.Errorf("invalid email address %q", to)
+	}
+
+	var params []string
+	if subject != "" {
+		params = append(params, "subject="+mailtoEscape(subject))
+	}
+	if body != "" {
+		params = append(params, "body="+mailtoEscape(body))
+	}
+
+	payload := "mailto:" + to
+	if len(params) > 0 {
+		payload += "?" + strings.Join(params, "&")
+	}
+
+	return payload, nil
+}
+
+// mailtoEscape percent-encodes a mailto: (or sms:) header value. Spaces are
+// encoded as %20, as "+" is not decoded by mail clients.
+func mailtoEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// EncodeGeo constructs a QRCode which shows a location on a map when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeGeo(51.5007, -0.1246, qrcode.Medium)
+//
+// lat and lon are in decimal degrees. An error occurs if lat is outside
+// -90..90, or lon is outside -180..180.
+func EncodeGeo(lat, lon float64, level RecoveryLevel) (*QRCode, error) {
+	content, err := geoPayload(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// geoPayload returns the geo: payload for a location, e.g.
+// "geo:51.5007,-0.1246".
+func geoPayload(lat, lon float64) (string, error) {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return "", fmt.Errorf("invalid latitude %v (expected -90 to 90)", lat)
+	}
+
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return "", fmt.Errorf("invalid longitude %v (expected -180 to 180)", lon)
+	}
+
+	return "geo:" + strconv.FormatFloat(lat, 'f', -1, 64) + "," +
+		strconv.FormatFloat(lon, 'f', -1, 64), nil
+}
+
+// EncodeTel constructs a QRCode which calls a telephone number when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeTel("+44 20 7946 0000", qrcode.Medium)
+//
+// Spaces and dashes are removed from number. An error occurs if number
+// contains other characters than digits and a leading "+".
+func EncodeTel(number string, level RecoveryLevel) (*QRCode, error) {
+	content, err := telPayload(number)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// telPayload returns the tel: payload for a telephone number, e.g.
+// "tel:+442079460000".
+func telPayload(number string) (string, error) {
+	n, err := normalizePhoneNumber(number)
+	if err != nil {
+		return "", err
+	}
+
+	return "tel:" + n, nil
+}
+
+// EncodeSMS constructs a QRCode which composes a text message when scanned.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.EncodeSMS("+44 20 7946 0000", "Hello", qrcode.Medium)
+//
+// The message is optional. The number is normalised as for EncodeTel().
+func EncodeSMS(number, message string, level RecoveryLevel) (*QRCode, error) {
+	content, err := smsPayload(number, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// smsPayload returns the sms: payload (RFC 5724) for a text message, e.g.
+// "sms:+442079460000?body=Hello%20world".
+func smsPayload(number, message string) (string, error) {
+	n, err := normalizePhoneNumber(number)
+	if err != nil {
+		return "", err
+	}
+
+	payload := "sms:" + n
+	if message != "" {
+		payload += "?body=" + mailtoEscape(message)
+	}
+
+	return payload, nil
+}
+
+// normalizePhoneNumber removes spaces and dashes from a telephone number, and
+// checks the remainder is digits with an optional leading "+".
+func normalizePhoneNumber(number string) (string, error) {
+	n := strings.NewReplacer(" ", "", "-", "").Replace(number)
+
+	digits := strings.TrimPrefix(n, "+")
+	if digits == "" {
+		return "", fmt.Errorf("invalid telephone number %q", number)
+	}
+
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid telephone number %q", number)
+		}
+	}
+
+	return n, nil
+}
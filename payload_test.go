@@ -0,0 +1,245 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWiFiPayload(t *testing.T) {
+	tests := []struct {
+		ssid     string
+		password string
+		auth     WiFiAuthType
+		hidden   bool
+		expected string
+	}{
+		{
+			"home",
+			"secret",
+			WPA,
+			false,
+			"WIFI:T:WPA;S:home;P:secret;;",
+		},
+		{
+			"cafe;wifi",
+			`p"a:s,s\`,
+			WEP,
+			false,
+			`WIFI:T:WEP;S:cafe\;wifi;P:p\"a\:s\,s\\;;`,
+		},
+		{
+			"hidden",
+			"ignored",
+			NoPassword,
+			true,
+			"WIFI:T:nopass;S:hidden;H:true;;",
+		},
+	}
+
+	for _, test := range tests {
+		payload, err := wifiPayload(test.ssid, test.password, test.auth, test.hidden)
+		if err != nil {
+			t.Errorf("%q: got error %s", test.ssid, err.Error())
+			continue
+		}
+
+		if payload != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.ssid, payload, test.expected)
+		}
+	}
+
+	if _, err := EncodeWiFi("", "secret", WPA, false, Medium); err == nil {
+		t.Errorf("empty SSID: got success, expected error")
+	}
+
+	q, err := EncodeWiFi("cafe;wifi", "secret", WPA, true, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != `WIFI:T:WPA;S:cafe\;wifi;P:secret;H:true;;` {
+		t.Errorf("got content %q", q.Content)
+	}
+}
+
+func TestURLPayload(t *testing.T) {
+	valid := []string{
+		"https://example.org",
+		"http://example.org/path?query=1#fragment",
+		"urn:isbn:0451450523",
+	}
+
+	for _, u := range valid {
+		q, err := EncodeURL(u, Medium)
+		if err != nil {
+			t.Errorf("%q: got error %s", u, err.Error())
+			continue
+		}
+
+		if q.Content != u {
+			t.Errorf("%q: got content %q", u, q.Content)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"example.org",
+		"/relative/path",
+		"http://[::1",
+	}
+
+	for _, u := range invalid {
+		if _, err := EncodeURL(u, Medium); err == nil {
+			t.Errorf("%q: got success, expected error", u)
+		}
+	}
+}
+
+func TestMailtoPayload(t *testing.T) {
+	tests := []struct {
+		to       string
+		subject  string
+		body     string
+		expected string
+	}{
+		{
+			"info@example.org",
+			"",
+			"",
+			"mailto:info@example.org",
+		},
+		{
+			"info@example.org",
+			"Hello world",
+			"",
+			"mailto:info@example.org?subject=Hello%20world",
+		},
+		{
+			"info@example.org",
+			"Q&A",
+			"a=b?\nc+d \u00fc",
+			"mailto:info@example.org?subject=Q%26A&body=a%3Db%3F%0Ac%2Bd%20%C3%BC",
+		},
+		{
+			"info@example.org",
+			"",
+			"body only",
+			"mailto:info@example.org?body=body%20only",
+		},
+	}
+
+	for _, test := range tests {
+		payload, err := mailtoPayload(test.to, test.subject, test.body)
+		if err != nil {
+			t.Errorf("%q: got error %s", test.subject, err.Error())
+			continue
+		}
+
+		if payload != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.subject, payload, test.expected)
+		}
+	}
+
+	for _, to := range []string{"", "not an address", "Info <info@example.org>"} {
+		if _, err := EncodeMailto(to, "", "", Medium); err == nil {
+			t.Errorf("%q: got success, expected error", to)
+		}
+	}
+}
+
+func TestGeoPayload(t *testing.T) {
+	tests := []struct {
+		lat      float64
+		lon      float64
+		expected string
+	}{
+		{51.5007, -0.1246, "geo:51.5007,-0.1246"},
+		{0, 0, "geo:0,0"},
+		{-90, 180, "geo:-90,180"},
+	}
+
+	for _, test := range tests {
+		q, err := EncodeGeo(test.lat, test.lon, Medium)
+		if err != nil {
+			t.Errorf("%v,%v: got error %s", test.lat, test.lon, err.Error())
+			continue
+		}
+
+		if q.Content != test.expected {
+			t.Errorf("%v,%v: got %q, expected %q", test.lat, test.lon, q.Content, test.expected)
+		}
+	}
+
+	invalid := [][2]float64{
+		{90.1, 0},
+		{-91, 0},
+		{0, 180.5},
+		{0, -181},
+		{math.NaN(), 0},
+	}
+
+	for _, test := range invalid {
+		if _, err := EncodeGeo(test[0], test[1], Medium); err == nil {
+			t.Errorf("%v,%v: got success, expected error", test[0], test[1])
+		}
+	}
+}
+
+func TestTelPayload(t *testing.T) {
+	tests := []struct {
+		number   string
+		expected string
+	}{
+		{"+44 20 7946 0000", "tel:+442079460000"},
+		{"555-0100", "tel:5550100"},
+		{"  +1 555-0100 ", "tel:+15550100"},
+	}
+
+	for _, test := range tests {
+		q, err := EncodeTel(test.number, Medium)
+		if err != nil {
+			t.Errorf("%q: got error %s", test.number, err.Error())
+			continue
+		}
+
+		if q.Content != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.number, q.Content, test.expected)
+		}
+	}
+
+	for _, number := range []string{"", "+", " - ", "01234 ext 5", "1+2", "++1"} {
+		if _, err := EncodeTel(number, Medium); err == nil {
+			t.Errorf("%q: got success, expected error", number)
+		}
+	}
+}
+
+func TestSMSPayload(t *testing.T) {
+	tests := []struct {
+		number   string
+		message  string
+		expected string
+	}{
+		{"+44 20 7946 0000", "", "sms:+442079460000"},
+		{"555-0100", "Fish & chips?", "sms:5550100?body=Fish%20%26%20chips%3F"},
+	}
+
+	for _, test := range tests {
+		payload, err := smsPayload(test.number, test.message)
+		if err != nil {
+			t.Errorf("%q: got error %s", test.message, err.Error())
+			continue
+		}
+
+		if payload != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.message, payload, test.expected)
+		}
+	}
+
+	if _, err := EncodeSMS("call me", "hello", Medium); err == nil {
+		t.Errorf("invalid number: got success, expected error")
+	}
+}
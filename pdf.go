@@ -0,0 +1,193 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// PDF page sizes, in points (1/72 inch).
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+
+	LetterWidth  = 612
+	LetterHeight = 792
+)
+
+// pdfPageMargin is the minimum margin around the QR Code on each PDF page, in
+// points.
+const pdfPageMargin = 36
+
+// PDFOptions are the page settings of MultiPDFWithOptions.
+type PDFOptions struct {
+	// Page width and height in points (1/72 inch), e.g. LetterWidth and
+	// LetterHeight for US Letter pages. If both are zero, A4 is used.
+	PageWidth  float64
+	PageHeight float64
+}
+
+// MultiPDF returns a PDF document with one QR Code per A4 page, e.g. to print
+// the output of EncodeMulti().
+//
+// Each QR Code is drawn as an image of modulePixels pixels per module (see
+// ImageExact()), centred on its page. The images are printed at 72 pixels per
+// inch, or scaled down to fit within a half inch margin if larger.
+//
+// An error occurs if codes is empty or contains a nil QR Code, or modulePixels
+// is less than 1.
+func MultiPDF(codes []*QRCode, modulePixels int) ([]byte, error) {
+	return MultiPDFWithOptions(codes, modulePixels, PDFOptions{})
+}
+
+// MultiPDFWithOptions returns a PDF document with one QR Code per page, as
+// MultiPDF, with the page size set by opts.
+func MultiPDFWithOptions(codes []*QRCode, modulePixels int, opts PDFOptions) ([]byte, error) {
+	if len(codes) == 0 {
+		return nil, errors.New("no QR Codes to write to a PDF")
+	}
+
+	if modulePixels < 1 {
+		return nil, fmt.Errorf("invalid module size %dpx (expected 1 or more)", modulePixels)
+	}
+
+	width, height := opts.PageWidth, opts.PageHeight
+	if width == 0 && height == 0 {
+		width, height = A4Width, A4Height
+	}
+
+	if !(width > 2*pdfPageMargin && height > 2*pdfPageMargin) {
+		return nil, fmt.Errorf("invalid PDF page size %gx%gpt (expected more than %dpt per side)",
+			width, height, 2*pdfPageMargin)
+	}
+
+	for i, q := range codes {
+		if q == nil {
+			return nil, fmt.Errorf("QR Code %d is nil", i)
+		}
+	}
+
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1 and 2 are the catalog and page tree, then each page has a page,
+	// content stream and image object.
+	const pagesObject = 2
+	pageObject := func(i int) int { return 3 + 3*i }
+
+	w.beginObject(1)
+	fmt.Fprintf(&w.buf, "<< /Type /Catalog /Pages %d 0 R >>\n", pagesObject)
+	w.endObject()
+
+	w.beginObject(pagesObject)
+	w.buf.WriteString("<< /Type /Pages /Kids [")
+	for i := range codes {
+		fmt.Fprintf(&w.buf, " %d 0 R", pageObject(i))
+	}
+	fmt.Fprintf(&w.buf, " ] /Count %d >>\n", len(codes))
+	w.endObject()
+
+	for i, q := range codes {
+		img := q.ImageExact(modulePixels)
+		b := img.Bounds()
+
+		// Opaque RGB pixels, composited over white.
+		var pixels bytes.Buffer
+		z := zlib.NewWriter(&pixels)
+		row := make([]byte, 0, 3*b.Dx())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row = row[:0]
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.RGBAModel.Convert(blendOver(img.At(x, y), color.White)).(color.RGBA)
+				row = append(row, c.R, c.G, c.B)
+			}
+			z.Write(row)
+		}
+		z.Close()
+
+		// Image size in points, at 72 pixels per inch or shrunk to fit.
+		scale := math.Min(1, math.Min((width-2*pdfPageMargin)/float64(b.Dx()),
+			(height-2*pdfPageMargin)/float64(b.Dy())))
+		imgWidth := float64(b.Dx()) * scale
+		imgHeight := float64(b.Dy()) * scale
+
+		page := pageObject(i)
+
+		w.beginObject(page)
+		fmt.Fprintf(&w.buf, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\n",
+			pagesObject, pdfNumber(width), pdfNumber(height), page+2, page+1)
+		w.endObject()
+
+		content := fmt.Sprintf("q %s 0 0 %s %s %s cm /Im0 Do Q\n",
+			pdfNumber(imgWidth), pdfNumber(imgHeight),
+			pdfNumber((width-imgWidth)/2), pdfNumber((height-imgHeight)/2))
+
+		w.beginObject(page + 1)
+		w.stream(fmt.Sprintf("<< /Length %d >>", len(content)), []byte(content))
+		w.endObject()
+
+		w.beginObject(page + 2)
+		w.stream(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+			b.Dx(), b.Dy(), pixels.Len()), pixels.Bytes())
+		w.endObject()
+	}
+
+	return w.finish(), nil
+}
+
+// pdfNumber formats v as a PDF real number, with at most two decimal places.
+func pdfNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// pdfWriter writes the objects of a PDF document, recording their offsets for
+// the cross-reference table.
+type pdfWriter struct {
+	buf bytes.Buffer
+
+	// Byte offset of each object, indexed by object number - 1.
+	offsets []int
+}
+
+// beginObject starts the object numbered n. Objects must be written in order,
+// starting from 1.
+func (w *pdfWriter) beginObject(n int) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n", n)
+}
+
+// endObject ends the current object.
+func (w *pdfWriter) endObject() {
+	w.buf.WriteString("endobj\n")
+}
+
+// stream writes a stream with the dictionary dict.
+func (w *pdfWriter) stream(dict string, data []byte) {
+	w.buf.WriteString(dict)
+	w.buf.WriteString("\nstream\n")
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\n")
+}
+
+// finish writes the cross-reference table and trailer, and returns the
+// document.
+func (w *pdfWriter) finish() []byte {
+	xref := w.buf.Len()
+
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n", len(w.offsets)+1)
+	w.buf.WriteString("0000000000 65535 f \n")
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+
+	return w.buf.Bytes()
+}
@@ -0,0 +1,131 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMultiPDF(t *testing.T) {
+	codes, err := EncodeMulti(strings.Repeat("multi page pdf ", 200), Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(codes) < 2 {
+		t.Fatalf("got %d codes, expected several", len(codes))
+	}
+
+	data, err := MultiPDF(codes, 4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("got prefix %q, expected %%PDF-", data[:8])
+	}
+	if !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Errorf("missing %%%%EOF trailer")
+	}
+
+	if n := bytes.Count(data, []byte("/Type /Page ")); n != len(codes) {
+		t.Errorf("got %d pages, expected %d", n, len(codes))
+	}
+	if !bytes.Contains(data, []byte(fmt.Sprintf("/Count %d", len(codes)))) {
+		t.Errorf("page tree count missing")
+	}
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 595.28 841.89]")) {
+		t.Errorf("expected A4 pages")
+	}
+
+	// The cross-reference table locates each object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) != 2+3*len(codes) {
+		t.Errorf("got %d xref entries, expected %d", len(entries), 2+3*len(codes))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("xref entry %d: offset %d is not object %d", i, offset, i+1)
+		}
+	}
+
+	// The first image has 4 pixels per module, and decompresses to RGB pixels.
+	size := len(codes[0].Bitmap()) * 4
+	header := fmt.Sprintf("/Width %d /Height %d /ColorSpace /DeviceRGB", size, size)
+	i := bytes.Index(data, []byte(header))
+	if i < 0 {
+		t.Fatalf("missing image %q", header)
+	}
+	start := i + bytes.Index(data[i:], []byte("stream\n")) + len("stream\n")
+	end := start + bytes.Index(data[start:], []byte("\nendstream"))
+
+	z, err := zlib.NewReader(bytes.NewReader(data[start:end]))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	pixels, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(pixels) != 3*size*size {
+		t.Errorf("got %d bytes of pixels, expected %d", len(pixels), 3*size*size)
+	}
+	if pixels[0] != 0xff || pixels[3*(size*codes[0].symbol.quietZoneSize*4+codes[0].symbol.quietZoneSize*4)] != 0 {
+		t.Errorf("expected a white quiet zone and dark finder pattern")
+	}
+}
+
+func TestMultiPDFWithOptions(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := MultiPDFWithOptions([]*QRCode{q}, 100, PDFOptions{PageWidth: LetterWidth, PageHeight: LetterHeight})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 612 792]")) {
+		t.Errorf("expected Letter pages")
+	}
+
+	// A large image is shrunk to fit within the margins, and centred.
+	if !bytes.Contains(data, []byte("q 540 0 0 540 36 126 cm /Im0 Do Q")) {
+		t.Errorf("image not scaled to fit")
+	}
+
+	tests := []struct {
+		codes        []*QRCode
+		modulePixels int
+		opts         PDFOptions
+	}{
+		{nil, 4, PDFOptions{}},
+		{[]*QRCode{q, nil}, 4, PDFOptions{}},
+		{[]*QRCode{q}, 0, PDFOptions{}},
+		{[]*QRCode{q}, 4, PDFOptions{PageWidth: 612}},
+		{[]*QRCode{q}, 4, PDFOptions{PageWidth: -612, PageHeight: 792}},
+	}
+
+	for i, test := range tests {
+		if _, err := MultiPDFWithOptions(test.codes, test.modulePixels, test.opts); err == nil {
+			t.Errorf("test %d: got success, expected error", i)
+		}
+	}
+}
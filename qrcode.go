@@ -43,6 +43,12 @@ The maximum capacity of a QR Code varies according to the content encoded and
 the error recovery level. The maximum capacity is 2,953 bytes, 4,296
 alphanumeric characters, 7,089 numeric digits, or a combination of these.
 
+Micro QR Codes, a smaller variant for short content such as serial numbers, are
+created by NewMicro().
+
+QR Codes produced by this package can be read back using Decode(), which
+decodes clean, axis-aligned images such as those returned by Image().
+
 This package implements a subset of QR Code 2005, as defined in ISO/IEC
 18004:2006.
 */
@@ -50,15 +56,24 @@ package qrcode
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/png"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
 	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
@@ -83,7 +98,9 @@ func Encode(content string, level RecoveryLevel, size int) ([]byte, error) {
 	return q.PNG(size)
 }
 
-// WriteFile encodes, then writes a QR Code to the given filename in PNG format.
+// WriteFile encodes, then writes a QR Code to the given filename. The image
+// format is chosen by the file extension: See the documentation for
+// QRCode.WriteFile().
 //
 // size is both the image width and height in pixels. If size is too small then
 // a larger image is silently written. Negative values for size cause a variable
@@ -100,7 +117,8 @@ func WriteFile(content string, level RecoveryLevel, size int, filename string) e
 	return q.WriteFile(size, filename)
 }
 
-// WriteColorFile encodes, then writes a QR Code to the given filename in PNG format.
+// WriteColorFile encodes, then writes a QR Code to the given filename, in the
+// image format chosen by the file extension as for WriteFile().
 // With WriteColorFile you can also specify the colors you want to use.
 //
 // size is both the image width and height in pixels. If size is too small then
@@ -136,24 +154,241 @@ type QRCode struct {
 	ForegroundColor color.Color
 	BackgroundColor color.Color
 
+	// Optional colour of the quiet zone in images. The default of nil uses
+	// BackgroundColor.
+	BorderColor color.Color
+
+	// Optional gradient fill of the dark modules in images, used in place of
+	// ForegroundColor.
+	Gradient *Gradient
+
+	// Optional colour of the dark modules of the finder patterns (the three
+	// "eyes") in images. The default of nil uses ForegroundColor (or the
+	// Gradient). The light modules of the finder patterns and their separators
+	// use BackgroundColor.
+	FinderColor color.Color
+
+	// Shape of the dark modules in images. CornerRadius is the corner radius of
+	// Rounded modules, as a fraction of the module size (up to 0.5); zero
+	// selects 0.25. If SquareFinders is set, the finder patterns are always
+	// drawn with gapless Square modules, which aids scanning.
+	ModuleShape   ModuleShape
+	CornerRadius  float64
+	SquareFinders bool
+
+	// Gap between the dark modules in images, as a fraction of the module
+	// size, for a "pixelated" look. Each dark module is inset by half the gap
+	// on every side. Gaps reduce the dark area readers detect, so the gap is
+	// clamped to 0-0.3; keep it small, and set SquareFinders.
+	ModuleGap float64
+
 	// Disable the QR Code border.
 	DisableBorder bool
 
+	// Width of the QR Code border (quiet zone) in modules. Zero selects the
+	// default width, negative values are treated as no border. DisableBorder
+	// takes precedence.
+	QuietZone int
+
+	// Return an error from PNG() and the other image encoders, instead of
+	// silently increasing the image size, if the requested size is too small.
+	// See the documentation for Image().
+	StrictSize bool
+
+	// Return an error from PNG(), SVG() and the other image encoders if the
+	// ForegroundColor and BackgroundColor contrast too little to scan
+	// reliably, i.e. ContrastRatio() is below 3:1.
+	StrictContrast bool
+
+	// Data mask pattern to apply, 0-7. The default of -1 (or any other value
+	// outside 0-7) selects the mask automatically, by minimising the penalty
+	// score.
+	ForcedMask int
+
+	// Raise the recovery level, when the QR Code is encoded, to the highest
+	// level at which the content still fits the same version. Content rarely
+	// fills a version exactly, and the spare capacity is spent on error
+	// correction at no cost in size. Level is updated to the level used. Micro
+	// QR Codes are not boosted.
+	BoostECC bool
+
 	encoder *dataEncoder
 	version qrCodeVersion
 
-	data   *bitset.Bitset
-	symbol *symbol
-	mask   int
+	// Micro QR Code version, or nil for regular QR Codes.
+	micro *microVersion
+
+	data    *bitset.Bitset
+	symbol  *symbol
+	mask    int
+	penalty int
 }
 
+// ParallelMaskSelection enables building and scoring the candidate data masks
+// of a QR Code concurrently, one goroutine per mask, when it is encoded. This
+// reduces the latency of encoding large QR Codes, at the cost of using more CPU
+// cores.
+//
+// The default is false: Masks are evaluated serially, which suits programs
+// already encoding many QR Codes concurrently. The selected mask, and so the
+// symbol, is identical either way. Set it before encoding any QR Codes.
+var ParallelMaskSelection bool
+
+// ErrContentTooLong is returned when the content does not fit in any QR Code
+// version at the recovery level. Check for it with errors.Is(), as some
+// constructors wrap it with further detail.
+var ErrContentTooLong = errors.New("content too long to encode")
+
 // New constructs a QRCode.
 //
 //	var q *qrcode.QRCode
 //	q, err := qrcode.New("my content", qrcode.Medium)
 //
-// An error occurs if the content is too long.
+// An error occurs if the content is too long (ErrContentTooLong), or level is
+// not one of the RecoveryLevel constants.
 func New(content string, level RecoveryLevel) (*QRCode, error) {
+	return newWithOptions(content, level, encodeOptions{})
+}
+
+// NewBytes constructs a QRCode of binary data.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewBytes([]byte{0x00, 0x01, 0xff}, qrcode.Medium)
+//
+// The data is encoded as is in the byte data mode, never in the denser
+// numeric, alphanumeric or Kanji data modes, and without an ECI header. This
+// suits arbitrary binary data (which may contain NUL bytes), as readers are
+// not led to interpret it as text. The Content field holds the data as a
+// string.
+//
+// An error occurs if the data is empty or too long.
+func NewBytes(data []byte, level RecoveryLevel) (*QRCode, error) {
+	return newWithOptions(string(data), level, encodeOptions{singleMode: dataModeByte})
+}
+
+// NewNumeric constructs a QRCode of a string of decimal digits (0-9).
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewNumeric("0123456789", qrcode.Medium)
+//
+// The digits are encoded as a single numeric mode segment, skipping the data
+// mode analysis of New. The QR Code is identical to that produced by New, which
+// also encodes digits in the numeric mode, but is faster to construct.
+//
+// An error occurs if digits is empty, too long, or contains any other
+// character.
+func NewNumeric(digits string, level RecoveryLevel) (*QRCode, error) {
+	for i := 0; i < len(digits); i++ {
+		if !isNumericCharacter(digits[i]) {
+			return nil, fmt.Errorf("invalid character %q at position %d (expected digits 0-9)", digits[i], i)
+		}
+	}
+
+	return newWithOptions(digits, level, encodeOptions{singleMode: dataModeNumeric})
+}
+
+// NewUpper constructs a QRCode of the content with lower case ASCII letters
+// (a-z) converted to upper case.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewUpper("https://example.org/", qrcode.Medium)
+//
+// The conversion is lossy: The QR Code contains the upper case content, which
+// is also stored in the Content field. It is only suitable for case
+// insensitive content, e.g. the scheme and host name of URLs. Upper case
+// content may be encoded in the alphanumeric data mode, which is denser than
+// the byte mode, and so yields smaller QR Codes.
+//
+// An error occurs if the content is too long.
+func NewUpper(content string, level RecoveryLevel) (*QRCode, error) {
+	upper := []byte(content)
+	for i, c := range upper {
+		if c >= 'a' && c <= 'z' {
+			upper[i] = c - 'a' + 'A'
+		}
+	}
+
+	return New(string(upper), level)
+}
+
+// NewWithECI constructs a QRCode whose data is preceded by an ECI (Extended
+// Channel Interpretation) header.
+//
+// The ECI assignment number eci tells the reader which character set the
+// content's bytes are in, e.g. 26 for UTF-8, or 3 for ISO-8859-1. New writes no
+// ECI header, leaving readers to guess the character set (most assume UTF-8 or
+// ISO-8859-1).
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewWithECI("Grüße", qrcode.Medium, 26)
+//
+// The content is encoded as is; it is not converted to the stated character
+// set. An error occurs if the content is too long, or if eci is larger than
+// 999999.
+func NewWithECI(content string, level RecoveryLevel, eci uint32) (*QRCode, error) {
+	return newWithOptions(content, level, encodeOptions{eci: eci, hasECI: true})
+}
+
+// NewWithMinVersion constructs a QRCode of at least version minVersion.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewWithMinVersion("my content", qrcode.Medium, 10)
+//
+// This is useful for producing a series of QR Codes of the same physical size.
+// The smallest version able to hold content is used if larger than minVersion.
+//
+// An error occurs if the content is too long, or in case of invalid minVersion.
+func NewWithMinVersion(content string, level RecoveryLevel, minVersion int) (*QRCode, error) {
+	if minVersion < 1 || minVersion > 40 {
+		return nil, fmt.Errorf("Invalid version %d (expected 1-40 inclusive)", minVersion)
+	}
+
+	return newWithOptions(content, level, encodeOptions{minVersion: minVersion})
+}
+
+// encodeOptions holds the optional data encoding settings of a QRCode.
+type encodeOptions struct {
+	// ECI assignment number, used if hasECI is set.
+	eci    uint32
+	hasECI bool
+
+	// Structured Append header, or nil.
+	structuredAppend *structuredAppend
+
+	// Smallest QR Code version to use, or 0 for no minimum.
+	minVersion int
+
+	// Data mode to encode the content in as a single segment, or zero to
+	// choose the data modes automatically.
+	singleMode dataMode
+
+	// Segments to encode as is, or nil to segment the content automatically.
+	// The content must be the concatenated segment data.
+	segments []segment
+}
+
+// apply applies the options to the dataEncoder d.
+func (o encodeOptions) apply(d *dataEncoder) {
+	d.eci = o.eci
+	d.hasECI = o.hasECI
+	d.structuredAppend = o.structuredAppend
+	d.singleMode = o.singleMode
+	d.segments = o.segments
+}
+
+// newWithOptions constructs a QRCode of the smallest version able to hold
+// content, using the data encoding options opts.
+func newWithOptions(content string, level RecoveryLevel, opts encodeOptions) (*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
+	// Reject huge content before it is segmented, which takes time
+	// proportional to its length.
+	if len(content) > maxContentLength(level) {
+		return nil, ErrContentTooLong
+	}
+
 	encoders := []dataEncoderType{dataEncoderType1To9, dataEncoderType10To26,
 		dataEncoderType27To40}
 
@@ -164,6 +399,11 @@ func New(content string, level RecoveryLevel) (*QRCode, error) {
 
 	for _, t := range encoders {
 		encoder = newDataEncoder(t)
+		if encoder.maxVersion < opts.minVersion {
+			continue
+		}
+
+		opts.apply(encoder)
 		encoded, err = encoder.encode([]byte(content))
 
 		if err != nil {
@@ -173,6 +413,9 @@ func New(content string, level RecoveryLevel) (*QRCode, error) {
 		chosenVersion = chooseQRCodeVersion(level, encoder, encoded.Len())
 
 		if chosenVersion != nil {
+			if chosenVersion.version < opts.minVersion {
+				chosenVersion = getQRCodeVersion(level, opts.minVersion)
+			}
 			break
 		}
 	}
@@ -180,7 +423,7 @@ func New(content string, level RecoveryLevel) (*QRCode, error) {
 	if err != nil {
 		return nil, err
 	} else if chosenVersion == nil {
-		return nil, errors.New("content too long to encode")
+		return nil, ErrContentTooLong
 	}
 
 	q := &QRCode{
@@ -192,6 +435,8 @@ func New(content string, level RecoveryLevel) (*QRCode, error) {
 		ForegroundColor: color.Black,
 		BackgroundColor: color.White,
 
+		ForcedMask: -1,
+
 		encoder: encoder,
 		data:    encoded,
 		version: *chosenVersion,
@@ -207,6 +452,10 @@ func New(content string, level RecoveryLevel) (*QRCode, error) {
 //
 // An error occurs in case of invalid version.
 func NewWithForcedVersion(content string, version int, level RecoveryLevel) (*QRCode, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return nil, err
+	}
+
 	var encoder *dataEncoder
 
 	switch {
@@ -234,7 +483,8 @@ func NewWithForcedVersion(content string, version int, level RecoveryLevel) (*QR
 	}
 
 	if encoded.Len() > chosenVersion.numDataBits() {
-		return nil, fmt.Errorf("Cannot encode QR code: content too large for fixed size QR Code version %d (encoded length is %d bits, maximum length is %d bits)",
+		return nil, fmt.Errorf("Cannot encode QR code: %w for fixed size QR Code version %d (encoded length is %d bits, maximum length is %d bits)",
+			ErrContentTooLong,
 			version,
 			encoded.Len(),
 			chosenVersion.numDataBits())
@@ -249,6 +499,8 @@ func NewWithForcedVersion(content string, version int, level RecoveryLevel) (*QR
 		ForegroundColor: color.Black,
 		BackgroundColor: color.White,
 
+		ForcedMask: -1,
+
 		encoder: encoder,
 		data:    encoded,
 		version: *chosenVersion,
@@ -257,12 +509,245 @@ func NewWithForcedVersion(content string, version int, level RecoveryLevel) (*QR
 	return q, nil
 }
 
+// WithLevel returns a copy of the QR Code, encoded afresh at recovery level
+// level. The original content is available as the Content field.
+//
+// The version is chosen anew for the level. Micro QR Codes remain Micro QR
+// Codes, and any ECI header, NewBytes() or NewFromSegments() encoding is kept.
+// The drawing options (colours, border, shape etc.) are copied.
+//
+// An error occurs if the content is too long to encode at the level.
+func (q *QRCode) WithLevel(level RecoveryLevel) (*QRCode, error) {
+	var r *QRCode
+	var err error
+
+	switch {
+	case q.micro != nil:
+		r, err = NewMicro(q.Content, level)
+	case q.encoder.hasECI:
+		r, err = NewWithECI(q.Content, level, q.encoder.eci)
+	case q.encoder.segments != nil:
+		r, err = newWithOptions(q.Content, level, encodeOptions{segments: q.encoder.segments})
+	case q.encoder.singleMode == dataModeByte:
+		r, err = NewBytes([]byte(q.Content), level)
+	case q.encoder.singleMode == dataModeNumeric:
+		r, err = NewNumeric(q.Content, level)
+	default:
+		r, err = New(q.Content, level)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.ForegroundColor = q.ForegroundColor
+	r.BackgroundColor = q.BackgroundColor
+	r.BorderColor = q.BorderColor
+	r.Gradient = q.Gradient
+	r.FinderColor = q.FinderColor
+	r.ModuleShape = q.ModuleShape
+	r.CornerRadius = q.CornerRadius
+	r.SquareFinders = q.SquareFinders
+	r.ModuleGap = q.ModuleGap
+	r.DisableBorder = q.DisableBorder
+	r.QuietZone = q.QuietZone
+	r.StrictSize = q.StrictSize
+	r.StrictContrast = q.StrictContrast
+	r.ForcedMask = q.ForcedMask
+	r.BoostECC = q.BoostECC
+
+	return r, nil
+}
+
+// Invert swaps the ForegroundColor and BackgroundColor of the QR Code, e.g. to
+// draw light modules on a dark background.
+//
+// Not all QR Code readers can decode inverted QR Codes. The other colours
+// (BorderColor, FinderColor and Gradient) are unchanged.
+func (q *QRCode) Invert() {
+	q.ForegroundColor, q.BackgroundColor = q.BackgroundColor, q.ForegroundColor
+}
+
+// Inverted returns a copy of the QR Code with the ForegroundColor and
+// BackgroundColor swapped, see Invert(). q is unchanged.
+func (q *QRCode) Inverted() *QRCode {
+	r := *q
+	r.data = bitset.Clone(q.data)
+	r.symbol = nil
+	r.Invert()
+
+	return &r
+}
+
+// Version returns the QR Code version number, 1-40, or 1-4 for Micro QR
+// Codes. The version determines the size of the QR Code symbol.
+func (q *QRCode) Version() int {
+	return q.VersionNumber
+}
+
+// Mask returns the data mask pattern applied to the QR Code, 0-7.
+//
+// Unless ForcedMask is set, the mask is selected during encoding, to minimise
+// features which can hinder scanning, such as large blocks of a single colour.
+// The mask then depends only on the content and recovery level.
+//
+// Mask selection is deterministic: The mask with the lowest penalty score is
+// used, and ties are broken by choosing the lowest numbered mask. Identical
+// content and recovery level always produce an identical symbol, see
+// Fingerprint().
+func (q *QRCode) Mask() int {
+	// Build QR code.
+	q.encode()
+
+	return q.mask
+}
+
+// MaskPenalty returns the penalty score of the data mask applied to the QR
+// Code, as computed during mask selection (see Mask()).
+//
+// The penalty score is the sum of four rules specified by ISO/IEC 18004, each
+// scoring a feature which can hinder scanning:
+//
+//   - Runs of five or more modules of the same colour in a row or column.
+//   - 2x2 blocks of modules of the same colour.
+//   - Patterns resembling a finder pattern (1:1:3:1:1) in a row or column.
+//   - Imbalance of the proportion of dark modules away from 50%.
+//
+// A lower score is better. If ForcedMask is set, the score of the forced mask
+// is returned. Micro QR Codes rank masks by a different evaluation, and return
+// 0.
+func (q *QRCode) MaskPenalty() int {
+	// Build QR code.
+	q.encode()
+
+	return q.penalty
+}
+
+// FormatInfo returns the 15-bit format information written into the QR Code
+// symbol, e.g. to compare against other encoders or the tables of ISO/IEC
+// 18004.
+//
+// For regular QR Codes, from the most significant bit:
+//
+//   - Bits 14-13: The error correction level (Low 01, Medium 00, High 11,
+//     Highest 10).
+//   - Bits 12-10: The data mask pattern, see Mask().
+//   - Bits 9-0: The BCH(15,5) error correction bits of bits 14-10.
+//
+// The 15 bits are then XORed with 0x5412. For Micro QR Codes, bits 14-12 are
+// the symbol number (the version and level), bits 11-10 the data mask, and
+// the XOR mask is 0x4445. Both copies of the format information in the symbol
+// are the same value.
+func (q *QRCode) FormatInfo() uint16 {
+	// Build QR code.
+	q.encode()
+
+	var f *bitset.Bitset
+	if q.micro != nil {
+		f = q.micro.formatInfo(q.mask)
+	} else {
+		f = q.version.formatInfo(q.mask)
+	}
+
+	var info uint16
+	for i := 0; i < f.Len(); i++ {
+		info <<= 1
+		if f.At(i) {
+			info |= 1
+		}
+	}
+
+	return info
+}
+
+// Fingerprint returns a stable hash of the QR Code symbol, as a hex encoded
+// SHA-256 digest of the modules returned by Matrix().
+//
+// QR Codes with identical fingerprints have identical symbols. The fingerprint
+// is unaffected by the drawing options, such as the colours and quiet zone.
+// It is useful for asserting that output is unchanged, e.g. in tests, without
+// storing whole images.
+func (q *QRCode) Fingerprint() string {
+	matrix := q.Matrix()
+
+	h := sha256.New()
+	for _, row := range matrix {
+		line := make([]byte, len(row)+1)
+		for x, v := range row {
+			line[x] = '0'
+			if v {
+				line[x] = '1'
+			}
+		}
+		line[len(row)] = '\n'
+
+		h.Write(line)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RemainingCapacity returns the number of additional bytes of content the QR
+// Code's version could hold, at its recovery level.
+//
+// Additional content is assumed to require byte mode (e.g. lower case
+// letters), and the estimate includes the segment header overhead this may
+// add. Denser content, such as digits, may fit in greater numbers.
+func (q *QRCode) RemainingCapacity() int {
+	numDataBits := q.version.numDataBits()
+	if q.micro != nil {
+		numDataBits = q.micro.numDataBits
+	}
+
+	// Encode using a copy, to preserve the encoder's state.
+	encoder := *q.encoder
+	fits := func(n int) bool {
+		data := append([]byte(q.Content), bytes.Repeat([]byte{'a'}, n)...)
+		encoded, err := encoder.encode(data)
+
+		return err == nil && encoded.Len() <= numDataBits
+	}
+
+	// Binary search for the largest number of bytes that fit. n=0 always fits,
+	// and no more bytes fit than there are data bytes.
+	lo, hi := 0, numDataBits/8+1
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if fits(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo
+}
+
+// DataBitsUsed returns the number of data bits the content is encoded in: The
+// segment headers (mode indicators and character counts), the segment data,
+// and any ECI or Structured Append headers. The terminator and padding bits
+// which fill the rest of the version's capacity are not counted.
+//
+// Together with the capacity of the version, this gives how full the QR Code
+// is. See also RemainingCapacity().
+func (q *QRCode) DataBitsUsed() int {
+	// Measure using a copy, to preserve the encoder's state.
+	encoder := *q.encoder
+
+	numBits, err := encoder.encodedBits([]byte(q.Content))
+	if err != nil {
+		log.Panicf("bug: content no longer encodes: %s", err)
+	}
+
+	return numBits
+}
+
 // Bitmap returns the QR Code as a 2D array of 1-bit pixels.
 //
 // bitmap[y][x] is true if the pixel at (x, y) is set.
 //
 // The bitmap includes the required "quiet zone" around the QR Code to aid
-// decoding.
+// decoding. Its width is set by QuietZone and DisableBorder.
 func (q *QRCode) Bitmap() [][]bool {
 	// Build QR code.
 	q.encode()
@@ -270,6 +755,46 @@ func (q *QRCode) Bitmap() [][]bool {
 	return q.symbol.bitmap()
 }
 
+// BitmapWithBorder returns the QR Code as a 2D array of 1-bit pixels, as
+// Bitmap(), and the width of the quiet zone in modules.
+//
+// The module at (x, y) of the symbol is grid[y+border][x+border].
+func (q *QRCode) BitmapWithBorder() (grid [][]bool, border int) {
+	grid = q.Bitmap()
+
+	return grid, q.symbol.quietZoneSize
+}
+
+// DataBitmap returns the QR Code symbol as a 2D array of modules, excluding the
+// quiet zone. It is equivalent to Matrix().
+//
+// Use Bitmap() or BitmapWithBorder() to include the quiet zone.
+func (q *QRCode) DataBitmap() [][]bool {
+	return q.Matrix()
+}
+
+// Matrix returns the QR Code symbol as a 2D array of modules.
+//
+// The matrix is row-major with the origin at the top-left: matrix[y][x] is true
+// if the module at (x, y) is dark. Unlike Bitmap(), the quiet zone is never
+// included, regardless of QuietZone and DisableBorder.
+//
+// The returned matrix is a copy, and may be freely modified.
+func (q *QRCode) Matrix() [][]bool {
+	// Build QR code.
+	q.encode()
+
+	s := q.symbol
+	matrix := make([][]bool, s.symbolSize)
+
+	for y := range matrix {
+		matrix[y] = make([]bool, s.symbolSize)
+		copy(matrix[y], s.module[y+s.quietZoneSize][s.quietZoneSize:])
+	}
+
+	return matrix
+}
+
 // Image returns the QR Code as an image.Image.
 //
 // A positive size sets a fixed image width and height (e.g. 256 yields an
@@ -283,37 +808,262 @@ func (q *QRCode) Bitmap() [][]bool {
 // returned is the minimum size required for the QR Code. Choose a larger
 // negative number to increase the scale of the image. e.g. a size of -5 causes
 // each module (QR Code "pixel") to be 5px in size.
+//
+// Modules are at least 10px in size for reliable decoding: Smaller sizes are
+// silently increased. Set StrictSize to have PNG() and the other image
+// encoders return an error instead.
+//
+// Translucent colours are preserved, e.g. a BackgroundColor of
+// color.RGBA{0, 0, 0, 0} produces a transparent background and quiet zone.
+//
+// Dark modules are drawn in the shape set by ModuleShape.
 func (q *QRCode) Image(size int) image.Image {
+	return q.renderImage(size, nil)
+}
+
+// renderImage returns the QR Code as an image.Image, as Image(), with the dark
+// modules coloured by darkFn if non-nil, see ImageWithColorFunc().
+func (q *QRCode) renderImage(size int, darkFn func(x int, y int, n int) color.Color) image.Image {
 	// Build QR code.
 	q.encode()
 
-	// Minimum pixels (both width and height) required.
-	realSize := q.symbol.size
-
 	// Variable size support.
-	if size < 0 {
-		size = size * -1 * realSize
-	}
+	size = q.requestedImageSize(size)
 
 	// Actual pixels available to draw the symbol. Automatically increase the
 	// image size if it's not large enough.
-	// Ensure minimum 10 pixels per module for reliable decoding
-	minSize := realSize * 10
-	if size < minSize {
+	if minSize := q.minImageSize(); size < minSize {
+		size = minSize
+	}
+
+	return q.drawImage(size, darkFn)
+}
+
+// ImageGray returns the QR Code as a grayscale image, e.g. for image
+// processing pipelines which expect a single channel.
+//
+// size is as for Image(). Dark modules are drawn black (0), and light modules
+// and the quiet zone white (255), ignoring the ForegroundColor, BackgroundColor
+// and other colours. The ModuleShape and ModuleGap apply as for Image().
+func (q *QRCode) ImageGray(size int) *image.Gray {
+	// Build QR code.
+	q.encode()
+
+	size = q.requestedImageSize(size)
+	if minSize := q.minImageSize(); size < minSize {
 		size = minSize
 	}
 
-	if size < realSize {
-		size = realSize
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
 	}
 
+	q.drawModules(size, func(x int, y int, mx int, my int) {
+		img.Pix[img.PixOffset(x, y)] = 0
+	})
+
+	return img
+}
+
+// ImageExact returns the QR Code as an image.Image, with each module exactly
+// modulePixels pixels in size.
+//
+// The image width and height are modulePixels * (symbol size + 2 * quiet zone
+// size) pixels, see BitmapWithBorder(). Unlike Image(), no rounding occurs and
+// the 10px minimum module size is not applied. modulePixels less than 1 is
+// treated as 1.
+func (q *QRCode) ImageExact(modulePixels int) image.Image {
+	// Build QR code.
+	q.encode()
+
+	if modulePixels < 1 {
+		modulePixels = 1
+	}
+
+	return q.drawImage(modulePixels*q.symbol.size, nil)
+}
+
+// drawImage draws the encoded QR Code as a size x size pixel image. If darkFn
+// is non-nil, it colours the dark modules, see ImageWithColorFunc().
+func (q *QRCode) drawImage(size int, darkFn func(x int, y int, n int) color.Color) image.Image {
 	// Output image.
 	rect := image.Rectangle{Min: image.Point{0, 0}, Max: image.Point{size, size}}
 
+	var img image.Image
+	// setForeground draws the pixel at (x, y) of the module at (mx, my) in the
+	// foreground colour.
+	var setForeground func(x int, y int, mx int, my int)
+
+	opaqueBorder := q.BorderColor == nil || isOpaque(q.BorderColor)
+	opaqueFinder := q.FinderColor == nil || isOpaque(q.FinderColor)
+
+	if isOpaque(q.BackgroundColor) && isOpaque(q.ForegroundColor) && opaqueBorder && opaqueFinder && q.Gradient == nil && darkFn == nil {
+		paletted := image.NewPaletted(rect, q.palette())
+		fgClr := uint8(paletted.Palette.Index(q.ForegroundColor))
+		finderClr := fgClr
+		if q.FinderColor != nil {
+			finderClr = uint8(paletted.Palette.Index(q.FinderColor))
+		}
+
+		img = paletted
+		setForeground = func(x int, y int, mx int, my int) {
+			if finderClr != fgClr && q.isFinderModule(mx, my) {
+				paletted.Pix[paletted.PixOffset(x, y)] = finderClr
+				return
+			}
+
+			paletted.Pix[paletted.PixOffset(x, y)] = fgClr
+		}
+	} else {
+		// Translucent colours, e.g. a transparent background, gradients and
+		// colour functions are preserved exactly using a full colour image.
+		nrgba := image.NewNRGBA(rect)
+		draw.Draw(nrgba, rect, &image.Uniform{q.BackgroundColor}, image.Point{}, draw.Src)
+		fgClr := color.NRGBAModel.Convert(q.ForegroundColor).(color.NRGBA)
+
+		var moduleColors []color.NRGBA
+		if darkFn != nil {
+			moduleColors = q.darkModuleColors(darkFn)
+		}
+
+		img = nrgba
+		setForeground = func(x int, y int, mx int, my int) {
+			if q.FinderColor != nil && q.isFinderModule(mx, my) {
+				nrgba.Set(x, y, q.FinderColor)
+				return
+			}
+
+			if moduleColors != nil {
+				border := q.symbol.quietZoneSize
+				nrgba.SetNRGBA(x, y, moduleColors[(my-border)*q.symbol.symbolSize+mx-border])
+				return
+			}
+
+			if q.Gradient != nil {
+				border := q.symbol.quietZoneSize
+				nrgba.SetNRGBA(x, y, q.Gradient.at(mx-border, my-border, q.symbol.symbolSize))
+				return
+			}
+
+			nrgba.SetNRGBA(x, y, fgClr)
+		}
+	}
+
+	if q.BorderColor != nil {
+		// Fill the quiet zone, around the symbol.
+		dst := img.(draw.Image)
+		draw.Draw(dst, rect, &image.Uniform{q.BorderColor}, image.Point{}, draw.Src)
+		draw.Draw(dst, q.symbolPixelBounds(size), &image.Uniform{q.BackgroundColor}, image.Point{}, draw.Src)
+	}
+
+	q.drawModules(size, setForeground)
+
+	return img
+}
+
+// palette returns the colours of the QR Code's paletted images: The
+// BackgroundColor and ForegroundColor, in that order, followed by the
+// BorderColor and FinderColor if set.
+func (q *QRCode) palette() color.Palette {
 	// Saves a few bytes to have them in this order
-	p := color.Palette([]color.Color{q.BackgroundColor, q.ForegroundColor})
-	img := image.NewPaletted(rect, p)
-	fgClr := uint8(img.Palette.Index(q.ForegroundColor))
+	p := color.Palette{q.BackgroundColor, q.ForegroundColor}
+	if q.BorderColor != nil {
+		p = append(p, q.BorderColor)
+	}
+	if q.FinderColor != nil {
+		p = append(p, q.FinderColor)
+	}
+
+	return p
+}
+
+// symbolPixelBounds returns the pixels of a size x size pixel image of the QR
+// Code which lie within the symbol, i.e. are not part of the quiet zone. The QR
+// Code must be encoded.
+func (q *QRCode) symbolPixelBounds(size int) image.Rectangle {
+	modulesPerPixel := float64(q.symbol.size) / float64(size)
+	border := q.symbol.quietZoneSize
+
+	// The first pixel mapped to module m, as in drawModules().
+	firstPixel := func(m int) int {
+		x := 0
+		for x < size && int(float64(x)*modulesPerPixel) < m {
+			x++
+		}
+		return x
+	}
+
+	lo := firstPixel(border)
+	hi := firstPixel(border + q.symbol.symbolSize)
+
+	return image.Rect(lo, lo, hi, hi)
+}
+
+// DrawInto draws the QR Code onto dst, scaled to fit rect.
+//
+// The QR Code is drawn as the largest square fitting within rect, centred.
+// The rest of rect is filled with the BorderColor, or the BackgroundColor.
+// Translucent colours are composited over the existing contents of dst.
+//
+// Unlike Image(), the size is not increased to 10px per module: Keep rect
+// large enough for reliable decoding, see BitmapWithBorder() for the number of
+// modules.
+func (q *QRCode) DrawInto(dst draw.Image, rect image.Rectangle) {
+	// Build QR code.
+	q.encode()
+
+	size := min(rect.Dx(), rect.Dy())
+	if size <= 0 {
+		return
+	}
+
+	origin := rect.Min.Add(image.Point{(rect.Dx() - size) / 2, (rect.Dy() - size) / 2})
+	border := q.symbol.quietZoneSize
+
+	if q.BorderColor != nil {
+		draw.Draw(dst, rect, &image.Uniform{q.BorderColor}, image.Point{}, draw.Over)
+		draw.Draw(dst, q.symbolPixelBounds(size).Add(origin), &image.Uniform{q.BackgroundColor}, image.Point{}, draw.Over)
+	} else {
+		draw.Draw(dst, rect, &image.Uniform{q.BackgroundColor}, image.Point{}, draw.Over)
+	}
+
+	q.drawModules(size, func(x int, y int, mx int, my int) {
+		c := q.ForegroundColor
+		if q.FinderColor != nil && q.isFinderModule(mx, my) {
+			c = q.FinderColor
+		} else if q.Gradient != nil {
+			c = q.Gradient.at(mx-border, my-border, q.symbol.symbolSize)
+		}
+
+		p := origin.Add(image.Point{x, y})
+		dst.Set(p.X, p.Y, blendOver(c, dst.At(p.X, p.Y)))
+	})
+}
+
+// blendOver returns the colour src composited over dst.
+func blendOver(src color.Color, dst color.Color) color.Color {
+	sr, sg, sb, sa := src.RGBA()
+	if sa == 0xffff {
+		return src
+	}
+
+	dr, dg, db, da := dst.RGBA()
+	a := 0xffff - sa
+
+	return color.RGBA64{
+		R: uint16(sr + dr*a/0xffff),
+		G: uint16(sg + dg*a/0xffff),
+		B: uint16(sb + db*a/0xffff),
+		A: uint16(sa + da*a/0xffff),
+	}
+}
+
+// drawModules calls setForeground for each pixel (x, y) of a size x size pixel
+// image of the QR Code which lies within the dark module (mx, my) of the
+// bitmap. The QR Code must be encoded.
+func (q *QRCode) drawModules(size int, setForeground func(x int, y int, mx int, my int)) {
+	realSize := q.symbol.size
 
 	// QR code bitmap.
 	bitmap := q.symbol.bitmap()
@@ -327,28 +1077,192 @@ func (q *QRCode) Image(size int) image.Image {
 
 			v := bitmap[y2][x2]
 
+			if v && (q.ModuleShape != Square || q.ModuleGap > 0) {
+				// Position of the pixel's centre within the module.
+				fx := (float64(x)+0.5)*modulesPerPixel - float64(x2)
+				fy := (float64(y)+0.5)*modulesPerPixel - float64(y2)
+
+				v = q.inModuleShape(x2, y2, fx, fy)
+			}
+
 			if v {
-				pos := img.PixOffset(x, y)
-				img.Pix[pos] = fgClr
+				setForeground(x, y, x2, y2)
 			}
 		}
 	}
+}
 
-	return img
+// minModulePixels is the minimum width of a module in pixels in images, for
+// reliable decoding.
+const minModulePixels = 10
+
+// requestedImageSize returns the image width requested by size, resolving
+// negative sizes as in Image(). The QR Code must be encoded.
+func (q *QRCode) requestedImageSize(size int) int {
+	if size < 0 {
+		return size * -1 * q.symbol.size
+	}
+
+	return size
+}
+
+// minImageSize returns the minimum image width in pixels. The QR Code must be
+// encoded.
+func (q *QRCode) minImageSize() int {
+	return q.symbol.size * minModulePixels
+}
+
+// checkImage returns an error if StrictSize is set and size is too small,
+// i.e. Image(size) would return a larger image than requested, or if
+// StrictContrast is set and the colours contrast too little.
+func (q *QRCode) checkImage(size int) error {
+	if err := q.checkContrast(); err != nil {
+		return err
+	}
+
+	if !q.StrictSize {
+		return nil
+	}
+
+	q.encode()
+
+	if requested := q.requestedImageSize(size); requested < q.minImageSize() {
+		return fmt.Errorf("image size %dpx is too small for a QR Code of %d modules: use a size of at least %dpx",
+			requested, q.symbol.size, q.minImageSize())
+	}
+
+	return nil
+}
+
+// minContrastRatio is the lowest ContrastRatio() accepted with StrictContrast.
+const minContrastRatio = 3
+
+// checkContrast returns an error if StrictContrast is set and the
+// ContrastRatio() is below minContrastRatio.
+func (q *QRCode) checkContrast() error {
+	if !q.StrictContrast {
+		return nil
+	}
+
+	if ratio := q.ContrastRatio(); ratio < minContrastRatio {
+		return fmt.Errorf("colour contrast ratio %.2f:1 is too low to scan reliably (expected at least %d:1)",
+			ratio, minContrastRatio)
+	}
+
+	return nil
+}
+
+// ContrastRatio returns the contrast ratio of the ForegroundColor and
+// BackgroundColor, from 1 (identical luminance) to 21 (black and white).
+//
+// The ratio is as defined by WCAG 2: (L1 + 0.05) / (L2 + 0.05), where L1 and
+// L2 are the relative luminances of the lighter and darker colours.
+// Translucent colours are composited over white first. QR Code readers need
+// strong contrast: Aim for 7:1 or more, and avoid less than 3:1 (see
+// StrictContrast).
+func (q *QRCode) ContrastRatio() float64 {
+	l1 := relativeLuminance(q.ForegroundColor)
+	l2 := relativeLuminance(q.BackgroundColor)
+
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of c composited over
+// white, from 0 (black) to 1 (white).
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := blendOver(c, color.White).RGBA()
+
+	// Linearise the sRGB components.
+	linear := func(v uint32) float64 {
+		s := float64(v) / 0xffff
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
+
+// quietZoneSize returns the width of the QR Code's quiet zone in modules.
+func (q *QRCode) quietZoneSize() int {
+	switch {
+	case q.DisableBorder || q.QuietZone < 0:
+		return 0
+	case q.QuietZone > 0:
+		return q.QuietZone
+	case q.micro != nil:
+		return microQuietZoneSize
+	}
+
+	return q.version.quietZoneSize()
+}
+
+// isOpaque returns true if c is fully opaque.
+func isOpaque(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0xffff
 }
 
 // PNG returns the QR Code as a PNG image.
 //
 // size is both the image width and height in pixels. If size is too small then
-// a larger image is silently returned. Negative values for size cause a
-// variable sized image to be returned: See the documentation for Image().
+// a larger image is silently returned, or an error if StrictSize is set.
+// Negative values for size cause a variable sized image to be returned: See the
+// documentation for Image().
 func (q *QRCode) PNG(size int) ([]byte, error) {
+	var b bytes.Buffer
+	err := q.Write(size, &b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// PNGPaletted returns the QR Code as a PNG image, as PNG(), with a two colour
+// palette of the BackgroundColor and ForegroundColor, at 1 bit per pixel. This
+// gives the smallest PNG files.
+//
+// PNG() writes QR Codes of opaque colours this way too, unless BorderColor or
+// FinderColor add colours, but writes full colour images for translucent
+// colours and gradients. Here, every pixel is drawn in the nearest of the two
+// colours instead: The BorderColor, FinderColor and Gradient are approximated,
+// and translucent colours are kept as translucent palette entries.
+func (q *QRCode) PNGPaletted(size int) ([]byte, error) {
+	if err := q.checkImage(size); err != nil {
+		return nil, err
+	}
+
 	img := q.Image(size)
+	b := img.Bounds()
 
+	paletted := image.NewPaletted(b, color.Palette{q.BackgroundColor, q.ForegroundColor})
+	draw.Draw(paletted, b, img, b.Min, draw.Src)
+
+	var buf bytes.Buffer
 	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, paletted); err != nil {
+		return nil, err
+	}
 
+	return buf.Bytes(), nil
+}
+
+// PNGWithDPI returns the QR Code as a PNG image, as PNG(), with a physical
+// resolution of dpi pixels per inch.
+//
+// The resolution is stored in a pHYs chunk (in pixels per metre), so the image
+// prints at a predictable physical size: A size 300 image at 300 DPI prints one
+// inch wide. An error occurs if dpi is less than 1.
+func (q *QRCode) PNGWithDPI(size int, dpi int) ([]byte, error) {
 	var b bytes.Buffer
-	err := encoder.Encode(&b, img)
+	err := q.WritePNGWithDPI(size, dpi, &b)
 
 	if err != nil {
 		return nil, err
@@ -357,44 +1271,155 @@ func (q *QRCode) PNG(size int) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// WritePNGWithDPI writes the QR Code as a PNG image with a physical resolution
+// of dpi pixels per inch to io.Writer. See the documentation for PNGWithDPI().
+func (q *QRCode) WritePNGWithDPI(size int, dpi int, out io.Writer) error {
+	if dpi < 1 {
+		return fmt.Errorf("invalid resolution %d DPI (expected 1 or more)", dpi)
+	}
+
+	// Pixels per metre, in both directions; unit 1 is the metre.
+	ppm := uint32(math.Round(float64(dpi) / 0.0254))
+
+	phys := make([]byte, 9)
+	binary.BigEndian.PutUint32(phys[0:], ppm)
+	binary.BigEndian.PutUint32(phys[4:], ppm)
+	phys[8] = 1
+
+	var chunk bytes.Buffer
+	writePNGChunk(&chunk, "pHYs", phys)
+
+	return q.Write(size, &insertWriter{out: out, offset: pngIHDREnd, insert: chunk.Bytes()})
+}
+
+// pngIHDREnd is the offset of the end of the IHDR chunk in a PNG image. The
+// signature and IHDR chunk, which image/png always writes first, precede any
+// other chunks.
+const pngIHDREnd = 8 + 4 + 4 + 13 + 4
+
+// insertWriter writes insert to out at byte offset offset of the data written
+// through it.
+type insertWriter struct {
+	out    io.Writer
+	offset int
+	insert []byte
+
+	written int
+}
+
+func (w *insertWriter) Write(p []byte) (int, error) {
+	n := 0
+
+	if w.insert != nil && w.written+len(p) >= w.offset {
+		// Write up to the offset, then the inserted data.
+		head := w.offset - w.written
+		m, err := w.out.Write(p[:head])
+		n += m
+		w.written += m
+		if err != nil {
+			return n, err
+		}
+
+		if _, err := w.out.Write(w.insert); err != nil {
+			return n, err
+		}
+		w.insert = nil
+		p = p[head:]
+	}
+
+	m, err := w.out.Write(p)
+	w.written += m
+	return n + m, err
+}
+
+// writePNGChunk writes a PNG chunk of type chunkType and data to w.
+func writePNGChunk(w *bytes.Buffer, chunkType string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	w.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+
+	w.WriteString(chunkType)
+	w.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	w.Write(sum[:])
+}
+
 // Write writes the QR Code as a PNG image to io.Writer.
 //
+// The image is encoded directly to out, without buffering it in memory, e.g.
+// to serve it over HTTP.
+//
 // size is both the image width and height in pixels. If size is too small then
-// a larger image is silently written. Negative values for size cause a
-// variable sized image to be written: See the documentation for Image().
+// a larger image is silently written, or an error if StrictSize is set.
+// Negative values for size cause a variable sized image to be written: See the
+// documentation for Image().
 func (q *QRCode) Write(size int, out io.Writer) error {
-	var png []byte
-
-	png, err := q.PNG(size)
-
-	if err != nil {
+	if err := q.checkImage(size); err != nil {
 		return err
 	}
-	_, err = out.Write(png)
-	return err
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+
+	return encoder.Encode(out, q.Image(size))
 }
 
-// WriteFile writes the QR Code as a PNG image to the specified file.
+// defaultJPEGQuality is the JPEG quality used by WriteFile().
+const defaultJPEGQuality = 90
+
+// WriteFile writes the QR Code as an image to the specified file.
+//
+// The image format is chosen by the file extension: .png, .jpg or .jpeg, .gif,
+// .svg, or .webp. JPEG images are written at quality 90, and WebP images are
+// lossless. An error occurs for other extensions.
 //
 // size is both the image width and height in pixels. If size is too small then
 // a larger image is silently written. Negative values for size cause a
 // variable sized image to be written: See the documentation for Image().
 func (q *QRCode) WriteFile(size int, filename string) error {
-	var png []byte
+	var data []byte
+	var err error
 
-	png, err := q.PNG(size)
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".png":
+		data, err = q.PNG(size)
+	case ".jpg", ".jpeg":
+		data, err = q.JPEG(size, defaultJPEGQuality)
+	case ".gif":
+		data, err = q.GIF(size)
+	case ".svg":
+		data, err = q.SVG(size)
+	case ".webp":
+		data, err = q.WebP(size, true)
+	default:
+		return fmt.Errorf("unsupported image file extension %q (expected .png, .jpg, .jpeg, .gif, .svg or .webp)", ext)
+	}
 
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(filename, png, os.FileMode(0644))
+	return ioutil.WriteFile(filename, data, os.FileMode(0644))
 }
 
 // encode completes the steps required to encode the QR Code. These include
 // adding the terminator bits and padding, splitting the data into blocks and
 // applying the error correction, and selecting the best data mask.
 func (q *QRCode) encode() {
+	if q.micro != nil {
+		q.encodeMicro()
+		return
+	}
+
+	if q.BoostECC {
+		q.boostLevel()
+	}
+
 	numTerminatorBits := q.version.numTerminatorBitsRequired(q.data.Len())
 
 	q.addTerminatorBits(numTerminatorBits)
@@ -403,13 +1428,16 @@ func (q *QRCode) encode() {
 	encoded := q.encodeBlocks()
 
 	const numMasks int = 8
-	penalty := 0
 
-	for mask := 0; mask < numMasks; mask++ {
-		var s *symbol
-		var err error
+	// The symbol is rebuilt on each call, as the drawing options (e.g. the
+	// quiet zone width) may have changed.
+	q.symbol = nil
+
+	var symbols [numMasks]*symbol
+	var penalties [numMasks]int
 
-		s, err = buildRegularSymbol(q.version, mask, encoded, !q.DisableBorder)
+	build := func(mask int) {
+		s, err := buildRegularSymbol(q.version, mask, encoded, q.quietZoneSize())
 
 		if err != nil {
 			log.Panic(err.Error())
@@ -421,15 +1449,66 @@ func (q *QRCode) encode() {
 				numEmptyModules, q.VersionNumber)
 		}
 
-		p := s.penaltyScore()
+		symbols[mask] = s
+		penalties[mask] = s.penaltyScore()
 
-		//log.Printf("mask=%d p=%3d p1=%3d p2=%3d p3=%3d p4=%d\n", mask, p, s.penalty1(), s.penalty2(), s.penalty3(), s.penalty4())
+		//log.Printf("mask=%d p=%3d p1=%3d p2=%3d p3=%3d p4=%d\n", mask, penalties[mask], s.penalty1(), s.penalty2(), s.penalty3(), s.penalty4())
+	}
 
-		if q.symbol == nil || p < penalty {
-			q.symbol = s
+	var masks []int
+	for mask := 0; mask < numMasks; mask++ {
+		if q.ForcedMask >= 0 && q.ForcedMask < numMasks && mask != q.ForcedMask {
+			continue
+		}
+		masks = append(masks, mask)
+	}
+
+	if ParallelMaskSelection && len(masks) > 1 {
+		var wg sync.WaitGroup
+		for _, mask := range masks {
+			wg.Add(1)
+			go func(mask int) {
+				defer wg.Done()
+				build(mask)
+			}(mask)
+		}
+		wg.Wait()
+	} else {
+		for _, mask := range masks {
+			build(mask)
+		}
+	}
+
+	// The lowest penalty wins, with ties broken by the lowest numbered mask,
+	// however the symbols were built.
+	for _, mask := range masks {
+		if q.symbol == nil || penalties[mask] < q.penalty {
+			q.symbol = symbols[mask]
 			q.mask = mask
-			penalty = p
+			q.penalty = penalties[mask]
+		}
+	}
+}
+
+// boostLevel raises the recovery level to the highest at which the data fits
+// the current version, see BoostECC.
+func (q *QRCode) boostLevel() {
+	// The data may already be padded by a previous encode().
+	numDataBits, err := q.encoder.encodedBits([]byte(q.Content))
+	if err != nil {
+		return
+	}
+
+	for level := Highest; level > q.Level; level-- {
+		v := getQRCodeVersion(level, q.VersionNumber)
+		if v == nil || v.numDataBits() < numDataBits {
+			continue
 		}
+
+		q.Level = level
+		q.version = *v
+		q.data = q.data.Substr(0, numDataBits)
+		return
 	}
 }
 
@@ -558,6 +1637,9 @@ func (q *QRCode) addPadding() {
 }
 
 // ToString produces a multi-line string that forms a QR-code image.
+//
+// The string includes the quiet zone, as Bitmap(), so it is set by QuietZone
+// and DisableBorder, as are the other text representations.
 func (q *QRCode) ToString(inverseColor bool) string {
 	bits := q.Bitmap()
 	var buf bytes.Buffer
@@ -612,3 +1694,80 @@ func (q *QRCode) ToSmallString(inverseColor bool) string {
 	}
 	return buf.String()
 }
+
+// ToUnicodeString produces a multi-line string that forms a QR-code image, using
+// the Unicode half block characters to draw two module rows per line of text.
+// The output is half the height of ToString, which suits terminals.
+//
+// If the bitmap has an odd number of rows, the missing row below the last is
+// treated as light.
+func (q *QRCode) ToUnicodeString(inverseColor bool) string {
+	bits := q.Bitmap()
+	var buf bytes.Buffer
+	for y := 0; y < len(bits); y += 2 {
+		for x := range bits[y] {
+			// As in ToString, a dark module is drawn as a space unless
+			// inverseColor is set.
+			top := bits[y][x] != inverseColor
+			bottom := inverseColor
+			if y+1 < len(bits) {
+				bottom = bits[y+1][x] != inverseColor
+			}
+
+			switch {
+			case top && bottom:
+				buf.WriteString(" ")
+			case top:
+				buf.WriteString("▄")
+			case bottom:
+				buf.WriteString("▀")
+			default:
+				buf.WriteString("█")
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// brailleDots maps the (x, y) module offsets within a Braille cell to the dots
+// of the Unicode Braille patterns.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// ToBrailleString produces a multi-line string that forms a QR-code image, using
+// the Unicode Braille patterns to draw a 2x4 block of modules per character.
+// The output is a quarter of the height of ToString, the smallest text
+// representation.
+//
+// As in ToString, dots are drawn for light modules unless inverseColor is set.
+// If the bitmap size is not a multiple of 2 wide or 4 tall, the missing
+// modules are treated as light.
+func (q *QRCode) ToBrailleString(inverseColor bool) string {
+	bits := q.Bitmap()
+	var buf bytes.Buffer
+	for y := 0; y < len(bits); y += 4 {
+		for x := 0; x < len(bits[y]); x += 2 {
+			r := rune(0x2800)
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					dark := false
+					if y+dy < len(bits) && x+dx < len(bits[y+dy]) {
+						dark = bits[y+dy][x+dx]
+					}
+
+					if dark == inverseColor {
+						r |= brailleDots[dy][dx]
+					}
+				}
+			}
+			buf.WriteRune(r)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
@@ -4,13 +4,16 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
+	"image"
 	_ "image/jpeg"
-	_ "image/png"
+	"image/png"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -21,16 +24,33 @@ import (
 
 const defaultRecoveryLevel = qrcode.Highest
 
+// defaultLogoScale is the default -logo-scale, a logo a fifth of the QR code
+// width.
+const defaultLogoScale = 0.2
+
+// defaultQuietZone is the quiet zone width in modules, as recommended by the
+// QR Code specification.
+const defaultQuietZone = 4
+
 func main() {
-	outFile := flag.String("o", "", "out PNG file prefix, empty for stdout")
+	outFile := flag.String("o", "", "out PNG (or SVG) file prefix, empty for stdout")
 	size := flag.Int("s", 256, "image size (pixel)")
 	textArt := flag.Bool("t", false, "print as text-art on stdout")
 	negative := flag.Bool("i", false, "invert black and white")
-	disableBorder := flag.Bool("d", false, "disable QR Code border")
-	inputFile := flag.String("f", "", "read input from file, hex-encode bytes to text before generating QR")
+	disableBorder := flag.Bool("d", false, "disable QR Code border (same as -q 0)")
+	quietZone := flag.Int("q", defaultQuietZone, "quiet zone (border) width in modules")
+	inputFile := flag.String("f", "", "read content from file (- for stdin) instead of arguments")
+	hexEncode := flag.Bool("hex", false, "hex-encode the content before generating QR (for binary -f files)")
 	splitLong := flag.Bool("split-long", false, "split long content into multiple QR codes")
 	grid := flag.Bool("grid", false, "combine split QR codes into a single grid image (use with -split-long)")
 	decodePath := flag.String("decode", "", "decode QR code(s) from a file or directory, save as .txt")
+	svg := flag.Bool("svg", false, "write SVG instead of PNG")
+	level := flag.String("r", "highest", "recovery level: low, medium, high or highest (or L, M, Q, H)")
+	batch := flag.Bool("batch", false, "encode each line of stdin (or the -f file) as a separate QR code (requires -o)")
+	verbose := flag.Bool("v", false, "print the version, size, recovery level and mask of each QR code to stderr")
+	logoFile := flag.String("logo", "", "embed a PNG or JPEG logo `FILE` in the centre of each QR code (implies -r highest)")
+	logoScale := flag.Float64("logo-scale", defaultLogoScale, "logo size, as a fraction of the QR code width")
+	autoLevel := flag.Bool("auto-level", false, "if the content is too long at the -r recovery level, use the highest lower level it fits")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `qrcode -- QR Code encoder in Go
 https://github.com/skip2/go-qrcode
@@ -50,11 +70,25 @@ Usage:
 
        qrcode "homepage: https://github.com/skip2/go-qrcode" > out.png
 
-  3. Encode a binary file as hex, split into multiple QR codes:
+  3. Encode a file, split into multiple QR codes (add -hex for binary files):
 
        qrcode -f data.csv -split-long -o output
+       cat data.csv | qrcode -f - -split-long -o output
+
+  4. Encode each line of a file as a separate QR code, written to
+     output-1.png, output-2.png etc. (numbered by line):
+
+       qrcode -batch -o output < urls.txt
+
+  5. Write an SVG image instead of a PNG:
+
+       qrcode -svg -o output "https://example.org"
+
+  6. Embed a logo in the centre of the QR code:
+
+       qrcode -logo logo.png -o output "https://example.org"
 
-  4. Decode QR codes from a file or directory (requires zbarimg installed):
+  7. Decode QR codes from a file or directory (requires zbarimg installed):
 
        qrcode -decode ./output-dir
        qrcode -decode image.png
@@ -67,37 +101,114 @@ Usage:
 		return
 	}
 
-	content, err := loadContent(flag.Args(), *inputFile)
+	recoveryLevel, err := parseRecoveryLevel(*level)
 	if err != nil {
 		flag.Usage()
 		checkError(err)
 	}
 
-	q, err := prepareQRCode(content, *disableBorder)
+	if *quietZone < 0 {
+		flag.Usage()
+		checkError(fmt.Errorf("invalid quiet zone width %d (expected >= 0)", *quietZone))
+	}
+	if *disableBorder {
+		*quietZone = 0
+	}
+
+	if *svg && *textArt {
+		checkError(errors.New("-svg and -t are mutually exclusive"))
+	}
+
+	var logo image.Image
+	if *logoFile != "" {
+		if *svg || *textArt {
+			checkError(errors.New("-logo does not support SVG or text-art output"))
+		}
+
+		if *autoLevel {
+			checkError(errors.New("-logo and -auto-level are mutually exclusive"))
+		}
+
+		logo, err = loadLogo(*logoFile)
+		checkError(err)
+
+		// The logo obscures modules, which the error recovery must make up for.
+		if recoveryLevel != qrcode.Highest {
+			fmt.Fprintf(os.Stderr, "-logo: using recovery level highest instead of %s\n", levelName(recoveryLevel))
+			recoveryLevel = qrcode.Highest
+		}
+	}
+
+	opts := outputOptions{
+		size:      *size,
+		quietZone: *quietZone,
+		negative:  *negative,
+		svg:       *svg,
+		logo:      logo,
+		logoScale: *logoScale,
+	}
+	if *verbose {
+		opts.verbose = os.Stderr
+	}
+
+	if *batch {
+		if *textArt {
+			checkError(errors.New("batch does not support text-art output"))
+		}
+
+		in := os.Stdin
+		if *inputFile != "" && *inputFile != "-" {
+			fh, err := os.Open(*inputFile)
+			checkError(err)
+			defer fh.Close()
+			in = fh
+		}
+
+		checkError(batchWrite(in, recoveryLevel, *outFile, opts))
+		return
+	}
+
+	content, err := loadContent(flag.Args(), *inputFile, *hexEncode, os.Stdin)
+	if err != nil {
+		flag.Usage()
+		checkError(err)
+	}
+
+	var q *qrcode.QRCode
+	if *autoLevel {
+		q, err = prepareQRCodeAutoLevel(content, recoveryLevel, *quietZone, os.Stderr)
+	} else {
+		q, err = prepareQRCode(content, recoveryLevel, *quietZone)
+	}
 
 	if err == nil {
 		if *textArt {
 			art := q.ToString(*negative)
 			fmt.Println(art)
+			opts.describe(q, "")
 			return
 		}
 
-		if *negative {
-			q.ForegroundColor, q.BackgroundColor = q.BackgroundColor, q.ForegroundColor
-		}
+		opts.applyColors(q)
 
-		checkError(writeSingleCode(q, *size, *outFile))
+		checkError(writeSingleCode(q, opts, *outFile))
 		return
 	}
 
-	if *splitLong {
-		checkError(splitAndWrite(content, *size, *outFile, *disableBorder, *negative, *textArt, *grid))
+	if *splitLong && isContentTooLong(err) {
+		checkError(splitAndWrite(content, recoveryLevel, *outFile, opts, *textArt, *grid))
 		return
 	}
 
 	checkError(err)
 }
 
+// isContentTooLong returns true if err reports content too long to fit in a
+// single QR code, in which case -split-long applies.
+func isContentTooLong(err error) bool {
+	return errors.Is(err, qrcode.ErrContentTooLong)
+}
+
 func checkError(err error) {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
@@ -105,34 +216,171 @@ func checkError(err error) {
 	}
 }
 
-func prepareQRCode(content string, disableBorder bool) (*qrcode.QRCode, error) {
-	q, err := qrcode.New(content, defaultRecoveryLevel)
+// parseRecoveryLevel parses a recovery level name, e.g. "medium" or "M".
+func parseRecoveryLevel(name string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToLower(name) {
+	case "low", "l":
+		return qrcode.Low, nil
+	case "medium", "m":
+		return qrcode.Medium, nil
+	case "high", "q":
+		return qrcode.High, nil
+	case "highest", "h":
+		return qrcode.Highest, nil
+	}
+
+	return defaultRecoveryLevel, fmt.Errorf("invalid recovery level %q (expected low, medium, high, highest, L, M, Q or H)", name)
+}
+
+func prepareQRCode(content string, level qrcode.RecoveryLevel, quietZone int) (*qrcode.QRCode, error) {
+	q, err := qrcode.New(content, level)
 	if err != nil {
 		return nil, err
 	}
 
-	if disableBorder {
-		q.DisableBorder = true
-	}
+	setQuietZone(q, quietZone)
 
 	return q, nil
 }
 
-func writeSingleCode(q *qrcode.QRCode, size int, outFile string) error {
-	png, err := q.PNG(size)
+// prepareQRCodeAutoLevel prepares a QR code as prepareQRCode, retrying at
+// progressively lower recovery levels while the content is too long. A lower
+// level settled on is reported to warn.
+func prepareQRCodeAutoLevel(content string, level qrcode.RecoveryLevel, quietZone int, warn io.Writer) (*qrcode.QRCode, error) {
+	q, err := prepareQRCode(content, level, quietZone)
+
+	for l := level - 1; l >= qrcode.Low && isContentTooLong(err); l-- {
+		q, err = prepareQRCode(content, l, quietZone)
+		if err == nil {
+			fmt.Fprintf(warn, "-auto-level: using recovery level %s instead of %s\n", levelName(l), levelName(level))
+		}
+	}
+
+	return q, err
+}
+
+// setQuietZone sets the quiet zone width of q in modules, 0 for none.
+func setQuietZone(q *qrcode.QRCode, modules int) {
+	if modules == 0 {
+		q.QuietZone = -1
+	} else {
+		q.QuietZone = modules
+	}
+}
+
+// outputOptions are the image settings applied to each QR code written.
+type outputOptions struct {
+	size      int
+	quietZone int
+	negative  bool
+	svg       bool
+
+	// logo is embedded in the centre of each QR code at logoScale, if set.
+	logo      image.Image
+	logoScale float64
+
+	// verbose receives a description of each QR code written, or nil.
+	verbose io.Writer
+}
+
+// applyColors inverts the colours of q if requested.
+func (o outputOptions) applyColors(q *qrcode.QRCode) {
+	if o.negative {
+		q.Invert()
+	}
+}
+
+// encode returns q as a PNG or SVG image.
+func (o outputOptions) encode(q *qrcode.QRCode) ([]byte, error) {
+	if o.svg {
+		return q.SVG(o.size)
+	}
+	if o.logo != nil {
+		img, err := q.ImageWithLogo(o.size, o.logo, o.logoScale)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	return q.PNG(o.size)
+}
+
+// loadLogo reads a PNG or JPEG logo image from path.
+func loadLogo(path string) (image.Image, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	img, _, err := image.Decode(fh)
+	if err != nil {
+		return nil, fmt.Errorf("logo %s: %w", filepath.Base(path), err)
+	}
+	return img, nil
+}
+
+// describe writes the version, size, recovery level and mask of q to
+// o.verbose, if set. A non-empty label (e.g. a file name) prefixes the line.
+func (o outputOptions) describe(q *qrcode.QRCode, label string) {
+	if o.verbose == nil {
+		return
+	}
+
+	if label != "" {
+		label += ": "
+	}
+
+	modules := len(q.Matrix())
+	fmt.Fprintf(o.verbose, "%sversion %d (%dx%d modules), recovery level %s, mask %d\n",
+		label, q.Version(), modules, modules, levelName(q.Level), q.Mask())
+}
+
+// levelName returns the name of a recovery level, as accepted by
+// parseRecoveryLevel().
+func levelName(level qrcode.RecoveryLevel) string {
+	switch level {
+	case qrcode.Low:
+		return "low"
+	case qrcode.Medium:
+		return "medium"
+	case qrcode.High:
+		return "high"
+	}
+
+	return "highest"
+}
+
+// ext returns the file extension of the images written.
+func (o outputOptions) ext() string {
+	if o.svg {
+		return ".svg"
+	}
+	return ".png"
+}
+
+func writeSingleCode(q *qrcode.QRCode, opts outputOptions, outFile string) error {
+	data, err := opts.encode(q)
 	if err != nil {
 		return err
 	}
 
 	if outFile == "" {
-		_, err = os.Stdout.Write(png)
+		_, err = os.Stdout.Write(data)
+		opts.describe(q, "")
 		return err
 	}
 
-	return writeFile(outFile+".png", png)
+	opts.describe(q, outFile+opts.ext())
+	return writeFile(outFile+opts.ext(), data)
 }
 
-func splitAndWrite(content string, size int, outPrefix string, disableBorder, negative, textArt, grid bool) error {
+func splitAndWrite(content string, level qrcode.RecoveryLevel, outPrefix string, opts outputOptions, textArt, grid bool) error {
 	if textArt {
 		return errors.New("split-long does not support text-art output")
 	}
@@ -141,35 +389,42 @@ func splitAndWrite(content string, size int, outPrefix string, disableBorder, ne
 		return errors.New("split-long requires an output file prefix via -o")
 	}
 
-	codes, err := qrcode.EncodeMulti(content, defaultRecoveryLevel)
+	codes, err := qrcode.EncodeMulti(content, level)
 	if err != nil {
 		return err
 	}
 
 	for _, q := range codes {
-		if disableBorder {
-			q.DisableBorder = true
-		}
-		if negative {
-			q.ForegroundColor, q.BackgroundColor = q.BackgroundColor, q.ForegroundColor
-		}
+		setQuietZone(q, opts.quietZone)
+		opts.applyColors(q)
 	}
 
 	if grid {
-		png, err := qrcode.GridPNG(codes, size, 0)
+		if opts.svg {
+			return errors.New("grid does not support SVG output")
+		}
+		if opts.logo != nil {
+			return errors.New("grid does not support -logo")
+		}
+
+		png, err := qrcode.GridPNG(codes, opts.size, 0)
 		if err != nil {
 			return err
 		}
+		for i, q := range codes {
+			opts.describe(q, fmt.Sprintf("chunk %d", i))
+		}
 		return writeFile(outPrefix+"-grid.png", png)
 	}
 
 	for i, q := range codes {
-		png, err := q.PNG(size)
+		data, err := opts.encode(q)
 		if err != nil {
 			return err
 		}
-		filename := fmt.Sprintf("%s-%d.png", outPrefix, i)
-		if err := writeFile(filename, png); err != nil {
+		filename := fmt.Sprintf("%s-%d%s", outPrefix, i, opts.ext())
+		opts.describe(q, filename)
+		if err := writeFile(filename, data); err != nil {
 			return err
 		}
 	}
@@ -178,6 +433,46 @@ func splitAndWrite(content string, size int, outPrefix string, disableBorder, ne
 	return nil
 }
 
+// batchWrite encodes each non-empty line read from r as a separate QR code,
+// written to <outPrefix>-<line number>.png (or .svg). Lines are trimmed of
+// leading and trailing whitespace.
+func batchWrite(r io.Reader, level qrcode.RecoveryLevel, outPrefix string, opts outputOptions) error {
+	if outPrefix == "" {
+		return errors.New("batch requires an output file prefix via -o")
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	written := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		q, err := prepareQRCode(line, level, opts.quietZone)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		opts.applyColors(q)
+
+		if err := writeSingleCode(q, opts, fmt.Sprintf("%s-%d", outPrefix, lineNumber)); err != nil {
+			return err
+		}
+		written++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %d QR codes\n", written)
+	return nil
+}
+
 func decodePNG(path string) error {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -274,22 +569,38 @@ func writeFile(filename string, data []byte) error {
 	return err
 }
 
-func loadContent(args []string, inputFile string) (string, error) {
-	if inputFile == "" {
-		if len(args) == 0 {
-			return "", fmt.Errorf("Error: no content given")
+// loadContent returns the content to encode: The arguments joined by spaces,
+// or the entire contents of inputFile (read from stdin if "-"). If hexEncode is
+// set the content is hex-encoded, which suits binary files.
+func loadContent(args []string, inputFile string, hexEncode bool, stdin io.Reader) (string, error) {
+	var data []byte
+
+	switch {
+	case inputFile == "" && len(args) == 0:
+		return "", fmt.Errorf("Error: no content given")
+	case inputFile == "":
+		data = []byte(strings.Join(args, " "))
+	case len(args) > 0:
+		return "", fmt.Errorf("Error: use either -f or arguments, not both")
+	case inputFile == "-":
+		var err error
+		if data, err = io.ReadAll(stdin); err != nil {
+			return "", err
+		}
+	default:
+		var err error
+		if data, err = os.ReadFile(inputFile); err != nil {
+			return "", err
 		}
-		return strings.Join(args, " "), nil
 	}
 
-	if len(args) > 0 {
-		return "", fmt.Errorf("Error: use either -f or arguments, not both")
+	if len(data) == 0 {
+		return "", fmt.Errorf("Error: no content given")
 	}
 
-	data, err := os.ReadFile(inputFile)
-	if err != nil {
-		return "", err
+	if hexEncode {
+		return hex.EncodeToString(data), nil
 	}
 
-	return hex.EncodeToString(data), nil
+	return string(data), nil
 }
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,6 +21,19 @@ func hasZbarimg() bool {
 	return err == nil
 }
 
+func TestIsContentTooLong(t *testing.T) {
+	t.Parallel()
+
+	_, err := qrcode.New(strings.Repeat("a", qrcode.MaxByteCapacity(qrcode.Low)+1), qrcode.Low)
+	if !isContentTooLong(err) {
+		t.Errorf("oversized content: got %v, expected content too long", err)
+	}
+
+	if isContentTooLong(nil) || isContentTooLong(fmt.Errorf("other error")) {
+		t.Errorf("unrelated errors reported as content too long")
+	}
+}
+
 func TestSplitAndWriteCreatesNumberedFiles(t *testing.T) {
 	t.Parallel()
 
@@ -23,7 +41,7 @@ func TestSplitAndWriteCreatesNumberedFiles(t *testing.T) {
 	dir := t.TempDir()
 	prefix := filepath.Join(dir, "qr")
 
-	if err := splitAndWrite(longContent, 32, prefix, false, false, false, false); err != nil {
+	if err := splitAndWrite(longContent, defaultRecoveryLevel, prefix, outputOptions{size: 32, quietZone: defaultQuietZone}, false, false); err != nil {
 		t.Fatalf("splitAndWrite returned error: %v", err)
 	}
 
@@ -55,7 +73,7 @@ func TestSplitAndWriteGrid(t *testing.T) {
 	dir := t.TempDir()
 	prefix := filepath.Join(dir, "qr")
 
-	if err := splitAndWrite(longContent, 32, prefix, false, false, false, true); err != nil {
+	if err := splitAndWrite(longContent, defaultRecoveryLevel, prefix, outputOptions{size: 32, quietZone: defaultQuietZone}, false, true); err != nil {
 		t.Fatalf("splitAndWrite grid returned error: %v", err)
 	}
 
@@ -160,7 +178,7 @@ func TestRoundTripSplitDecode(t *testing.T) {
 	dir := t.TempDir()
 	prefix := filepath.Join(dir, "chunk")
 
-	if err := splitAndWrite(original, 256, prefix, false, false, false, false); err != nil {
+	if err := splitAndWrite(original, defaultRecoveryLevel, prefix, outputOptions{size: 256, quietZone: defaultQuietZone}, false, false); err != nil {
 		t.Fatalf("splitAndWrite failed: %v", err)
 	}
 
@@ -199,7 +217,7 @@ func TestLoadContentFromFileHex(t *testing.T) {
 		t.Fatalf("write temp file failed: %v", err)
 	}
 
-	content, err := loadContent(nil, path)
+	content, err := loadContent(nil, path, true, nil)
 	if err != nil {
 		t.Fatalf("loadContent returned error: %v", err)
 	}
@@ -209,10 +227,55 @@ func TestLoadContentFromFileHex(t *testing.T) {
 	}
 }
 
+func TestLoadContentFromFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "content.txt")
+	data := "line one\nline two: \"quoted\" $HOME `special`\n"
+
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("write temp file failed: %v", err)
+	}
+
+	content, err := loadContent(nil, path, false, nil)
+	if err != nil {
+		t.Fatalf("loadContent returned error: %v", err)
+	}
+	if content != data {
+		t.Fatalf("got %q, expected %q", content, data)
+	}
+
+	q, err := prepareQRCode(content, defaultRecoveryLevel, defaultQuietZone)
+	if err != nil {
+		t.Fatalf("prepareQRCode failed: %v", err)
+	}
+	outFile := filepath.Join(dir, "out")
+	if err := writeSingleCode(q, outputOptions{size: 256, quietZone: defaultQuietZone}, outFile); err != nil {
+		t.Fatalf("writeSingleCode failed: %v", err)
+	}
+	if _, err := os.Stat(outFile + ".png"); err != nil {
+		t.Fatalf("expected file to exist: %v", err)
+	}
+
+	// - reads stdin.
+	content, err = loadContent(nil, "-", false, strings.NewReader(data))
+	if err != nil || content != data {
+		t.Fatalf("stdin: got %q, %v", content, err)
+	}
+
+	if _, err := loadContent(nil, filepath.Join(dir, "missing"), false, nil); err == nil {
+		t.Fatalf("expected error for a missing file")
+	}
+	if _, err := loadContent(nil, "-", false, strings.NewReader("")); err == nil {
+		t.Fatalf("expected error for empty input")
+	}
+}
+
 func TestLoadContentConflicts(t *testing.T) {
 	t.Parallel()
 
-	if _, err := loadContent([]string{"foo"}, "bar"); err == nil {
+	if _, err := loadContent([]string{"foo"}, "bar", false, nil); err == nil {
 		t.Fatalf("expected error when both args and file provided")
 	}
 }
@@ -220,7 +283,352 @@ func TestLoadContentConflicts(t *testing.T) {
 func TestLoadContentNoInput(t *testing.T) {
 	t.Parallel()
 
-	if _, err := loadContent(nil, ""); err == nil {
+	if _, err := loadContent(nil, "", false, nil); err == nil {
 		t.Fatalf("expected error when no input provided")
 	}
 }
+
+func TestBatchWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "url")
+
+	input := "https://example.org/1\n\n   https://example.org/3  \n\t\nhttps://example.org/5"
+
+	if err := batchWrite(strings.NewReader(input), defaultRecoveryLevel, prefix, outputOptions{size: 32, quietZone: defaultQuietZone}); err != nil {
+		t.Fatalf("batchWrite returned error: %v", err)
+	}
+
+	expected := []string{"url-1.png", "url-3.png", "url-5.png"}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+
+	if len(files) != len(expected) {
+		t.Fatalf("unexpected number of files written: got %d, want %d", len(files), len(expected))
+	}
+
+	for i, name := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expected file %s to exist: %v", name, err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s is not a PNG: %v", name, err)
+		}
+
+		content, err := qrcode.Decode(img)
+		if err != nil {
+			t.Fatalf("decode %s failed: %v", name, err)
+		}
+
+		if want := fmt.Sprintf("https://example.org/%d", 2*i+1); content != want {
+			t.Fatalf("%s: got content %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestBatchWriteRequiresPrefix(t *testing.T) {
+	t.Parallel()
+
+	if err := batchWrite(strings.NewReader("a\nb\n"), defaultRecoveryLevel, "", outputOptions{size: 32, quietZone: defaultQuietZone}); err == nil {
+		t.Fatalf("expected error without an output prefix")
+	}
+}
+
+func TestParseRecoveryLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want qrcode.RecoveryLevel
+	}{
+		{"low", qrcode.Low},
+		{"L", qrcode.Low},
+		{"medium", qrcode.Medium},
+		{"M", qrcode.Medium},
+		{"high", qrcode.High},
+		{"Q", qrcode.High},
+		{"highest", qrcode.Highest},
+		{"H", qrcode.Highest},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRecoveryLevel(tt.name)
+		if err != nil {
+			t.Fatalf("parseRecoveryLevel(%q) returned error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseRecoveryLevel(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := parseRecoveryLevel("best"); err == nil || !strings.Contains(err.Error(), "highest") {
+		t.Fatalf("expected error listing valid levels, got %v", err)
+	}
+}
+
+func TestRecoveryLevelSymbolSize(t *testing.T) {
+	t.Parallel()
+
+	content := "https://example.org/recovery-level"
+
+	low, err := prepareQRCode(content, qrcode.Low, defaultQuietZone)
+	if err != nil {
+		t.Fatalf("prepareQRCode failed: %v", err)
+	}
+
+	def, err := prepareQRCode(content, defaultRecoveryLevel, defaultQuietZone)
+	if err != nil {
+		t.Fatalf("prepareQRCode failed: %v", err)
+	}
+
+	if len(low.Bitmap()) >= len(def.Bitmap()) {
+		t.Fatalf("low recovery level symbol is %d modules, default is %d", len(low.Bitmap()), len(def.Bitmap()))
+	}
+}
+
+func TestSplitAndWriteSVG(t *testing.T) {
+	t.Parallel()
+
+	longContent := strings.Repeat("A", 1900)
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "qr")
+
+	opts := outputOptions{size: 256, negative: true, svg: true}
+	if err := splitAndWrite(longContent, defaultRecoveryLevel, prefix, opts, false, false); err != nil {
+		t.Fatalf("splitAndWrite returned error: %v", err)
+	}
+
+	for _, name := range []string{"qr-0.svg", "qr-1.svg"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expected file %s to exist: %v", name, err)
+		}
+
+		// Skip the XML declaration.
+		svg := strings.TrimSpace(string(data))
+		if strings.HasPrefix(svg, "<?xml") {
+			svg = strings.TrimSpace(svg[strings.Index(svg, "?>")+2:])
+		}
+
+		if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+			t.Fatalf("%s is not an SVG document", name)
+		}
+
+		// Inverted: the background is black.
+		if !strings.Contains(svg, `fill="#000000"/>`) {
+			t.Fatalf("%s: background is not inverted", name)
+		}
+	}
+
+	if err := splitAndWrite(longContent, defaultRecoveryLevel, prefix, opts, false, true); err == nil {
+		t.Fatalf("expected error for SVG grid output")
+	}
+}
+
+func TestLogo(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// A solid red logo.
+	red := color.RGBA{R: 0xff, A: 0xff}
+	logo := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(logo, logo.Bounds(), &image.Uniform{red}, image.Point{}, draw.Src)
+
+	logoFile := filepath.Join(dir, "logo.png")
+	fh, err := os.Create(logoFile)
+	if err != nil {
+		t.Fatalf("create logo: %v", err)
+	}
+	if err := png.Encode(fh, logo); err != nil {
+		t.Fatalf("encode logo: %v", err)
+	}
+	fh.Close()
+
+	loaded, err := loadLogo(logoFile)
+	if err != nil {
+		t.Fatalf("loadLogo failed: %v", err)
+	}
+
+	q, err := prepareQRCode("https://example.org/logo", qrcode.Highest, defaultQuietZone)
+	if err != nil {
+		t.Fatalf("prepareQRCode failed: %v", err)
+	}
+
+	opts := outputOptions{size: 512, quietZone: defaultQuietZone, logo: loaded, logoScale: defaultLogoScale}
+	outFile := filepath.Join(dir, "qr")
+	if err := writeSingleCode(q, opts, outFile); err != nil {
+		t.Fatalf("writeSingleCode failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile + ".png")
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatalf("got an empty PNG")
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+
+	b := img.Bounds()
+	if c := color.RGBAModel.Convert(img.At(b.Dx()/2, b.Dy()/2)); c != red {
+		t.Errorf("centre pixel: got %v, expected the logo colour %v", c, red)
+	}
+
+	// Errors for a missing or invalid logo file, and a logo too large to
+	// recover from.
+	if _, err := loadLogo(filepath.Join(dir, "missing.png")); err == nil {
+		t.Errorf("missing logo: got success, expected error")
+	}
+	if _, err := loadLogo(os.Args[0]); err == nil {
+		t.Errorf("invalid logo: got success, expected error")
+	}
+
+	opts.logoScale = 0.9
+	if err := writeSingleCode(q, opts, outFile); err == nil {
+		t.Errorf("logo scale 0.9: got success, expected error")
+	}
+}
+
+func TestAutoLevel(t *testing.T) {
+	t.Parallel()
+
+	// Version 40 holds 1273 bytes at Highest, 1663 at High and 2331 at
+	// Medium.
+	content := strings.Repeat("x", 2000)
+
+	if _, err := prepareQRCode(content, qrcode.Highest, defaultQuietZone); !isContentTooLong(err) {
+		t.Fatalf("got %v at highest, expected content too long", err)
+	}
+
+	var warn bytes.Buffer
+	q, err := prepareQRCodeAutoLevel(content, qrcode.Highest, defaultQuietZone, &warn)
+	if err != nil {
+		t.Fatalf("prepareQRCodeAutoLevel failed: %v", err)
+	}
+	if q.Level != qrcode.Medium {
+		t.Errorf("got recovery level %s, expected medium", levelName(q.Level))
+	}
+	if got, expected := warn.String(), "-auto-level: using recovery level medium instead of highest\n"; got != expected {
+		t.Errorf("got warning %q, expected %q", got, expected)
+	}
+
+	// Content which fits is unchanged, and not reported.
+	warn.Reset()
+	q, err = prepareQRCodeAutoLevel("short", qrcode.Highest, defaultQuietZone, &warn)
+	if err != nil {
+		t.Fatalf("prepareQRCodeAutoLevel failed: %v", err)
+	}
+	if q.Level != qrcode.Highest || warn.Len() != 0 {
+		t.Errorf("short: got recovery level %s, warning %q", levelName(q.Level), warn.String())
+	}
+
+	// Content too long at every level is an error.
+	if _, err := prepareQRCodeAutoLevel(strings.Repeat("x", 3000), qrcode.Highest, defaultQuietZone, &warn); !isContentTooLong(err) {
+		t.Errorf("too long: got %v, expected content too long", err)
+	}
+}
+
+func TestVerboseOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	var stderr bytes.Buffer
+	opts := outputOptions{size: 32, quietZone: defaultQuietZone, verbose: &stderr}
+
+	q, err := prepareQRCode("https://example.org/verbose", qrcode.Medium, defaultQuietZone)
+	if err != nil {
+		t.Fatalf("prepareQRCode failed: %v", err)
+	}
+	if err := writeSingleCode(q, opts, filepath.Join(dir, "single")); err != nil {
+		t.Fatalf("writeSingleCode failed: %v", err)
+	}
+
+	expected := fmt.Sprintf("single.png: version %d (%dx%d modules), recovery level medium, mask %d\n",
+		q.Version(), 21+4*(q.Version()-1), 21+4*(q.Version()-1), q.Mask())
+	if got := stderr.String(); !strings.Contains(got, "version") || !strings.HasSuffix(got, expected) {
+		t.Fatalf("got %q, expected %q", got, expected)
+	}
+
+	// One line per chunk with -split-long.
+	stderr.Reset()
+	if err := splitAndWrite(strings.Repeat("A", 1900), qrcode.Highest, filepath.Join(dir, "qr"), opts, false, false); err != nil {
+		t.Fatalf("splitAndWrite returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2: %q", len(lines), stderr.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, fmt.Sprintf("qr-%d.png: version ", i)) || !strings.Contains(line, "recovery level highest") {
+			t.Errorf("chunk %d: got %q", i, line)
+		}
+	}
+
+	// Quiet without -v.
+	stderr.Reset()
+	opts.verbose = nil
+	if err := writeSingleCode(q, opts, filepath.Join(dir, "quiet")); err != nil {
+		t.Fatalf("writeSingleCode failed: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("got %q, expected no output", stderr.String())
+	}
+}
+
+func TestQuietZoneWidth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := "https://example.org/quiet-zone"
+
+	imageSize := func(quietZone int) (int, int) {
+		q, err := prepareQRCode(content, defaultRecoveryLevel, quietZone)
+		if err != nil {
+			t.Fatalf("prepareQRCode failed: %v", err)
+		}
+
+		outFile := filepath.Join(dir, fmt.Sprintf("qz-%d", quietZone))
+		if err := writeSingleCode(q, outputOptions{size: -4, quietZone: quietZone}, outFile); err != nil {
+			t.Fatalf("writeSingleCode failed: %v", err)
+		}
+
+		fh, err := os.Open(outFile + ".png")
+		if err != nil {
+			t.Fatalf("expected file to exist: %v", err)
+		}
+		defer fh.Close()
+
+		config, err := png.DecodeConfig(fh)
+		if err != nil {
+			t.Fatalf("invalid PNG: %v", err)
+		}
+
+		return config.Width, config.Height
+	}
+
+	defW, defH := imageSize(defaultQuietZone)
+	w, h := imageSize(2)
+
+	if w >= defW || h >= defH {
+		t.Fatalf("-q 2 image is %dx%d, default is %dx%d", w, h, defW, defH)
+	}
+
+	// Each step removes two modules from each side.
+	if noneW, _ := imageSize(0); noneW >= w || w-noneW != defW-w {
+		t.Fatalf("-q 0 image is %d pixels wide, -q 2 is %d, default is %d", noneW, w, defW)
+	}
+}
@@ -137,6 +137,54 @@ func TestDecodeAllCharacters(t *testing.T) {
 	}
 }
 
+func TestDecodeECI(t *testing.T) {
+	if !*testDecode {
+		t.Skip("Decode tests not enabled")
+	}
+
+	tests := []struct {
+		content string
+		eci     uint32
+	}{
+		{
+			"Grüße aus Köln",
+			26,
+		},
+		{
+			"こんにちは",
+			26,
+		},
+		{
+			"Gr\xfc\xdfe",
+			3,
+		},
+	}
+
+	for _, test := range tests {
+		q, err := NewWithECI(test.content, Medium, test.eci)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+
+		s, err := zbarimgDecode(q)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+
+		// zbarimg outputs UTF-8.
+		expected := test.content
+		if test.eci == 3 {
+			expected = "Grüße"
+		}
+
+		if s != expected {
+			t.Errorf("ECI %d: got %q, expected %q", test.eci, s, expected)
+		}
+	}
+}
+
 func TestDecodeFuzz(t *testing.T) {
 	if !*testDecodeFuzz {
 		t.Skip("Decode fuzz tests not enabled")
@@ -4,8 +4,22 @@
 package qrcode
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestQRCodeMaxCapacity(t *testing.T) {
@@ -142,22 +156,1607 @@ func TestQRCodeVersionCapacity(t *testing.T) {
 	}
 }
 
+func TestErrContentTooLong(t *testing.T) {
+	content := strings.Repeat("a", MaxByteCapacity(Low)+1)
+
+	if _, err := New(content, Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("New: got %v, expected ErrContentTooLong", err)
+	}
+
+	if _, err := NewWithForcedVersion(content, 10, Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("NewWithForcedVersion: got %v, expected ErrContentTooLong", err)
+	}
+
+	if _, err := NewMicro(strings.Repeat("1", 36), Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("NewMicro: got %v, expected ErrContentTooLong", err)
+	}
+
+	if _, err := NewBytes(make([]byte, 1<<16), Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("NewBytes: got %v, expected ErrContentTooLong", err)
+	}
+
+	// Other errors are distinct.
+	if _, err := New("", Low); err == nil || errors.Is(err, ErrContentTooLong) {
+		t.Errorf("no content: got %v, expected another error", err)
+	}
+}
+
+func TestContentTooLongPrecheck(t *testing.T) {
+	// The most digits each level holds, from ISO/IEC 18004 Table 7, fit within
+	// the bound.
+	maxDigits := map[RecoveryLevel]int{Low: 7089, Medium: 5596, High: 3993, Highest: 3057}
+
+	for level, n := range maxDigits {
+		if bound := maxContentLength(level); n > bound {
+			t.Errorf("level %d: got bound %d, expected at least %d", level, bound, n)
+		}
+
+		if _, err := New(strings.Repeat("1", n), level); err != nil {
+			t.Errorf("level %d: %d digits: %v", level, n, err)
+		}
+	}
+
+	// Huge content is rejected without segmenting it.
+	content := strings.Repeat("a", 10<<20)
+
+	start := time.Now()
+	if _, err := New(content, Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("New: got %v, expected ErrContentTooLong", err)
+	}
+	if _, err := VersionFor(content, Low); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("VersionFor: got %v, expected ErrContentTooLong", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("10MB content took %v to reject", d)
+	}
+}
+
+func TestInvalidRecoveryLevel(t *testing.T) {
+	q, err := New("valid", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, level := range []RecoveryLevel{RecoveryLevel(99), RecoveryLevel(-1), Highest + 1} {
+		constructors := map[string]func() error{
+			"New": func() error { _, err := New("content", level); return err },
+			"NewWithForcedVersion": func() error {
+				_, err := NewWithForcedVersion("content", 5, level)
+				return err
+			},
+			"NewMicro":    func() error { _, err := NewMicro("123", level); return err },
+			"NewBytes":    func() error { _, err := NewBytes([]byte{0}, level); return err },
+			"NewWithECI":  func() error { _, err := NewWithECI("content", level, 26); return err },
+			"EncodeMulti": func() error { _, err := EncodeMulti("content", level); return err },
+			"EncodeStructuredAppend": func() error {
+				_, err := EncodeStructuredAppend("content", level)
+				return err
+			},
+			"EncodeMultiConcurrent": func() error {
+				_, err := EncodeMultiConcurrent("content", level, 4)
+				return err
+			},
+			"WithLevel": func() error { _, err := q.WithLevel(level); return err },
+		}
+
+		for name, constructor := range constructors {
+			if err := constructor(); err == nil || !strings.Contains(err.Error(), "invalid recovery level") {
+				t.Errorf("%s, level %d: got %v, expected invalid recovery level error", name, level, err)
+			}
+		}
+	}
+}
+
+func TestQRCodeNewBytes(t *testing.T) {
+	// Includes NUL, 0xff, and a Shift JIS Kanji character which New would
+	// encode in Kanji mode.
+	data := []byte{0x00, 0xff, 0x93, 0x5f, '1', '2', '3', 'A', 0x00, 0x80}
+
+	q, err := NewBytes(data, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != string(data) {
+		t.Errorf("got content %q, expected %q", q.Content, data)
+	}
+
+	segments := q.encoder.optimised
+	if len(segments) != 1 || segments[0].dataMode != dataModeByte || string(segments[0].data) != string(data) {
+		t.Errorf("got segments %s, expected a single byte segment", segmentsString(segments))
+	}
+
+	if q.encoder.hasECI {
+		t.Errorf("got ECI header, expected none")
+	}
+
+	s, err := Decode(q.Image(-3))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s != string(data) {
+		t.Errorf("decode: got %q, expected %q", s, data)
+	}
+
+	// Long data uses the larger versions' character count fields.
+	long := bytes.Repeat([]byte{0x00, 0xff}, 500)
+	if q, err := NewBytes(long, Low); err != nil || q.VersionNumber < 10 {
+		t.Errorf("1000 bytes: got %v, %v", q, err)
+	}
+
+	if _, err := NewBytes(nil, Medium); err == nil {
+		t.Errorf("no data: got success, expected error")
+	}
+	if _, err := NewBytes(make([]byte, MaxByteCapacity(Low)+1), Low); err == nil {
+		t.Errorf("too long: got success, expected error")
+	}
+
+	// WithLevel keeps the byte encoding.
+	r, err := q.WithLevel(Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r.encoder.singleMode != dataModeByte || len(r.encoder.optimised) != 1 {
+		t.Errorf("WithLevel: byte encoding not kept")
+	}
+}
+
+func TestQRCodeNewNumeric(t *testing.T) {
+	for _, digits := range []string{"0", "0123456789", strings.Repeat("9876543210", 100), strings.Repeat("1", MaxNumericCapacity(Low))} {
+		for _, level := range []RecoveryLevel{Low, Medium, High, Highest} {
+			expected, err := New(digits, level)
+			if err != nil {
+				continue
+			}
+
+			q, err := NewNumeric(digits, level)
+			if err != nil {
+				t.Fatalf("%d digits, level %d: got error %s", len(digits), level, err.Error())
+			}
+
+			segments := q.encoder.optimised
+			if len(segments) != 1 || segments[0].dataMode != dataModeNumeric {
+				t.Errorf("%d digits, level %d: got segments %s, expected a single numeric segment",
+					len(digits), level, segmentsString(segments))
+			}
+
+			if q.Fingerprint() != expected.Fingerprint() {
+				t.Errorf("%d digits, level %d: symbol differs from New()", len(digits), level)
+			}
+		}
+	}
+
+	for _, digits := range []string{"", "123a", "12 34", "١٢٣", strings.Repeat("1", MaxNumericCapacity(Low)+1)} {
+		if _, err := NewNumeric(digits, Low); err == nil {
+			t.Errorf("%q: got success, expected error", truncate(digits, 10))
+		}
+	}
+
+	q, err := NewNumeric("0123456789", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r, err := q.WithLevel(Highest); err != nil || r.encoder.singleMode != dataModeNumeric {
+		t.Errorf("WithLevel: got %v, %v", r, err)
+	}
+}
+
+func TestQRCodeNewUpper(t *testing.T) {
+	content := "hello world 123"
+
+	q, err := New(content, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	u, err := NewUpper(content, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if u.Content != "HELLO WORLD 123" {
+		t.Errorf("got content %q", u.Content)
+	}
+
+	if u.VersionNumber >= q.VersionNumber {
+		t.Errorf("NewUpper got version %d, expected smaller than New's %d", u.VersionNumber, q.VersionNumber)
+	}
+
+	// Only ASCII letters are converted.
+	if u, err := NewUpper("grüße ß", Medium); err != nil || u.Content != "GRüßE ß" {
+		t.Errorf("got %v, %v", u, err)
+	}
+}
+
+func TestQRCodeFingerprint(t *testing.T) {
+	tests := []struct {
+		content string
+		level   RecoveryLevel
+	}{
+		{"https://example.org", Medium},
+		{strings.Repeat("0123456789", 100), Low},
+		{"Grüße", Highest},
+	}
+
+	seen := map[string]bool{}
+	for _, test := range tests {
+		a, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		b, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		fingerprint := a.Fingerprint()
+		if len(fingerprint) != 64 {
+			t.Errorf("got fingerprint %q, expected 64 hex digits", fingerprint)
+		}
+
+		if fingerprint != b.Fingerprint() || a.Mask() != b.Mask() {
+			t.Errorf("%q: independent encodings differ", test.content)
+		}
+
+		// Drawing options do not affect the fingerprint.
+		b.ForegroundColor = color.RGBA{0x80, 0x00, 0x00, 0xff}
+		b.DisableBorder = true
+		if b.Fingerprint() != fingerprint {
+			t.Errorf("%q: fingerprint changed by drawing options", test.content)
+		}
+
+		if seen[fingerprint] {
+			t.Errorf("%q: duplicate fingerprint", test.content)
+		}
+		seen[fingerprint] = true
+
+		// A different symbol has a different fingerprint.
+		b.ForcedMask = (a.Mask() + 1) % 8
+		if b.Fingerprint() == fingerprint {
+			t.Errorf("%q: fingerprint unchanged by mask", test.content)
+		}
+	}
+
+	// Golden value, guarding against changes to the encoding.
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const golden = "92229d61f7d7f593945c192a1f3808b02ed96e9b0bcf65d64c472989dd3df711"
+	if got := q.Fingerprint(); got != golden {
+		t.Errorf("got fingerprint %s, expected %s", got, golden)
+	}
+}
+
+func TestQRCodeMinVersion(t *testing.T) {
+	tests := []struct {
+		content    string
+		minVersion int
+		expected   int
+	}{
+		{"short", 1, 1},
+		{"short", 10, 10},
+		{"short", 27, 27},
+		{"short", 40, 40},
+		// Content too long for the minimum version ignores it.
+		{strings.Repeat("0", 200), 1, 5},
+		{strings.Repeat("0", 200), 3, 5},
+		{strings.Repeat("0", 200), 6, 6},
+	}
+
+	for _, test := range tests {
+		q, err := NewWithMinVersion(test.content, Medium, test.minVersion)
+		if err != nil {
+			t.Errorf("minimum version %d: got error %s", test.minVersion, err.Error())
+			continue
+		}
+
+		if q.VersionNumber != test.expected {
+			t.Errorf("%d byte content, minimum version %d: got version %d, expected %d",
+				len(test.content), test.minVersion, q.VersionNumber, test.expected)
+		}
+
+		// The symbol can be built.
+		if size := len(q.Matrix()); size != 21+(test.expected-1)*4 {
+			t.Errorf("got symbol size %d for version %d", size, test.expected)
+		}
+	}
+
+	for _, minVersion := range []int{0, 41, -1} {
+		if _, err := NewWithMinVersion("short", Medium, minVersion); err == nil {
+			t.Errorf("minimum version %d: got success, expected error", minVersion)
+		}
+	}
+}
+
+func TestQRCodeVersionAndMask(t *testing.T) {
+	tests := []struct {
+		content  string
+		level    RecoveryLevel
+		expected int
+	}{
+		// 41 and 187 digits fill versions 1 and 4 at Low.
+		{strings.Repeat("1", 41), Low, 1},
+		{strings.Repeat("1", 42), Low, 2},
+		{strings.Repeat("1", 187), Low, 4},
+		{strings.Repeat("1", 188), Low, 5},
+		{strings.Repeat("1", 7089), Low, 40},
+	}
+
+	for _, test := range tests {
+		q, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if q.Version() != test.expected {
+			t.Errorf("%d digits: got version %d, expected %d", len(test.content),
+				q.Version(), test.expected)
+		}
+
+		mask := q.Mask()
+		if mask < 0 || mask > 7 {
+			t.Errorf("%d digits: got mask %d, expected 0-7", len(test.content), mask)
+		}
+
+		// Identical inputs select the same mask.
+		q2, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		q2.Bitmap()
+		if q2.Mask() != mask {
+			t.Errorf("%d digits: got masks %d and %d for identical content",
+				len(test.content), mask, q2.Mask())
+		}
+	}
+}
+
+func TestParallelMaskSelection(t *testing.T) {
+	defer func(parallel bool) { ParallelMaskSelection = parallel }(ParallelMaskSelection)
+
+	contents := []string{
+		"a",
+		"https://example.org/parallel",
+		strings.Repeat("Parallel mask selection. ", 40),
+		strings.Repeat("0123456789", 700),
+	}
+
+	for _, content := range contents {
+		q, err := New(content, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ParallelMaskSelection = false
+		serial, mask, penalty := q.Fingerprint(), q.Mask(), q.MaskPenalty()
+
+		ParallelMaskSelection = true
+		if q.Fingerprint() != serial || q.Mask() != mask || q.MaskPenalty() != penalty {
+			t.Errorf("version %d: got mask %d (penalty %d), expected mask %d (penalty %d)",
+				q.Version(), q.Mask(), q.MaskPenalty(), mask, penalty)
+		}
+
+		q.ForcedMask = (mask + 1) % 8
+		if q.Mask() != q.ForcedMask {
+			t.Errorf("version %d: got mask %d, expected forced mask %d", q.Version(), q.Mask(), q.ForcedMask)
+		}
+	}
+}
+
+func TestQRCodeMaskPenalty(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const expected = 1052
+
+	auto := q.Mask()
+	penalty := q.MaskPenalty()
+	if penalty != expected {
+		t.Errorf("got penalty %d, expected %d", penalty, expected)
+	}
+
+	// The automatic mask has the lowest penalty of all the masks.
+	for mask := 0; mask < 8; mask++ {
+		q.ForcedMask = mask
+
+		p := q.MaskPenalty()
+		if p != q.symbol.penaltyScore() {
+			t.Errorf("ForcedMask=%d: got penalty %d, expected %d", mask, p, q.symbol.penaltyScore())
+		}
+
+		if p < penalty || (mask == auto && p != penalty) {
+			t.Errorf("ForcedMask=%d: got penalty %d, automatic mask %d has penalty %d",
+				mask, p, auto, penalty)
+		}
+	}
+}
+
+func TestQRCodeFormatInfo(t *testing.T) {
+	// Values from ISO/IEC 18004 Annex C, Table C.1.
+	tests := []struct {
+		level    RecoveryLevel
+		mask     int
+		expected uint16
+	}{
+		{Low, 0, 0x77c4},
+		{Low, 4, 0x662f},
+		{Medium, 0, 0x5412},
+		{Medium, 5, 0x40ce},
+		{High, 0, 0x355f},
+		{Highest, 0, 0x1689},
+		{Highest, 7, 0x083b},
+	}
+
+	for _, test := range tests {
+		q, err := New("format information", test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.ForcedMask = test.mask
+
+		if got := q.FormatInfo(); got != test.expected {
+			t.Errorf("level %d mask %d: got %#04x, expected %#04x", test.level, test.mask, got, test.expected)
+		}
+	}
+
+	// Micro QR Code M1 (symbol number 0), mask 0.
+	m, err := NewMicro("1", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	m.ForcedMask = 0
+
+	if m.Version() != 1 {
+		t.Fatalf("got Micro QR Code version %d, expected 1", m.Version())
+	}
+	if got := m.FormatInfo(); got != 0x4445 {
+		t.Errorf("Micro QR Code: got %#04x, expected 0x4445", got)
+	}
+}
+
+func TestQRCodeForcedMask(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.ForcedMask != -1 {
+		t.Errorf("got default ForcedMask %d, expected -1", q.ForcedMask)
+	}
+	auto := q.Mask()
+
+	for mask := 0; mask < 8; mask++ {
+		q.ForcedMask = mask
+
+		if got := q.Mask(); got != mask {
+			t.Errorf("ForcedMask=%d: got mask %d", mask, got)
+		}
+
+		s, err := Decode(q.Image(-3))
+		if err != nil || s != q.Content {
+			t.Errorf("ForcedMask=%d: decoded %q, %v", mask, s, err)
+		}
+	}
+
+	for _, mask := range []int{-1, 8} {
+		q.ForcedMask = mask
+
+		if got := q.Mask(); got != auto {
+			t.Errorf("ForcedMask=%d: got mask %d, expected automatic mask %d", mask, got, auto)
+		}
+	}
+}
+
+func TestQRCodeWriteMatchesBytes(t *testing.T) {
+	q, err := New("https://example.org/write", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.ForegroundColor = color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+
+	tests := []struct {
+		name  string
+		bytes func() ([]byte, error)
+		write func(w io.Writer) error
+	}{
+		{"PNG", func() ([]byte, error) { return q.PNG(-4) }, func(w io.Writer) error { return q.Write(-4, w) }},
+		{"PNGWithDPI", func() ([]byte, error) { return q.PNGWithDPI(-4, 150) }, func(w io.Writer) error { return q.WritePNGWithDPI(-4, 150, w) }},
+		{"SVG", func() ([]byte, error) { return q.SVG(256) }, func(w io.Writer) error { return q.WriteSVG(256, w) }},
+		{"GIF", func() ([]byte, error) { return q.GIF(-4) }, func(w io.Writer) error { return q.WriteGIF(-4, w) }},
+		{"JPEG", func() ([]byte, error) { return q.JPEG(-4, 90) }, func(w io.Writer) error { return q.WriteJPEG(-4, 90, w) }},
+		{"WebP", func() ([]byte, error) { return q.WebP(-4, true) }, func(w io.Writer) error { return q.WriteWebP(-4, true, w) }},
+	}
+
+	for _, test := range tests {
+		expected, err := test.bytes()
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+
+		var b bytes.Buffer
+		if err := test.write(&b); err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("%s: wrote %d bytes, differing from the %d bytes returned", test.name, b.Len(), len(expected))
+		}
+
+		// Write errors are returned.
+		if err := test.write(failingWriter{}); err == nil {
+			t.Errorf("%s: got success writing to a failing writer, expected error", test.name)
+		}
+	}
+
+	// Invalid arguments are reported before anything is written.
+	q.StrictSize = true
+	var b bytes.Buffer
+	if err := q.Write(10, &b); err == nil || b.Len() != 0 {
+		t.Errorf("too small: got %v, wrote %d bytes, expected error and no output", err, b.Len())
+	}
+	if err := q.WritePNGWithDPI(-4, 0, &b); err == nil || b.Len() != 0 {
+		t.Errorf("0 DPI: got %v, wrote %d bytes, expected error and no output", err, b.Len())
+	}
+}
+
+func TestInsertWriter(t *testing.T) {
+	for _, chunkSize := range []int{1, 2, 3, 4, 10} {
+		var b bytes.Buffer
+		w := &insertWriter{out: &b, offset: 4, insert: []byte("-")}
+
+		data := []byte("abcdefghi")
+		for i := 0; i < len(data); i += chunkSize {
+			end := min(i+chunkSize, len(data))
+			if n, err := w.Write(data[i:end]); err != nil || n != end-i {
+				t.Fatalf("chunk size %d: got %d, %v, expected %d", chunkSize, n, err, end-i)
+			}
+		}
+
+		if b.String() != "abcd-efghi" {
+			t.Errorf("chunk size %d: got %q, expected \"abcd-efghi\"", chunkSize, b.String())
+		}
+	}
+}
+
+// failingWriter is an io.Writer which always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestQRCodePNGPaletted(t *testing.T) {
+	q, err := New("https://example.org/paletted", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A gradient gives a full colour image.
+	q.Gradient = &Gradient{Start: color.Black, End: color.RGBA{B: 0x80, A: 0xff}}
+
+	const size = 1000
+	rgba := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(rgba, rgba.Bounds(), q.Image(size), image.Point{}, draw.Src)
+
+	var full bytes.Buffer
+	if err := png.Encode(&full, rgba); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := q.PNGPaletted(size)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(data) >= full.Len() {
+		t.Errorf("got %d bytes, expected fewer than the RGBA PNG's %d", len(data), full.Len())
+	}
+
+	// The bit depth in the IHDR chunk.
+	if depth := data[8+4+4+8]; depth != 1 {
+		t.Errorf("got bit depth %d, expected 1", depth)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	paletted, ok := img.(*image.Paletted)
+	if !ok || len(paletted.Palette) != 2 {
+		t.Fatalf("got %T, expected a 2 colour paletted image", img)
+	}
+
+	if got := img.Bounds().Size(); got != (image.Point{size, size}) {
+		t.Errorf("got size %v, expected %dx%d", got, size, size)
+	}
+
+	if s, err := Decode(img); err != nil || s != q.Content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	q.StrictSize = true
+	if _, err := q.PNGPaletted(10); err == nil {
+		t.Errorf("size 10: got success, expected error")
+	}
+}
+
+func TestQRCodePNGWithDPI(t *testing.T) {
+	q, err := New("https://example.org/dpi", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := q.PNGWithDPI(1000, 300)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if img, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err.Error())
+	} else if b := img.Bounds(); b.Dx() != 1000 {
+		t.Errorf("got width %d, expected 1000", b.Dx())
+	}
+
+	// Walk the chunks following the PNG signature.
+	var types []string
+	var phys []byte
+	for p := 8; p+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[p:]))
+		chunkType := string(data[p+4 : p+8])
+		chunk := data[p+8 : p+8+length]
+
+		if sum := binary.BigEndian.Uint32(data[p+8+length:]); sum != crc32.ChecksumIEEE(data[p+4:p+8+length]) {
+			t.Errorf("%s chunk: bad CRC %#x", chunkType, sum)
+		}
+
+		types = append(types, chunkType)
+		if chunkType == "pHYs" {
+			phys = chunk
+		}
+		p += 12 + length
+	}
+
+	// The pHYs chunk must precede the image data.
+	if order := strings.Join(types, " "); !strings.HasPrefix(order, "IHDR pHYs ") ||
+		!strings.Contains(order, " IDAT") {
+		t.Errorf("got chunks %v, expected IHDR, pHYs, ..., IDAT", types)
+	}
+
+	// 300 DPI is 11811 pixels per metre.
+	if len(phys) != 9 {
+		t.Fatalf("got pHYs length %d, expected 9", len(phys))
+	}
+	x, y := binary.BigEndian.Uint32(phys), binary.BigEndian.Uint32(phys[4:])
+	if x != 11811 || y != 11811 || phys[8] != 1 {
+		t.Errorf("got pHYs %dx%d unit %d, expected 11811x11811 unit 1", x, y, phys[8])
+	}
+
+	for _, dpi := range []int{0, -72} {
+		if _, err := q.PNGWithDPI(1000, dpi); err == nil {
+			t.Errorf("%d DPI: got success, expected error", dpi)
+		}
+	}
+}
+
+func TestQRCodeContrastRatio(t *testing.T) {
+	q, err := New("https://example.org/contrast", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	gray := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+	lightGray := color.RGBA{R: 0xa0, G: 0xa0, B: 0xa0, A: 0xff}
+
+	tests := []struct {
+		name   string
+		fg, bg color.Color
+		min    float64
+		max    float64
+		strict bool
+	}{
+		{"black on white", color.Black, color.White, 20.99, 21.01, true},
+		{"white on black", color.White, color.Black, 20.99, 21.01, true},
+		{"gray on light gray", gray, lightGray, 1.4, 1.6, false},
+		{"gray on gray", gray, gray, 1, 1, false},
+		{"translucent black on white", color.NRGBA{A: 0x10}, color.White, 1, 1.3, false},
+	}
+
+	for _, test := range tests {
+		q.ForegroundColor = test.fg
+		q.BackgroundColor = test.bg
+
+		ratio := q.ContrastRatio()
+		if ratio < test.min || ratio > test.max {
+			t.Errorf("%s: got ratio %.3f, expected %.2f-%.2f", test.name, ratio, test.min, test.max)
+		}
+
+		q.StrictContrast = false
+		if _, err := q.PNG(-4); err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+		}
+
+		q.StrictContrast = true
+		_, pngErr := q.PNG(-4)
+		_, svgErr := q.SVG(-4)
+		for _, err := range []error{pngErr, svgErr} {
+			if test.strict && err != nil {
+				t.Errorf("%s: got error %q with StrictContrast", test.name, err.Error())
+			} else if !test.strict && err == nil {
+				t.Errorf("%s: got success with StrictContrast, expected error", test.name)
+			}
+		}
+	}
+}
+
+func TestQRCodeBoostECC(t *testing.T) {
+	// 12 bytes fit version 1 at Low (17 bytes) and Medium (14 bytes), but not
+	// Quartile (11 bytes).
+	content := "hello, world"
+
+	q, err := New(content, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Boosting applies even after the QR Code has been encoded.
+	q.Bitmap()
+	q.BoostECC = true
+	bitmap := q.Bitmap()
+
+	if q.Level != Medium || q.VersionNumber != 1 {
+		t.Errorf("got level %d version %d, expected level %d version 1", q.Level, q.VersionNumber, Medium)
+	}
+
+	expected, err := New(content, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected.ForcedMask = q.Mask()
+
+	if !reflect.DeepEqual(bitmap, expected.Bitmap()) {
+		t.Errorf("boosted QR Code differs from the QR Code at level %d", Medium)
+	}
+
+	if s, err := Decode(q.Image(-4)); err != nil || s != content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	// Content filling its version is not boosted.
+	full, err := New(strings.Repeat("a", 17), Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	full.BoostECC = true
+	full.Bitmap()
+
+	if full.Level != Low || full.VersionNumber != 1 {
+		t.Errorf("full: got level %d version %d, expected level %d version 1", full.Level, full.VersionNumber, Low)
+	}
+}
+
+func TestQRCodeInvert(t *testing.T) {
+	q, err := New("https://example.org/invert", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fg := color.RGBA{R: 0x20, G: 0x40, B: 0x60, A: 0xff}
+	bg := color.RGBA{R: 0xf0, G: 0xe0, B: 0xd0, A: 0xff}
+	q.ForegroundColor = fg
+	q.BackgroundColor = bg
+	fingerprint := q.Fingerprint()
+
+	r := q.Inverted()
+	if r.ForegroundColor != bg || r.BackgroundColor != fg {
+		t.Errorf("Inverted: got colours %v on %v, expected %v on %v",
+			r.ForegroundColor, r.BackgroundColor, bg, fg)
+	}
+	if q.ForegroundColor != fg || q.BackgroundColor != bg {
+		t.Errorf("Inverted changed the original colours to %v on %v", q.ForegroundColor, q.BackgroundColor)
+	}
+	if r.Fingerprint() != fingerprint {
+		t.Errorf("Inverted: got a different symbol")
+	}
+
+	q.Invert()
+	if q.ForegroundColor != bg || q.BackgroundColor != fg {
+		t.Errorf("Invert: got colours %v on %v, expected %v on %v",
+			q.ForegroundColor, q.BackgroundColor, bg, fg)
+	}
+
+	// Inverted QR Codes can be decoded.
+	if s, err := Decode(q.Image(-3)); err != nil || s != q.Content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	q.Invert()
+	if q.ForegroundColor != fg || q.BackgroundColor != bg {
+		t.Errorf("Invert twice: got colours %v on %v, expected %v on %v",
+			q.ForegroundColor, q.BackgroundColor, fg, bg)
+	}
+}
+
+func TestQRCodeWithLevel(t *testing.T) {
+	content := strings.Repeat("https://example.org/", 5)
+
+	q, err := New(content, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.ForegroundColor = color.RGBA{0x33, 0x33, 0x66, 0xff}
+	q.BackgroundColor = color.RGBA{0xef, 0xef, 0xef, 0xff}
+	q.DisableBorder = true
+
+	r, err := q.WithLevel(Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if r.Level != Highest || r.Content != content {
+		t.Errorf("got level %d content %q, expected level %d content %q", r.Level, r.Content, Highest, content)
+	}
+
+	if r.VersionNumber <= q.VersionNumber {
+		t.Errorf("got version %d, expected larger than %d", r.VersionNumber, q.VersionNumber)
+	}
+
+	if r.ForegroundColor != q.ForegroundColor || r.BackgroundColor != q.BackgroundColor || !r.DisableBorder {
+		t.Errorf("drawing options not copied")
+	}
+
+	if s, err := Decode(r.Image(-2)); err != nil || s != content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	// The original is unchanged.
+	if q.Level != Low {
+		t.Errorf("original level changed to %d", q.Level)
+	}
+
+	// Micro QR Codes and ECI headers are kept.
+	m, err := NewMicro("12345", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r, err := m.WithLevel(Medium); err != nil || !r.IsMicro() || r.Level != Medium {
+		t.Errorf("Micro QR Code: got %v, %v", r, err)
+	}
+	if _, err := m.WithLevel(Highest); err == nil {
+		t.Errorf("Micro QR Code at Highest: got success, expected error")
+	}
+
+	e, err := NewWithECI("Grüße", Low, 26)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r, err := e.WithLevel(High); err != nil || !r.encoder.hasECI || r.encoder.eci != 26 {
+		t.Errorf("ECI: got %v, %v", r, err)
+	}
+
+	long, err := New(strings.Repeat("a", MaxByteCapacity(Low)), Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := long.WithLevel(Highest); err == nil {
+		t.Errorf("too long: got success, expected error")
+	}
+}
+
+func TestQRCodeDataBitsUsed(t *testing.T) {
+	tests := []struct {
+		name     string
+		new      func() (*QRCode, error)
+		expected int
+	}{
+		// Mode indicator, 10-bit character count, and 10 digits at 10/3 bits
+		// per digit, rounded up.
+		{"numeric", func() (*QRCode, error) { return NewNumeric("0123456789", Medium) }, 4 + 10 + 34},
+		{"numeric with New", func() (*QRCode, error) { return New("0123456789", Medium) }, 4 + 10 + 34},
+		{"alphanumeric", func() (*QRCode, error) { return New("AB", Medium) }, 4 + 9 + 11},
+		{"byte", func() (*QRCode, error) { return New("ab", Medium) }, 4 + 8 + 16},
+		{"ECI", func() (*QRCode, error) { return NewWithECI("ab", Medium, 26) }, 4 + 8 + 4 + 8 + 16},
+		// M1 has no mode indicator, and a 3-bit character count.
+		{"micro", func() (*QRCode, error) { return NewMicro("12345", Low) }, 3 + 17},
+	}
+
+	for _, test := range tests {
+		q, err := test.new()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if got := q.DataBitsUsed(); got != test.expected {
+			t.Errorf("%s: got %d bits, expected %d", test.name, got, test.expected)
+		}
+
+		// Encoding pads the data, but doesn't change the count.
+		q.Bitmap()
+		if got := q.DataBitsUsed(); got != test.expected {
+			t.Errorf("%s: after encoding got %d bits, expected %d", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestQRCodeRemainingCapacity(t *testing.T) {
+	tests := []struct {
+		content  string
+		level    RecoveryLevel
+		expected int
+	}{
+		// Version 1-L holds 17 bytes.
+		{strings.Repeat("a", 17), Low, 0},
+		{strings.Repeat("a", 4), Low, 13},
+		{"a", Low, 16},
+		// Version 2-H holds 14 bytes.
+		{strings.Repeat("a", 14), Highest, 0},
+		{strings.Repeat("a", 10), Highest, 4},
+		// Version 1-L holds 41 digits, leaving no room for a byte segment.
+		{strings.Repeat("1", 41), Low, 0},
+		// Version 40-L holds 2953 bytes.
+		{strings.Repeat("a", 2953), Low, 0},
+	}
+
+	for _, test := range tests {
+		q, err := New(test.content, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		got := q.RemainingCapacity()
+		if got != test.expected {
+			t.Errorf("%d bytes at version %d: got remaining capacity %d, expected %d",
+				len(test.content), q.VersionNumber, got, test.expected)
+		}
+
+		// The remaining capacity fits without changing version.
+		more, err := New(test.content+strings.Repeat("a", got), test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if more.VersionNumber != q.VersionNumber {
+			t.Errorf("%d bytes: adding %d bytes changed version from %d to %d",
+				len(test.content), got, q.VersionNumber, more.VersionNumber)
+		}
+
+		// The encoder state is unchanged.
+		if string(q.encoder.data) != test.content {
+			t.Errorf("%d bytes: encoder data modified", len(test.content))
+		}
+	}
+}
+
 func TestQRCodeISOAnnexIExample(t *testing.T) {
 	var q *QRCode
 	q, err := New("01234567", Medium)
 
 	if err != nil {
-		t.Fatalf("Error producing ISO Annex I Example: %s, expected success",
-			err.Error())
+		t.Fatalf("Error producing ISO Annex I Example: %s, expected success",
+			err.Error())
+	}
+
+	q.encode()
+
+	const expectedMask int = 2
+
+	if q.mask != 2 {
+		t.Errorf("ISO Annex I example mask got %d, expected %d\n", q.mask,
+			expectedMask)
+	}
+}
+
+func TestQRCodeMatrix(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	m := q.Matrix()
+
+	size := q.version.symbolSize()
+	if len(m) != size {
+		t.Fatalf("Matrix has %d rows, expected %d", len(m), size)
+	}
+
+	for y, row := range m {
+		if len(row) != size {
+			t.Fatalf("Matrix row %d has %d columns, expected %d", y, len(row), size)
+		}
+	}
+
+	// Top left finder pattern: dark outer ring, light inner ring, dark centre.
+	if !m[0][0] || m[1][1] || !m[3][3] {
+		t.Errorf("Matrix does not start with the top left finder pattern")
+	}
+
+	// Top right and bottom left finder patterns.
+	if !m[0][size-1] || !m[size-1][0] || m[1][size-2] || m[size-2][1] {
+		t.Errorf("Matrix corners do not match the finder patterns")
+	}
+
+	// The result is a copy.
+	m[0][0] = false
+	if !q.Matrix()[0][0] {
+		t.Errorf("Matrix returned internal state")
+	}
+
+	// The quiet zone is never included.
+	q.DisableBorder = true
+	if len(q.Matrix()) != size {
+		t.Errorf("Matrix size changed with DisableBorder")
+	}
+}
+
+func TestQRCodeBitmapWithBorder(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
 	}
 
-	q.encode()
+	size := q.version.symbolSize()
 
-	const expectedMask int = 2
+	for _, quietZone := range []int{0, -1, 4} {
+		q.QuietZone = quietZone
 
-	if q.mask != 2 {
-		t.Errorf("ISO Annex I example mask got %d, expected %d\n", q.mask,
-			expectedMask)
+		data := q.DataBitmap()
+		if len(data) != size || len(data[0]) != size {
+			t.Fatalf("QuietZone=%d: DataBitmap is %dx%d, expected %dx%d",
+				quietZone, len(data[0]), len(data), size, size)
+		}
+
+		grid, border := q.BitmapWithBorder()
+		if border != q.quietZoneSize() || len(grid) != size+2*border {
+			t.Fatalf("QuietZone=%d: got %d rows with border %d", quietZone, len(grid), border)
+		}
+
+		for y := range data {
+			for x := range data[y] {
+				if data[y][x] != grid[y+border][x+border] {
+					t.Fatalf("QuietZone=%d: module (%d, %d) differs", quietZone, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestQRCodeBorderColor(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain := q.ToString(false)
+
+	frame := color.RGBA{0x20, 0x40, 0x80, 0xff}
+	q.BorderColor = frame
+	q.QuietZone = 4
+
+	for _, bg := range []color.Color{color.White, color.Transparent} {
+		q.BackgroundColor = bg
+
+		for _, size := range []int{-10, 333} {
+			img := q.Image(size)
+			grid, border := q.BitmapWithBorder()
+			scale := float64(img.Bounds().Dx()) / float64(len(grid))
+
+			// Find a light module inside the symbol.
+			var light image.Point
+			for y := border; y < len(grid)-border && light == (image.Point{}); y++ {
+				for x := border; x < len(grid)-border; x++ {
+					if !grid[y][x] {
+						light = image.Point{x, y}
+						break
+					}
+				}
+			}
+
+			at := func(mx, my int) color.Color {
+				return img.At(int((float64(mx)+0.5)*scale), int((float64(my)+0.5)*scale))
+			}
+
+			for _, m := range []image.Point{{0, 0}, {border - 1, border + 3}, {len(grid) - 1, len(grid) / 2}} {
+				if got := color.RGBAModel.Convert(at(m.X, m.Y)); got != frame {
+					t.Errorf("size %d: border module %v got %v, expected %v", size, m, got, frame)
+				}
+			}
+
+			expected := color.RGBAModel.Convert(bg)
+			if got := color.RGBAModel.Convert(at(light.X, light.Y)); got != expected {
+				t.Errorf("size %d: light module %v got %v, expected %v", size, light, got, expected)
+			}
+		}
+	}
+
+	// Text output is unaffected.
+	q.QuietZone = 0
+	if q.ToString(false) != plain {
+		t.Errorf("ToString changed by BorderColor")
+	}
+}
+
+func TestQRCodeFinderColor(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fingerprint := q.Fingerprint()
+
+	eye := color.RGBA{0xc0, 0x10, 0x30, 0xff}
+	q.FinderColor = eye
+
+	for _, fg := range []color.Color{color.Black, color.RGBA{0, 0, 0, 0x80}} {
+		q.ForegroundColor = fg
+
+		img := q.Image(-10)
+		grid, border := q.BitmapWithBorder()
+
+		at := func(mx, my int) color.Color {
+			return color.RGBAModel.Convert(img.At(mx*10+5, my*10+5))
+		}
+
+		// Outer ring, inner square, the light ring between them, and the
+		// separator, of each of the three finder patterns.
+		n := len(grid) - 2*border
+		for _, origin := range []image.Point{{border, border}, {border + n - 7, border}, {border, border + n - 7}} {
+			for _, m := range []image.Point{{0, 0}, {6, 6}, {3, 3}} {
+				if got := at(origin.X+m.X, origin.Y+m.Y); got != eye {
+					t.Errorf("finder module %v: got %v, expected %v", origin.Add(m), got, eye)
+				}
+			}
+
+			if got, expected := at(origin.X+1, origin.Y+1), color.RGBAModel.Convert(q.BackgroundColor); got != expected {
+				t.Errorf("finder light ring %v: got %v, expected %v", origin, got, expected)
+			}
+		}
+		if got, expected := at(border+7, border+7), color.RGBAModel.Convert(q.BackgroundColor); got != expected {
+			t.Errorf("separator: got %v, expected %v", got, expected)
+		}
+
+		// A dark data module keeps the ForegroundColor.
+		var data image.Point
+		for y := border + 9; y < len(grid)-border && data == (image.Point{}); y++ {
+			for x := border + 9; x < len(grid)-border; x++ {
+				if grid[y][x] {
+					data = image.Point{x, y}
+					break
+				}
+			}
+		}
+		if got, expected := at(data.X, data.Y), color.RGBAModel.Convert(fg); got != expected || got == eye {
+			t.Errorf("data module %v: got %v, expected %v", data, got, expected)
+		}
+
+		if s, err := Decode(img); fg == color.Black && (err != nil || s != q.Content) {
+			t.Errorf("decode: got %q, %v", s, err)
+		}
+	}
+
+	if q.Fingerprint() != fingerprint {
+		t.Errorf("FinderColor changed the symbol")
+	}
+}
+
+func TestQRCodeTransparentBackground(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.BackgroundColor = color.RGBA{0, 0, 0, 0}
+
+	data, err := q.PNG(-1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// The corner pixel is in the quiet zone.
+	b := img.Bounds()
+	if _, _, _, a := img.At(b.Min.X, b.Min.Y).RGBA(); a != 0 {
+		t.Errorf("quiet zone alpha is %d, expected 0", a)
+	}
+
+	// The top left finder pattern is dark.
+	scale := b.Dx() / len(q.Bitmap())
+	border := q.symbol.quietZoneSize * scale
+	if r, _, _, a := img.At(border, border).RGBA(); a != 0xffff || r != 0 {
+		t.Errorf("dark module is (r=%d, a=%d), expected opaque black", r, a)
+	}
+
+	// Opaque colours still use a paletted image.
+	q.BackgroundColor = color.White
+	if _, ok := q.Image(-1).(*image.Paletted); !ok {
+		t.Errorf("opaque colours did not produce a paletted image")
+	}
+}
+
+func TestQRCodeQuietZone(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	symbolSize := q.version.symbolSize()
+
+	tests := []struct {
+		quietZone     int
+		disableBorder bool
+		expected      int
+	}{
+		{0, false, symbolSize + 2*q.version.quietZoneSize()},
+		{4, false, symbolSize + 8},
+		{2, false, symbolSize + 4},
+		{-1, false, symbolSize},
+		{4, true, symbolSize},
+	}
+
+	for _, test := range tests {
+		q.QuietZone = test.quietZone
+		q.DisableBorder = test.disableBorder
+
+		if got := len(q.Bitmap()); got != test.expected {
+			t.Errorf("QuietZone=%d DisableBorder=%t: got bitmap size %d, expected %d",
+				test.quietZone, test.disableBorder, got, test.expected)
+		}
+
+		if got := strings.Count(q.ToString(false), "\n"); got != test.expected {
+			t.Errorf("QuietZone=%d DisableBorder=%t: got %d ToString lines, expected %d",
+				test.quietZone, test.disableBorder, got, test.expected)
+		}
+	}
+
+	// A quiet zone of 2 is 4 modules narrower than one of 4 on each axis.
+	q.DisableBorder = false
+	q.QuietZone = 4
+	wide := q.Image(-10).Bounds()
+
+	q.QuietZone = 2
+	narrow := q.Image(-10).Bounds()
+
+	if wide.Dx()-narrow.Dx() != 40 || wide.Dy()-narrow.Dy() != 40 {
+		t.Errorf("got image sizes %v and %v, expected a difference of 4 modules",
+			wide.Size(), narrow.Size())
+	}
+
+	data, err := q.PNG(-10)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if img.Bounds().Dx() != (symbolSize+4)*10 {
+		t.Errorf("got PNG width %d, expected %d", img.Bounds().Dx(), (symbolSize+4)*10)
+	}
+}
+
+func TestQRCodeDrawInto(t *testing.T) {
+	a, err := New("first", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b, err := New("second", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b.DisableBorder = true
+	b.ForegroundColor = color.RGBA{0x00, 0x00, 0x80, 0xff}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, 1200, 700))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.RGBA{0xff, 0x00, 0x00, 0xff}}, image.Point{}, draw.Src)
+
+	rectA := image.Rect(10, 20, 10+610, 20+610)
+	rectB := image.Rect(650, 30, 650+420, 30+420)
+	a.DrawInto(canvas, rectA)
+	b.DrawInto(canvas, rectB)
+
+	at := func(x, y int) color.RGBA {
+		return canvas.At(x, y).(color.RGBA)
+	}
+
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+
+	// Outside both rectangles is untouched.
+	for _, p := range []image.Point{{0, 0}, {rectA.Max.X + 5, 10}, {rectB.Max.X + 5, rectB.Max.Y + 5}} {
+		if got := at(p.X, p.Y); got != red {
+			t.Errorf("pixel %v: got %v, expected the canvas colour", p, got)
+		}
+	}
+
+	// The first code has a quiet zone, so its corners are white. The second
+	// has none, so its corners are finder patterns.
+	if got := at(rectA.Min.X, rectA.Min.Y); got != white {
+		t.Errorf("first code top left: got %v, expected white", got)
+	}
+	if got := at(rectA.Max.X-1, rectA.Max.Y-1); got != white {
+		t.Errorf("first code bottom right: got %v, expected white", got)
+	}
+	for _, p := range []image.Point{rectB.Min, {rectB.Max.X - 1, rectB.Min.Y}, {rectB.Min.X, rectB.Max.Y - 1}} {
+		if got := at(p.X, p.Y); got != b.ForegroundColor {
+			t.Errorf("second code corner %v: got %v, expected %v", p, got, b.ForegroundColor)
+		}
+	}
+	if got := at(rectB.Max.X-1, rectB.Max.Y-1); got == red {
+		t.Errorf("second code bottom right corner not drawn")
+	}
+
+	// Each code decodes.
+	for i, test := range []struct {
+		q    *QRCode
+		rect image.Rectangle
+	}{{a, rectA}, {b, rectB}} {
+		// Pad the borderless code with a quiet zone.
+		sub := image.NewRGBA(test.rect.Inset(-40))
+		draw.Draw(sub, sub.Bounds(), &image.Uniform{white}, image.Point{}, draw.Src)
+		draw.Draw(sub, test.rect, canvas, test.rect.Min, draw.Src)
+
+		if s, err := Decode(sub); err != nil || s != test.q.Content {
+			t.Errorf("code %d: decode got %q, %v", i, s, err)
+		}
+	}
+
+	// Translucent backgrounds are composited.
+	c, err := New("third", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	c.BackgroundColor = color.Transparent
+	c.DrawInto(canvas, image.Rect(0, 650, 50, 700))
+	if got := at(0, 650); got != red {
+		t.Errorf("transparent background: got %v, expected the canvas colour", got)
+	}
+}
+
+func TestQRCodeImageGray(t *testing.T) {
+	q, err := New("https://example.org/gray", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Colours are ignored.
+	q.ForegroundColor = color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+	q.BackgroundColor = color.RGBA{R: 0xff, G: 0xf0, B: 0xe0, A: 0xff}
+	q.FinderColor = color.RGBA{R: 0x80, A: 0xff}
+
+	const scale = 10
+	img := q.ImageGray(-scale)
+	bitmap := q.Bitmap()
+
+	if got := img.Bounds().Size(); got != (image.Point{len(bitmap) * scale, len(bitmap) * scale}) {
+		t.Fatalf("got size %v, expected %d modules of %dpx", got, len(bitmap), scale)
+	}
+
+	for my, row := range bitmap {
+		for mx, dark := range row {
+			expected := uint8(0xff)
+			if dark {
+				expected = 0
+			}
+
+			// Every pixel within the module.
+			for y := my * scale; y < (my+1)*scale; y++ {
+				for x := mx * scale; x < (mx+1)*scale; x++ {
+					if got := img.GrayAt(x, y).Y; got != expected {
+						t.Fatalf("module (%d, %d) pixel (%d, %d): got %d, expected %d", mx, my, x, y, got, expected)
+					}
+				}
+			}
+		}
+	}
+
+	if s, err := Decode(img); err != nil || s != q.Content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+}
+
+func TestQRCodeImageExact(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.QuietZone = 4
+
+	for _, modulePixels := range []int{1, 3, 7} {
+		img := q.ImageExact(modulePixels)
+		bitmap := q.Bitmap()
+
+		size := modulePixels * len(bitmap)
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Fatalf("%dpx: got size %v, expected %dx%d", modulePixels, b.Size(), size, size)
+		}
+
+		// Every pixel of each module region has the module's colour.
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				r, _, _, _ := img.At(x, y).RGBA()
+				if dark := r == 0; dark != bitmap[y/modulePixels][x/modulePixels] {
+					t.Fatalf("%dpx: pixel (%d, %d) does not match module (%d, %d)",
+						modulePixels, x, y, x/modulePixels, y/modulePixels)
+				}
+			}
+		}
+	}
+
+	if got := q.ImageExact(0).Bounds().Dx(); got != len(q.Bitmap()) {
+		t.Errorf("0px: got width %d, expected %d", got, len(q.Bitmap()))
+	}
+}
+
+func TestQRCodeStrictSize(t *testing.T) {
+	q, err := NewWithForcedVersion("strict size", 5, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Version 5 is 37 modules, plus the quiet zone.
+	minSize := len(q.Bitmap()) * minModulePixels
+
+	// Small sizes are silently increased by default.
+	if _, err := q.PNG(10); err != nil {
+		t.Errorf("got error %s, expected success without StrictSize", err.Error())
+	}
+
+	q.StrictSize = true
+
+	for _, size := range []int{10, minSize - 1, -1} {
+		_, err := q.PNG(size)
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%dpx", minSize)) {
+			t.Errorf("size %d: got %v, expected error suggesting %dpx", size, err, minSize)
+		}
+	}
+
+	if _, err := q.GIF(10); err == nil {
+		t.Errorf("GIF: got success, expected error")
+	}
+	if _, err := q.JPEG(10, 90); err == nil {
+		t.Errorf("JPEG: got success, expected error")
+	}
+
+	for _, size := range []int{minSize, -minModulePixels} {
+		if _, err := q.PNG(size); err != nil {
+			t.Errorf("size %d: got error %s", size, err.Error())
+		}
+	}
+}
+
+func TestQRCodeStringsDisableBorder(t *testing.T) {
+	q, err := New("text art", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	toStrings := map[string]func(bool) string{
+		"ToString":        q.ToString,
+		"ToSmallString":   q.ToSmallString,
+		"ToUnicodeString": q.ToUnicodeString,
+		"ToBrailleString": q.ToBrailleString,
+	}
+
+	for name, toString := range toStrings {
+		q.DisableBorder = false
+		bordered := strings.Count(toString(false), "\n")
+
+		q.DisableBorder = true
+		borderless := strings.Count(toString(false), "\n")
+
+		if borderless >= bordered {
+			t.Errorf("%s: got %d lines with DisableBorder, expected fewer than %d", name, borderless, bordered)
+		}
+	}
+}
+
+func TestQRCodeToUnicodeString(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, disableBorder := range []bool{false, true} {
+		q.DisableBorder = disableBorder
+		bits := q.Bitmap()
+
+		s := q.ToUnicodeString(false)
+		lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+
+		numToStringLines := strings.Count(q.ToString(false), "\n")
+		if len(lines) != (numToStringLines+1)/2 {
+			t.Errorf("got %d lines, expected half of ToString's %d", len(lines), numToStringLines)
+		}
+
+		for y, line := range lines {
+			runes := []rune(line)
+			if len(runes) != len(bits) {
+				t.Fatalf("line %d: got %d characters, expected %d", y, len(runes), len(bits))
+			}
+
+			for x, r := range runes {
+				// The missing row of an odd sized bitmap is light.
+				top := bits[2*y][x]
+				bottom := false
+				if 2*y+1 < len(bits) {
+					bottom = bits[2*y+1][x]
+				}
+
+				expected := map[[2]bool]rune{
+					{true, true}:   ' ',
+					{true, false}:  '▄',
+					{false, true}:  '▀',
+					{false, false}: '█',
+				}[[2]bool{top, bottom}]
+
+				if r != expected {
+					t.Errorf("DisableBorder=%t (%d, %d): got %q, expected %q",
+						disableBorder, x, y, r, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestQRCodeToBrailleString(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, quietZone := range []int{0, -1, 1, 2, 3} {
+		q.QuietZone = quietZone
+		bits := q.Bitmap()
+
+		for _, inverseColor := range []bool{false, true} {
+			s := q.ToBrailleString(inverseColor)
+			lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+
+			if len(lines) != (len(bits)+3)/4 {
+				t.Fatalf("QuietZone=%d: got %d lines for %d module rows", quietZone, len(lines), len(bits))
+			}
+
+			for y, line := range lines {
+				runes := []rune(line)
+				if len(runes) != (len(bits)+1)/2 {
+					t.Fatalf("QuietZone=%d line %d: got %d characters for %d modules",
+						quietZone, y, len(runes), len(bits))
+				}
+
+				for x, r := range runes {
+					if r < 0x2800 || r > 0x28ff {
+						t.Fatalf("(%d, %d): got %q, expected a Braille pattern", x, y, r)
+					}
+
+					// Dot 1 is the top left module of the cell.
+					if dot := r&0x01 != 0; dot != (bits[4*y][2*x] == inverseColor) {
+						t.Errorf("QuietZone=%d inverse=%t (%d, %d): got dot 1 %t",
+							quietZone, inverseColor, x, y, dot)
+					}
+				}
+			}
+
+			// Padding modules beyond the bitmap are light.
+			if len(bits)%4 == 1 && !inverseColor {
+				last := []rune(lines[len(lines)-1])[0]
+				if last&0x02 == 0 || last&0x04 == 0 || last&0x40 == 0 {
+					t.Errorf("QuietZone=%d: padding rows are not light: %q", quietZone, last)
+				}
+			}
+		}
 	}
 }
 
@@ -167,9 +1766,104 @@ func BenchmarkQRCodeURLSize(b *testing.B) {
 	}
 }
 
+func BenchmarkNew100Digits(b *testing.B) {
+	digits := strings.Repeat("0123456789", 10)
+
+	for n := 0; n < b.N; n++ {
+		New(digits, Medium)
+	}
+}
+
+func BenchmarkNewNumeric100Digits(b *testing.B) {
+	digits := strings.Repeat("0123456789", 10)
+
+	for n := 0; n < b.N; n++ {
+		NewNumeric(digits, Medium)
+	}
+}
+
+func benchmarkMaskSelection(b *testing.B, parallel bool) {
+	defer func(parallel bool) { ParallelMaskSelection = parallel }(ParallelMaskSelection)
+	ParallelMaskSelection = parallel
+
+	// A version 40 symbol.
+	q, err := New(strings.Repeat("0123456789", 700), Low)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		q.Bitmap()
+	}
+}
+
+func BenchmarkMaskSelectionSerial(b *testing.B) {
+	benchmarkMaskSelection(b, false)
+}
+
+func BenchmarkMaskSelectionParallel(b *testing.B) {
+	benchmarkMaskSelection(b, true)
+}
+
+func BenchmarkNewTooLong(b *testing.B) {
+	content := strings.Repeat("a", 10<<20)
+
+	for n := 0; n < b.N; n++ {
+		New(content, Low)
+	}
+}
+
 func BenchmarkQRCodeMaximumSize(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		// 7089 is the maximum encodable number of numeric digits.
 		New(strings.Repeat("0", 7089), Low)
 	}
 }
+
+func TestQRCodeWriteFileFormats(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		filename string
+		magic    string
+	}{
+		{"qr.png", "\x89PNG\r\n\x1a\n"},
+		{"qr.jpg", "\xff\xd8"},
+		{"qr.JPEG", "\xff\xd8"},
+		{"qr.gif", "GIF8"},
+		{"qr.svg", "<?xml"},
+		{"qr.webp", "RIFF"},
+	}
+
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.filename)
+
+		if err := q.WriteFile(256, filename); err != nil {
+			t.Errorf("%s: got error %s", test.filename, err.Error())
+			continue
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Errorf("%s: got error %s", test.filename, err.Error())
+			continue
+		}
+
+		if !strings.HasPrefix(string(data), test.magic) {
+			t.Errorf("%s: got prefix %q, expected %q", test.filename, data[:len(test.magic)], test.magic)
+		}
+	}
+
+	for _, filename := range []string{"qr.bmp", "qr"} {
+		err := q.WriteFile(256, filepath.Join(dir, filename))
+		if err == nil || !strings.Contains(err.Error(), ".svg") {
+			t.Errorf("%s: got %v, expected unsupported extension error", filename, err)
+		}
+	}
+}
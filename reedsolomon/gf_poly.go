@@ -69,6 +69,17 @@ func (e gfPoly) numTerms() int {
 	return len(e.term)
 }
 
+// evaluate returns the value of e at x.
+func (e gfPoly) evaluate(x gfElement) gfElement {
+	result := gfZero
+
+	for i := e.numTerms() - 1; i >= 0; i-- {
+		result = gfAdd(gfMultiply(result, x), e.term[i])
+	}
+
+	return result
+}
+
 // gfPolyMultiply returns a * b.
 func gfPolyMultiply(a, b gfPoly) gfPoly {
 	numATerms := a.numTerms()
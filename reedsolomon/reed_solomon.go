@@ -1,7 +1,8 @@
 // go-qrcode
 // Copyright 2014 Tom Harwood
 
-// Package reedsolomon provides error correction encoding for QR Code 2005.
+// Package reedsolomon provides error correction encoding and decoding for QR
+// Code 2005.
 //
 // QR Code 2005 uses a Reed-Solomon error correcting code to detect and correct
 // errors encountered during decoding.
@@ -11,6 +12,7 @@
 package reedsolomon
 
 import (
+	"errors"
 	"log"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
@@ -53,6 +55,129 @@ func Encode(data *bitset.Bitset, numECBytes int) *bitset.Bitset {
 	return result
 }
 
+// Decode corrects errors in data, a Reed-Solomon code produced by Encode, and
+// returns the original data bytes (without the error correction bytes).
+//
+// numECBytes is the number of error correction bytes at the end of data. Up to
+// numECBytes/2 erroneous bytes are corrected. An error is returned if data
+// contains more errors than can be corrected.
+func Decode(data *bitset.Bitset, numECBytes int) (*bitset.Bitset, error) {
+	numBytes := data.Len() / 8
+	if data.Len()%8 != 0 || numECBytes < 1 || numECBytes >= numBytes {
+		return nil, errors.New("invalid Reed-Solomon code length")
+	}
+
+	// The received codeword r(x). The x^i coefficient is byte numBytes-i-1.
+	received := newGFPolyFromData(data)
+
+	// The codeword is valid if it is divisible by the generator polynomial,
+	// i.e. r(a^i) == 0 for each generator root a^0 ... a^numECBytes-1.
+	syndromes := gfPoly{term: make([]gfElement, numECBytes)}
+	valid := true
+	for i := 0; i < numECBytes; i++ {
+		syndromes.term[i] = received.evaluate(gfExpTable[i])
+		if syndromes.term[i] != gfZero {
+			valid = false
+		}
+	}
+
+	if !valid {
+		errorLocator := rsErrorLocatorPoly(syndromes)
+
+		// The error evaluator is S(x) * errorLocator(x) mod x^numECBytes.
+		errorEvaluator := gfPolyMultiply(syndromes, errorLocator)
+		if errorEvaluator.numTerms() > numECBytes {
+			errorEvaluator.term = errorEvaluator.term[:numECBytes]
+		}
+
+		// The derivative of the error locator. The even degree terms vanish in
+		// GF(2^8).
+		derivative := gfPoly{term: make([]gfElement, errorLocator.numTerms())}
+		for i := 1; i < errorLocator.numTerms(); i += 2 {
+			derivative.term[i-1] = errorLocator.term[i]
+		}
+
+		// Find the error locations by testing every position (Chien search),
+		// and the error values using Forney's algorithm.
+		numErrors := 0
+		for i := 0; i < numBytes; i++ {
+			xInverse := gfExpTable[(255-i%255)%255]
+			if errorLocator.evaluate(xInverse) != gfZero {
+				continue
+			}
+
+			denominator := derivative.evaluate(xInverse)
+			if denominator == gfZero {
+				return nil, errors.New("too many errors to correct")
+			}
+
+			magnitude := gfMultiply(gfExpTable[i%255],
+				gfDivide(errorEvaluator.evaluate(xInverse), denominator))
+
+			received.term[i] = gfAdd(received.term[i], magnitude)
+			numErrors++
+		}
+
+		if numErrors != errorLocator.numTerms()-1 {
+			return nil, errors.New("too many errors to correct")
+		}
+
+		for i := 0; i < numECBytes; i++ {
+			if received.evaluate(gfExpTable[i]) != gfZero {
+				return nil, errors.New("too many errors to correct")
+			}
+		}
+	}
+
+	result := bitset.New()
+	result.AppendBytes(received.data(numBytes)[:numBytes-numECBytes])
+
+	return result, nil
+}
+
+// rsErrorLocatorPoly returns the error locator polynomial for the syndromes of
+// a received codeword, using the Berlekamp-Massey algorithm.
+//
+// The roots of the error locator polynomial are the inverses of a^i, for each
+// erroneous received coefficient x^i.
+func rsErrorLocatorPoly(syndromes gfPoly) gfPoly {
+	locator := gfPoly{term: []gfElement{gfOne}}
+	previous := gfPoly{term: []gfElement{gfOne}}
+
+	numErrors := 0
+	shift := 1
+	previousDiscrepancy := gfOne
+
+	for n := 0; n < syndromes.numTerms(); n++ {
+		discrepancy := syndromes.term[n]
+		for i := 1; i <= numErrors && i < locator.numTerms(); i++ {
+			discrepancy = gfAdd(discrepancy,
+				gfMultiply(locator.term[i], syndromes.term[n-i]))
+		}
+
+		if discrepancy == gfZero {
+			shift++
+			continue
+		}
+
+		correction := gfPolyMultiply(previous,
+			newGFPolyMonomial(gfDivide(discrepancy, previousDiscrepancy), shift))
+
+		if 2*numErrors <= n {
+			previous = locator
+			locator = gfPolyAdd(locator, correction)
+			numErrors = n + 1 - numErrors
+			previousDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			locator = gfPolyAdd(locator, correction)
+			shift++
+		}
+	}
+
+	return locator
+}
+
 // rsGeneratorPoly returns the Reed-Solomon generator polynomial with |degree|.
 //
 // The generator polynomial is calculated as:
@@ -87,3 +87,84 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode(t *testing.T) {
+	var tests = []struct {
+		numECBytes int
+		data       string
+		numErrors  int
+	}{
+		{
+			5,
+			"01000000 00011000 10101100 11000011 00000000",
+			0,
+		},
+		{
+			5,
+			"01000000 00011000 10101100 11000011 00000000",
+			2,
+		},
+		{
+			10,
+			"00010000 00100000 00001100 01010110 01100001 10000000 11101100 00010001 11101100 00010001 11101100 00010001 11101100 00010001 11101100 00010001",
+			5,
+		},
+		{
+			30,
+			"00010000 00100000 00001100 01010110 01100001 10000000 11101100 00010001 11101100 00010001 11101100 00010001 11101100 00010001 11101100 00010001",
+			15,
+		},
+	}
+
+	for _, test := range tests {
+		data := bitset.NewFromBase2String(test.data)
+		encoded := Encode(data, test.numECBytes)
+
+		// Corrupt bytes spread throughout the data and error correction bytes.
+		numBytes := encoded.Len() / 8
+		corrupt := map[int]bool{}
+		for i := 0; i < test.numErrors; i++ {
+			corrupt[i*numBytes/test.numErrors] = true
+		}
+
+		corrupted := bitset.New()
+		for i := 0; i < numBytes; i++ {
+			b := encoded.ByteAt(i * 8)
+			if corrupt[i] {
+				b ^= 0xa5
+			}
+			corrupted.AppendByte(b, 8)
+		}
+
+		result, err := Decode(corrupted, test.numECBytes)
+		if err != nil {
+			t.Errorf("data=%s, numECBytes=%d, numErrors=%d, got error %s",
+				data.String(), test.numECBytes, test.numErrors, err.Error())
+			continue
+		}
+
+		if !data.Equals(result) {
+			t.Errorf("data=%s, numECBytes=%d, numErrors=%d, decoded=%s",
+				data.String(), test.numECBytes, test.numErrors, result.String())
+		}
+	}
+}
+
+func TestDecodeTooManyErrors(t *testing.T) {
+	data := bitset.NewFromBase2String("01000000 00011000 10101100 11000011 00000000")
+	encoded := Encode(data, 5)
+
+	// Corrupt 4 of the 10 bytes, 2 more than can be corrected.
+	corrupted := bitset.New()
+	for i := 0; i < encoded.Len()/8; i++ {
+		b := encoded.ByteAt(i * 8)
+		if i < 4 {
+			b = ^b
+		}
+		corrupted.AppendByte(b, 8)
+	}
+
+	if result, err := Decode(corrupted, 5); err == nil {
+		t.Errorf("got %s, expected an error", result.String())
+	}
+}
@@ -105,12 +105,7 @@ var (
 )
 
 func buildRegularSymbol(version qrCodeVersion, mask int,
-	data *bitset.Bitset, includeQuietZone bool) (*symbol, error) {
-
-	quietZoneSize := 0
-	if includeQuietZone {
-		quietZoneSize = version.quietZoneSize()
-	}
+	data *bitset.Bitset, quietZoneSize int) (*symbol, error) {
 
 	m := &regularSymbol{
 		version: version,
@@ -242,37 +237,60 @@ const (
 )
 
 func (m *regularSymbol) addData() (bool, error) {
+	i := 0
+	walkDataModules(m.symbol, m.data.Len(), true, func(x int, y int) {
+		// != is equivalent to XOR.
+		m.symbol.set(x, y, dataMask(m.mask, x, y) != m.data.At(i))
+		i++
+	})
+
+	return true, nil
+}
+
+// dataMask returns true if the data mask pattern mask inverts the module at
+// (x, y).
+func dataMask(mask int, x int, y int) bool {
+	switch mask {
+	case 0:
+		return (y+x)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (y+x)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return (y*x)%2+(y*x)%3 == 0
+	case 6:
+		return ((y*x)%2+((y*x)%3))%2 == 0
+	case 7:
+		return ((y+x)%2+((y*x)%3))%2 == 0
+	}
+
+	return false
+}
+
+// walkDataModules calls fn with the coordinates of the first n modules in the
+// data region of s, in the order data bits are placed.
+//
+// Data is placed in two module wide columns, starting from the bottom right
+// corner and zig-zagging upwards and downwards. Modules already in use by
+// function patterns are skipped. If skipTimingColumn is set, the column
+// containing the vertical timing pattern (x=6) is skipped entirely; Micro QR
+// Codes have their timing pattern at x=0 instead.
+func walkDataModules(s *symbol, n int, skipTimingColumn bool, fn func(x int, y int)) {
 	xOffset := 1
 	dir := up
 
-	x := m.size - 2
-	y := m.size - 1
-
-	for i := 0; i < m.data.Len(); i++ {
-		var mask bool
-		switch m.mask {
-		case 0:
-			mask = (y+x+xOffset)%2 == 0
-		case 1:
-			mask = y%2 == 0
-		case 2:
-			mask = (x+xOffset)%3 == 0
-		case 3:
-			mask = (y+x+xOffset)%3 == 0
-		case 4:
-			mask = (y/2+(x+xOffset)/3)%2 == 0
-		case 5:
-			mask = (y*(x+xOffset))%2+(y*(x+xOffset))%3 == 0
-		case 6:
-			mask = ((y*(x+xOffset))%2+((y*(x+xOffset))%3))%2 == 0
-		case 7:
-			mask = ((y+x+xOffset)%2+((y*(x+xOffset))%3))%2 == 0
-		}
+	x := s.symbolSize - 2
+	y := s.symbolSize - 1
 
-		// != is equivalent to XOR.
-		m.symbol.set(x+xOffset, y, mask != m.data.At(i))
+	for i := 0; i < n; i++ {
+		fn(x+xOffset, y)
 
-		if i == m.data.Len()-1 {
+		if i == n-1 {
 			break
 		}
 
@@ -291,7 +309,7 @@ func (m *regularSymbol) addData() (bool, error) {
 						x -= 2
 					}
 				} else {
-					if y < m.size-1 {
+					if y < s.symbolSize-1 {
 						y++
 					} else {
 						dir = up
@@ -301,15 +319,13 @@ func (m *regularSymbol) addData() (bool, error) {
 			}
 
 			// Skip over the vertical timing pattern entirely.
-			if x == 5 {
+			if skipTimingColumn && x == 5 {
 				x--
 			}
 
-			if m.symbol.empty(x+xOffset, y) {
+			if s.empty(x+xOffset, y) {
 				break
 			}
 		}
 	}
-
-	return true, nil
 }
@@ -0,0 +1,141 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SegmentMode is the data mode a Segment is encoded in.
+type SegmentMode int
+
+const (
+	// Digits 0-9, at 3 1/3 bits per character.
+	Numeric SegmentMode = iota
+
+	// Digits 0-9, upper case letters A-Z, and the characters " $%*+-./:", at
+	// 5 1/2 bits per character.
+	Alphanumeric
+
+	// Any bytes, at 8 bits per byte.
+	Byte
+
+	// Shift JIS double byte characters, at 13 bits per character.
+	Kanji
+)
+
+// Segment is a run of data encoded in a single data mode, see
+// NewFromSegments().
+type Segment struct {
+	Mode SegmentMode
+	Data []byte
+
+	// Optional ECI (Extended Channel Interpretation) header, written
+	// immediately before a Byte mode segment, see NewWithECI(). The ECI
+	// assignment number states the character set of this segment and the
+	// following segments, until the next ECI header, e.g. 3 for ISO-8859-1 or
+	// 26 for UTF-8.
+	ECI    uint32
+	HasECI bool
+}
+
+// NewFromSegments constructs a QRCode of data segments, each encoded in its
+// own data mode.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewFromSegments([]qrcode.Segment{
+//		{Mode: qrcode.Numeric, Data: []byte("0123456789")},
+//		{Mode: qrcode.Byte, Data: []byte("https://example.org/order")},
+//	}, qrcode.Medium)
+//
+// The segments are encoded in order, as given, skipping the data mode analysis
+// of New. This gives full control of the encoding, e.g. to mix a numeric order
+// number and a byte mode URL. The Content field holds the concatenated segment
+// data.
+//
+// Byte mode segments may each carry an ECI header, so a single QR Code can mix
+// character sets, e.g. an ISO-8859-1 segment followed by a UTF-8 segment.
+//
+// An error occurs if segs is empty, a segment is empty or its data is invalid
+// for its mode, a segment other than a Byte mode segment has an ECI header, an
+// ECI assignment number is larger than 999999, or the data is too long.
+func NewFromSegments(segs []Segment, level RecoveryLevel) (*QRCode, error) {
+	if len(segs) == 0 {
+		return nil, errors.New("no segments to encode")
+	}
+
+	segments := make([]segment, len(segs))
+	var content strings.Builder
+
+	for i, s := range segs {
+		mode, err := s.dataMode()
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		if s.HasECI && mode != dataModeByte {
+			return nil, fmt.Errorf("segment %d: ECI header on a %s mode segment (expected byte mode)",
+				i, dataModeString(mode))
+		}
+
+		segments[i] = segment{
+			dataMode: mode,
+			data:     append([]byte{}, s.Data...),
+			eci:      s.ECI,
+			hasECI:   s.HasECI,
+		}
+		content.Write(s.Data)
+	}
+
+	return newWithOptions(content.String(), level, encodeOptions{segments: segments})
+}
+
+// dataMode returns the data mode of the segment, or an error if the segment is
+// empty or its data is invalid for its mode.
+func (s Segment) dataMode() (dataMode, error) {
+	if len(s.Data) == 0 {
+		return 0, errors.New("no data to encode")
+	}
+
+	var mode dataMode
+	var valid func(i int) bool
+
+	switch s.Mode {
+	case Numeric:
+		mode = dataModeNumeric
+		valid = func(i int) bool { return isNumericCharacter(s.Data[i]) }
+	case Alphanumeric:
+		mode = dataModeAlphanumeric
+		// isAlphanumericCharacter() excludes the digits.
+		valid = func(i int) bool { return isNumericCharacter(s.Data[i]) || isAlphanumericCharacter(s.Data[i]) }
+	case Byte:
+		return dataModeByte, nil
+	case Kanji:
+		if len(s.Data)%2 != 0 {
+			return 0, fmt.Errorf("odd length %d for Kanji mode (expected double byte characters)", len(s.Data))
+		}
+
+		for i := 0; i < len(s.Data); i += 2 {
+			if !isKanjiCharacter(s.Data[i], s.Data[i+1]) {
+				return 0, fmt.Errorf("invalid character %#x%02x at position %d for Kanji mode",
+					s.Data[i], s.Data[i+1], i)
+			}
+		}
+
+		return dataModeKanji, nil
+	default:
+		return 0, fmt.Errorf("invalid segment mode %d", s.Mode)
+	}
+
+	for i := range s.Data {
+		if !valid(i) {
+			return 0, fmt.Errorf("invalid character %q at position %d for %s mode",
+				s.Data[i], i, dataModeString(mode))
+		}
+	}
+
+	return mode, nil
+}
@@ -0,0 +1,171 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"strings"
+	"testing"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+)
+
+func TestNewFromSegments(t *testing.T) {
+	orderID := strings.Repeat("0123456789", 6)
+	url := "https://example.org/order?id="
+
+	segs := []Segment{
+		{Mode: Byte, Data: []byte(url)},
+		{Mode: Numeric, Data: []byte(orderID)},
+	}
+
+	q, err := NewFromSegments(segs, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != url+orderID {
+		t.Errorf("got content %q, expected %q", q.Content, url+orderID)
+	}
+
+	// Exactly the given segments are encoded.
+	if len(q.encoder.optimised) != 2 || q.encoder.optimised[0].dataMode != dataModeByte ||
+		q.encoder.optimised[1].dataMode != dataModeNumeric {
+		t.Errorf("got segments %v, expected byte then numeric", q.encoder.optimised)
+	}
+
+	// The numeric segment is denser than the byte mode.
+	b, err := NewBytes([]byte(url+orderID), Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Version() >= b.Version() {
+		t.Errorf("got version %d, expected less than byte mode version %d", q.Version(), b.Version())
+	}
+
+	if s, err := Decode(q.Image(-3)); err != nil || s != q.Content {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+
+	// The input may be reused.
+	segs[1].Data[0] = 'x'
+	if s, err := Decode(q.Image(-3)); err != nil || s != url+orderID {
+		t.Errorf("decode after modifying input: got %q, %v", s, err)
+	}
+
+	// The segments are kept at other levels.
+	r, err := q.WithLevel(Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(r.encoder.optimised) != 2 || r.encoder.optimised[1].dataMode != dataModeNumeric {
+		t.Errorf("WithLevel: got segments %v, expected byte then numeric", r.encoder.optimised)
+	}
+}
+
+func TestNewFromSegmentsAlphanumericDigits(t *testing.T) {
+	content := "ABC123 $%*+-./:"
+
+	q, err := NewFromSegments([]Segment{{Mode: Alphanumeric, Data: []byte(content)}}, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(q.encoder.optimised) != 1 || q.encoder.optimised[0].dataMode != dataModeAlphanumeric {
+		t.Errorf("got segments %v, expected a single alphanumeric segment", q.encoder.optimised)
+	}
+
+	if s, err := Decode(q.Image(-3)); err != nil || s != content {
+		t.Errorf("decode: got %q, %v, expected %q", s, err, content)
+	}
+}
+
+func TestNewFromSegmentsKanji(t *testing.T) {
+	// "茗荷" in Shift JIS.
+	kanji := []byte{0xe4, 0xaa, 0x89, 0xd7}
+
+	q, err := NewFromSegments([]Segment{
+		{Mode: Alphanumeric, Data: []byte("ITEM ")},
+		{Mode: Kanji, Data: kanji},
+	}, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != "ITEM "+string(kanji) {
+		t.Errorf("got content %q", q.Content)
+	}
+
+	if q.encoder.optimised[1].dataMode != dataModeKanji {
+		t.Errorf("got segment mode %s, expected kanji", dataModeString(q.encoder.optimised[1].dataMode))
+	}
+}
+
+func TestNewFromSegmentsECI(t *testing.T) {
+	latin1 := []byte("Gr\xfc\xdfe")
+	utf8 := []byte("Grüße")
+
+	q, err := NewFromSegments([]Segment{
+		{Mode: Byte, Data: latin1, ECI: 3, HasECI: true},
+		{Mode: Byte, Data: utf8, ECI: 26, HasECI: true},
+	}, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Each ECI header directly precedes its byte segment (versions 1-9 have
+	// 8-bit byte mode character counts).
+	expected := bitset.New()
+	for _, s := range []struct {
+		eci  uint32
+		data []byte
+	}{
+		{3, latin1},
+		{26, utf8},
+	} {
+		expected.AppendUint32(0x7, 4)
+		expected.AppendUint32(s.eci, 8)
+		expected.AppendUint32(0x4, 4)
+		expected.AppendUint32(uint32(len(s.data)), 8)
+		expected.AppendBytes(s.data)
+	}
+
+	if !q.data.Equals(expected) {
+		t.Errorf("got data\n%s\nexpected\n%s", q.data.String(), expected.String())
+	}
+
+	// The encoded length includes both headers.
+	if numBits, err := q.encoder.encodedBits([]byte(q.Content)); err != nil || numBits != expected.Len() {
+		t.Errorf("encodedBits: got %d, %v, expected %d", numBits, err, expected.Len())
+	}
+
+	if s, err := Decode(q.Image(-3)); err != nil || s != string(latin1)+string(utf8) {
+		t.Errorf("decode: got %q, %v", s, err)
+	}
+}
+
+func TestNewFromSegmentsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		segs []Segment
+	}{
+		{"no segments", nil},
+		{"empty segment", []Segment{{Mode: Byte}}},
+		{"letter in numeric", []Segment{{Mode: Numeric, Data: []byte("12a")}}},
+		{"lower case in alphanumeric", []Segment{{Mode: Alphanumeric, Data: []byte("abc")}}},
+		{"odd length kanji", []Segment{{Mode: Kanji, Data: []byte{0xe4, 0xaa, 0x89}}}},
+		{"invalid kanji", []Segment{{Mode: Kanji, Data: []byte("ab")}}},
+		{"invalid mode", []Segment{{Mode: SegmentMode(9), Data: []byte("1")}}},
+		{"too long", []Segment{{Mode: Byte, Data: make([]byte, 3000)}}},
+		{"ECI on numeric", []Segment{{Mode: Numeric, Data: []byte("1"), ECI: 26, HasECI: true}}},
+		{"invalid ECI", []Segment{{Mode: Byte, Data: []byte("a"), ECI: 1000000, HasECI: true}}},
+	}
+
+	for _, test := range tests {
+		if _, err := NewFromSegments(test.segs, Medium); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import "math"
+
+// A ModuleShape is the shape used to draw the dark modules of a QR Code image.
+// ModuleShape only affects rendering, not the encoded QR Code.
+type ModuleShape int
+
+const (
+	// Square modules, which join seamlessly.
+	Square ModuleShape = iota
+
+	// Rounded modules: squares with rounded corners, see QRCode.CornerRadius.
+	Rounded
+
+	// Circle modules: dots inscribed within each module.
+	Circle
+)
+
+// defaultCornerRadius is the corner radius of Rounded modules, as a fraction
+// of the module size, if QRCode.CornerRadius is zero.
+const defaultCornerRadius = 0.25
+
+// maxModuleGap is the largest QRCode.ModuleGap, as a fraction of the module
+// size.
+const maxModuleGap = 0.3
+
+// inModuleShape returns true if the point (fx, fy) is inside the shape of the
+// dark module at bitmap position (x, y). fx and fy are measured in modules from
+// the module's top left corner, so both are 0-1 inside the module.
+func (q *QRCode) inModuleShape(x int, y int, fx float64, fy float64) bool {
+	if q.SquareFinders && q.isFinderModule(x, y) {
+		return true
+	}
+
+	// Shrink the module by the gap, and measure within the remainder.
+	if gap := math.Min(q.ModuleGap, maxModuleGap); gap > 0 {
+		inset := gap / 2
+		if fx < inset || fx > 1-inset || fy < inset || fy > 1-inset {
+			return false
+		}
+
+		fx = (fx - inset) / (1 - gap)
+		fy = (fy - inset) / (1 - gap)
+	}
+
+	switch q.ModuleShape {
+	case Rounded:
+		r := q.CornerRadius
+		if r <= 0 {
+			r = defaultCornerRadius
+		}
+		r = math.Min(r, 0.5)
+
+		// Distance into the corner region, zero along the straight edges.
+		dx := math.Max(0, math.Abs(fx-0.5)-(0.5-r))
+		dy := math.Max(0, math.Abs(fy-0.5)-(0.5-r))
+
+		return dx*dx+dy*dy <= r*r
+	case Circle:
+		dx := fx - 0.5
+		dy := fy - 0.5
+
+		return dx*dx+dy*dy <= 0.25
+	}
+
+	return true
+}
+
+// isFinderModule returns true if the module at bitmap position (x, y) is part
+// of a finder pattern.
+func (q *QRCode) isFinderModule(x int, y int) bool {
+	border := q.symbol.quietZoneSize
+	size := q.symbol.symbolSize
+
+	x -= border
+	y -= border
+
+	inFinder := func(x0 int, y0 int) bool {
+		return x >= x0 && x < x0+finderPatternSize && y >= y0 && y < y0+finderPatternSize
+	}
+
+	if q.micro != nil {
+		return inFinder(0, 0)
+	}
+
+	far := size - finderPatternSize
+
+	return inFinder(0, 0) || inFinder(far, 0) || inFinder(0, far)
+}
@@ -0,0 +1,185 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"testing"
+)
+
+func TestModuleShape(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const scale = 20
+	n := len(q.Bitmap())
+	border := q.symbol.quietZoneSize * scale
+
+	isDark := func(img image.Image, x int, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r < 0x8000
+	}
+
+	tests := []struct {
+		shape         ModuleShape
+		squareFinders bool
+		cornerDark    bool
+		finderDark    bool
+	}{
+		{Square, false, true, true},
+		{Rounded, false, false, false},
+		{Circle, false, false, false},
+		{Circle, true, false, true},
+	}
+
+	for _, test := range tests {
+		q.ModuleShape = test.shape
+		q.SquareFinders = test.squareFinders
+
+		img := q.Image(-scale)
+
+		if b := img.Bounds(); b.Dx() != n*scale || b.Dy() != n*scale {
+			t.Errorf("shape %d: got size %v, expected %dx%d", test.shape, b.Size(), n*scale, n*scale)
+		}
+
+		// The top left pixel of the top left finder pattern.
+		if got := isDark(img, border, border); got != test.finderDark {
+			t.Errorf("shape %d, SquareFinders=%t: finder corner dark=%t, expected %t",
+				test.shape, test.squareFinders, got, test.finderDark)
+		}
+
+		// A dark data module: the bottom right corner pixel varies with the
+		// shape, but the centre is always dark.
+		bitmap := q.Bitmap()
+		found := false
+		for y := n / 2; y < n && !found; y++ {
+			for x := n / 2; x < n && !found; x++ {
+				if !bitmap[y][x] || q.isFinderModule(x, y) {
+					continue
+				}
+				found = true
+
+				if !isDark(img, x*scale+scale/2, y*scale+scale/2) {
+					t.Errorf("shape %d: module centre is light", test.shape)
+				}
+
+				if got := isDark(img, x*scale+scale-1, y*scale+scale-1); got != test.cornerDark {
+					t.Errorf("shape %d: module corner dark=%t, expected %t", test.shape, got, test.cornerDark)
+				}
+			}
+		}
+
+		// Decode() requires square finder patterns.
+		if !test.finderDark {
+			continue
+		}
+
+		s, err := Decode(img)
+		if err != nil || s != q.Content {
+			t.Errorf("shape %d: decoded %q, %v", test.shape, s, err)
+		}
+	}
+}
+
+func TestModuleShapeCornerRadius(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.Bitmap()
+
+	q.ModuleShape = Rounded
+
+	// A tiny corner radius leaves the module near square.
+	q.CornerRadius = 0.05
+	if !q.inModuleShape(0, 0, 0.1, 0.1) {
+		t.Errorf("radius 0.05: point (0.1, 0.1) outside module")
+	}
+
+	// A maximal radius is a circle.
+	q.CornerRadius = 0.5
+	if q.inModuleShape(0, 0, 0.1, 0.1) {
+		t.Errorf("radius 0.5: point (0.1, 0.1) inside module")
+	}
+	if !q.inModuleShape(0, 0, 0.5, 0.01) {
+		t.Errorf("radius 0.5: point (0.5, 0.01) outside module")
+	}
+}
+
+func TestModuleGap(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const scale = 20
+	bitmap := q.Bitmap()
+	n := len(bitmap)
+
+	// A dark data module, and the top left module of the first finder pattern.
+	var data image.Point
+	for y := n / 2; y < n && data == (image.Point{}); y++ {
+		for x := n / 2; x < n; x++ {
+			if bitmap[y][x] && !q.isFinderModule(x, y) {
+				data = image.Point{x, y}
+				break
+			}
+		}
+	}
+	finder := image.Point{q.symbol.quietZoneSize, q.symbol.quietZoneSize}
+
+	// darkArea returns the number of dark pixels drawn in the module m.
+	darkArea := func(img image.Image, m image.Point) int {
+		area := 0
+		for y := m.Y * scale; y < (m.Y+1)*scale; y++ {
+			for x := m.X * scale; x < (m.X+1)*scale; x++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+					area++
+				}
+			}
+		}
+		return area
+	}
+
+	tests := []struct {
+		gap           float64
+		squareFinders bool
+		dataArea      int
+		finderArea    int
+	}{
+		{0, false, scale * scale, scale * scale},
+		{0.2, false, 16 * 16, 16 * 16},
+		{0.2, true, 16 * 16, scale * scale},
+		{0.9, false, 14 * 14, 14 * 14}, // Clamped to 0.3.
+	}
+
+	for _, test := range tests {
+		q.ModuleGap = test.gap
+		q.SquareFinders = test.squareFinders
+
+		img := q.Image(-scale)
+
+		if got := darkArea(img, data); got != test.dataArea {
+			t.Errorf("gap %.1f: data module area %d, expected %d", test.gap, got, test.dataArea)
+		}
+		if got := darkArea(img, finder); got != test.finderArea {
+			t.Errorf("gap %.1f, SquareFinders=%t: finder module area %d, expected %d",
+				test.gap, test.squareFinders, got, test.finderArea)
+		}
+
+		// The centre of the module is always dark.
+		if r, _, _, _ := img.At(data.X*scale+scale/2, data.Y*scale+scale/2).RGBA(); r >= 0x8000 {
+			t.Errorf("gap %.1f: module centre is light", test.gap)
+		}
+
+		// Decode requires solid finder patterns.
+		if test.gap == 0 || test.squareFinders {
+			if s, err := Decode(img); err != nil || s != q.Content {
+				t.Errorf("gap %.1f: decode got %q, %v", test.gap, s, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// eciShiftJIS is the ECI assignment number of the Shift JIS character set.
+const eciShiftJIS = 20
+
+var (
+	shiftJISEncodeOnce sync.Once
+
+	// Shift JIS double byte code of each JIS X 0208 character, built from
+	// jisX0208 on first use.
+	shiftJISEncode map[rune]uint16
+)
+
+// NewShiftJIS constructs a QRCode of the content transcoded from UTF-8 to
+// Shift JIS, with an ECI header (assignment number 20) identifying the
+// character set.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewShiftJIS("茗荷", qrcode.Medium)
+//
+// This suits legacy Japanese readers which expect Shift JIS text in the byte
+// data mode, rather than UTF-8. Characters are encoded as their Shift JIS
+// bytes: Kanji mode is not used. The Content field holds the Shift JIS bytes as
+// a string.
+//
+// ASCII, half width katakana, and the JIS X 0208 characters are supported. An
+// error occurs if the content contains any other character, is not valid
+// UTF-8, or is too long.
+func NewShiftJIS(content string, level RecoveryLevel) (*QRCode, error) {
+	data, err := encodeShiftJIS(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithECI(string(data), level, eciShiftJIS)
+}
+
+// encodeShiftJIS returns the UTF-8 string s transcoded to Shift JIS.
+func encodeShiftJIS(s string) ([]byte, error) {
+	shiftJISEncodeOnce.Do(func() {
+		shiftJISEncode = make(map[rune]uint16, 6879)
+
+		for i, r := range jisX0208 {
+			if r == 0 {
+				continue
+			}
+
+			row := i/94 + 1
+			cell := i%94 + 1
+
+			// JIS X 0208 row and cell to the Shift JIS lead and trail bytes.
+			lead := (row+1)/2 + 0x80
+			if row > 62 {
+				lead += 0x40
+			}
+
+			trail := cell + 0x9e
+			if row%2 == 1 {
+				trail = cell + 0x3f
+				if cell >= 64 {
+					trail++
+				}
+			}
+
+			shiftJISEncode[rune(r)] = uint16(lead<<8 | trail)
+		}
+	})
+
+	data := make([]byte, 0, len(s))
+
+	for i, r := range s {
+		if _, size := utf8.DecodeRuneInString(s[i:]); r == utf8.RuneError && size == 1 {
+			return nil, fmt.Errorf("invalid UTF-8 at position %d", i)
+		}
+
+		switch {
+		case r < 0x80:
+			data = append(data, byte(r))
+		case r >= 0xff61 && r <= 0xff9f:
+			// Half width katakana.
+			data = append(data, byte(r-0xff61+0xa1))
+		default:
+			c, ok := shiftJISEncode[r]
+			if !ok {
+				return nil, fmt.Errorf("character %q at position %d not representable in Shift JIS", r, i)
+			}
+
+			data = append(data, byte(c>>8), byte(c))
+		}
+	}
+
+	return data, nil
+}
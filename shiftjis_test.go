@@ -0,0 +1,68 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"testing"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+)
+
+func TestNewShiftJIS(t *testing.T) {
+	// "茗荷" is 0xe4aa 0x89d7 in Shift JIS.
+	q, err := NewShiftJIS("茗荷", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != "\xe4\xaa\x89\xd7" {
+		t.Errorf("got content %x, expected e4aa89d7", q.Content)
+	}
+
+	// ECI 20, then the four bytes in byte mode.
+	expected := bitset.NewFromBase2String("0111 00010100 0100 00000100 11100100 10101010 10001001 11010111")
+	if !expected.Equals(q.data) {
+		t.Errorf("got %s, expected %s", q.data.String(), expected.String())
+	}
+
+	// The encoding is kept at other levels.
+	r, err := q.WithLevel(High)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !r.encoder.hasECI || r.encoder.eci != eciShiftJIS || r.Content != q.Content {
+		t.Errorf("WithLevel: got ECI %d (%t), content %x", r.encoder.eci, r.encoder.hasECI, r.Content)
+	}
+}
+
+func TestEncodeShiftJIS(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []byte
+	}{
+		{"abc~\\", []byte("abc~\\")},
+		{"A1 ｱ漢字、", []byte{0x41, 0x31, 0x20, 0xb1, 0x8a, 0xbf, 0x8e, 0x9a, 0x81, 0x41}},
+		{"ア", []byte{0x83, 0x41}},
+		{"熙", []byte{0xea, 0xa4}},
+	}
+
+	for _, test := range tests {
+		data, err := encodeShiftJIS(test.content)
+		if err != nil {
+			t.Errorf("%q: %s", test.content, err.Error())
+			continue
+		}
+
+		if !bytes.Equal(data, test.expected) {
+			t.Errorf("%q: got %x, expected %x", test.content, data, test.expected)
+		}
+	}
+
+	for _, content := range []string{"\U0001f600", "Grüße", "\xff"} {
+		if _, err := NewShiftJIS(content, Medium); err == nil {
+			t.Errorf("%q: got success, expected error", content)
+		}
+	}
+}
@@ -3,58 +3,313 @@
 
 package qrcode
 
-import "unicode/utf8"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
 // SplitContent splits content into chunks by byte boundary, each fitting in a
 // single QR code at the given recovery level.
+//
+// Each chunk is the longest that fits, so the number of chunks is minimal.
 func SplitContent(content string, level RecoveryLevel) []string {
-	cap := MaxByteCapacity(level)
-	if cap <= 0 {
-		return nil
+	return splitContent(content, fitsAt(level, ""), maxEncodableByteLength)
+}
+
+// SplitContentUTF8 splits content into chunks at rune boundaries, each fitting
+// in a single QR code at the given recovery level. This avoids splitting
+// multi-byte UTF-8 characters.
+//
+// Each chunk is the longest that fits, so the number of chunks is minimal. The
+// encoded length of a chunk depends on its content, e.g. chunks of digits hold
+// more characters than chunks of lower case letters.
+func SplitContentUTF8(content string, level RecoveryLevel) []string {
+	return splitContent(content, fitsAt(level, ""), maxEncodablePrefix)
+}
+
+// SplitBytes splits binary data into chunks, each fitting in a single QR code
+// constructed by NewBytes() at the given recovery level.
+//
+// Unlike SplitContentUTF8, no attention is paid to rune boundaries, so any
+// data may be split, including NUL bytes and invalid UTF-8. Each chunk is the
+// longest that fits, so the number of chunks is minimal. The chunks share data's
+// underlying array.
+func SplitBytes(data []byte, level RecoveryLevel) [][]byte {
+	fits := func(chunk string) bool {
+		_, err := NewBytes([]byte(chunk), level)
+		return err == nil
 	}
-	// Reduce capacity by 10 bytes as safety margin
-	cap -= 50
-	if cap <= 0 {
-		return nil
+
+	var chunks [][]byte
+	start := 0
+	for _, chunk := range splitContent(string(data), fits, maxEncodableByteLength) {
+		chunks = append(chunks, data[start:start+len(chunk):start+len(chunk)])
+		start += len(chunk)
 	}
 
-	data := []byte(content)
+	return chunks
+}
+
+// SplitContentIndexed splits content into chunks at rune boundaries, as
+// SplitContentUTF8, with each chunk prefixed by a header stating its position
+// within the sequence, e.g. "[2/5]". The headers allow chunks scanned out of
+// order to be reassembled, see JoinIndexed().
+//
+// Each chunk, including its header, fits in a single QR code at the given
+// recovery level.
+func SplitContentIndexed(content string, level RecoveryLevel) []string {
+	// The header length depends on the number of chunks, which in turn depends
+	// on the header length. Headers are sized for the largest index.
+	n := len(SplitContentUTF8(content, level))
+
 	var chunks []string
-	for len(data) > 0 {
-		end := cap
-		if end > len(data) {
-			end = len(data)
+	for {
+		chunks = splitContent(content, fitsAt(level, indexHeader(n, n)), maxEncodablePrefix)
+
+		if len(chunks) <= n {
+			break
 		}
-		chunks = append(chunks, string(data[:end]))
-		data = data[end:]
+		n = len(chunks)
 	}
+
+	for i := range chunks {
+		chunks[i] = indexHeader(i+1, len(chunks)) + chunks[i]
+	}
+
 	return chunks
 }
 
-// SplitContentUTF8 splits content into chunks at rune boundaries, each fitting
-// in a single QR code at the given recovery level. This avoids splitting
-// multi-byte UTF-8 characters.
-func SplitContentUTF8(content string, level RecoveryLevel) []string {
-	cap := MaxByteCapacity(level)
-	if cap <= 0 {
-		return nil
+// indexHeader returns the SplitContentIndexed header of chunk i of n.
+func indexHeader(i int, n int) string {
+	return fmt.Sprintf("[%d/%d]", i, n)
+}
+
+// JoinIndexed reassembles the chunks returned by SplitContentIndexed(), which
+// may be in any order.
+//
+// An error occurs if a chunk has no valid header, the chunks disagree on the
+// number of chunks, or a chunk is missing or duplicated.
+func JoinIndexed(chunks []string) (string, error) {
+	if len(chunks) == 0 {
+		return "", errors.New("no chunks to join")
+	}
+
+	ordered := make([]string, len(chunks))
+	found := make([]bool, len(chunks))
+
+	for _, chunk := range chunks {
+		i, n, body, err := parseIndexHeader(chunk)
+		if err != nil {
+			return "", err
+		}
+
+		if n != len(chunks) {
+			return "", fmt.Errorf("chunk [%d/%d]: got %d chunks, expected %d", i, n, len(chunks), n)
+		}
+
+		if found[i-1] {
+			return "", fmt.Errorf("duplicate chunk [%d/%d]", i, n)
+		}
+
+		ordered[i-1] = body
+		found[i-1] = true
+	}
+
+	// With no duplicates, and as many chunks as stated, none are missing.
+	return strings.Join(ordered, ""), nil
+}
+
+// parseIndexHeader parses the SplitContentIndexed header of chunk, returning
+// the chunk index i of n and the chunk without its header.
+func parseIndexHeader(chunk string) (i int, n int, body string, err error) {
+	end := strings.IndexByte(chunk, ']')
+	slash := strings.IndexByte(chunk, '/')
+
+	if !strings.HasPrefix(chunk, "[") || end < 0 || slash < 0 || slash > end {
+		return 0, 0, "", fmt.Errorf("chunk %q has no [i/n] header", truncate(chunk, 16))
 	}
-	// Reduce capacity by 10 bytes as safety margin to avoid encoding edge cases
-	cap -= 50
-	if cap <= 0 {
-		return nil
+
+	i, err1 := strconv.Atoi(chunk[1:slash])
+	n, err2 := strconv.Atoi(chunk[slash+1 : end])
+
+	if err1 != nil || err2 != nil || i < 1 || i > n {
+		return 0, 0, "", fmt.Errorf("chunk %q has an invalid [i/n] header", truncate(chunk, 16))
 	}
 
+	return i, n, chunk[end+1:], nil
+}
+
+// SplitContentOverlap splits content into chunks at rune boundaries, as
+// SplitContentIndexed, with each chunk after the first repeating the last
+// overlap runes of the previous chunk at its start.
+//
+// Each chunk is prefixed by a header stating its position within the sequence
+// and the number of repeated runes, e.g. "[2/5+8]". The repeated runes help a
+// person piece together a sequence with a chunk which fails to scan. Use
+// JoinOverlap() to reassemble the chunks, removing the repetition.
+//
+// Each chunk, including its header, fits in a single QR code at the given
+// recovery level. A negative overlap is treated as zero. Nil is returned if
+// the overlap leaves no room for further content in a chunk.
+func SplitContentOverlap(content string, level RecoveryLevel, overlap int) []string {
+	overlap = max(0, overlap)
+
+	// Headers are sized for the largest index, as in SplitContentIndexed.
+	n := len(SplitContentUTF8(content, level))
+
 	var chunks []string
-	for len(content) > 0 {
-		end := cap
-		if end > len(content) {
-			end = len(content)
+	var repeated []int
+	for {
+		var ok bool
+		chunks, repeated, ok = splitContentOverlap(content, level, overlap, overlapHeader(n, n, overlap))
+		if !ok {
+			return nil
+		}
+
+		if len(chunks) <= n {
+			break
+		}
+		n = len(chunks)
+	}
+
+	for i := range chunks {
+		chunks[i] = overlapHeader(i+1, len(chunks), repeated[i]) + chunks[i]
+	}
+
+	return chunks
+}
+
+// splitContentOverlap splits content into chunks for SplitContentOverlap(),
+// each fitting with header. The number of runes each chunk repeats from the
+// previous chunk is returned in repeated. ok is false if a chunk has no room
+// for further content.
+func splitContentOverlap(content string, level RecoveryLevel, overlap int,
+	header string) (chunks []string, repeated []int, ok bool) {
+	pos := 0
+	for pos < len(content) {
+		// Start of the last overlap runes of the preceding content.
+		start := pos
+		for k := 0; k < overlap && start > 0; k++ {
+			_, size := utf8.DecodeLastRuneInString(content[:start])
+			start -= size
+		}
+
+		end := maxEncodablePrefix(content[pos:], fitsAt(level, header+content[start:pos]))
+		if end == 0 {
+			return nil, nil, false
+		}
+
+		chunks = append(chunks, content[start:pos+end])
+		repeated = append(repeated, utf8.RuneCountInString(content[start:pos]))
+		pos += end
+	}
+
+	return chunks, repeated, true
+}
+
+// overlapHeader returns the SplitContentOverlap header of chunk i of n, which
+// repeats k runes of the previous chunk.
+func overlapHeader(i int, n int, k int) string {
+	return fmt.Sprintf("[%d/%d+%d]", i, n, k)
+}
+
+// JoinOverlap reassembles the chunks returned by SplitContentOverlap(), which
+// may be in any order, removing the runes each chunk repeats.
+//
+// An error occurs if a chunk has no valid header, the chunks disagree on the
+// number of chunks, a chunk is missing or duplicated, or the repeated runes of
+// a chunk don't match the end of the previous chunk.
+func JoinOverlap(chunks []string) (string, error) {
+	if len(chunks) == 0 {
+		return "", errors.New("no chunks to join")
+	}
+
+	ordered := make([]string, len(chunks))
+	repeated := make([]int, len(chunks))
+	found := make([]bool, len(chunks))
+
+	for _, chunk := range chunks {
+		i, n, k, body, err := parseOverlapHeader(chunk)
+		if err != nil {
+			return "", err
 		}
-		// Back up to rune boundary if we split a multi-byte rune
-		for end > 0 && end < len(content) && !utf8.RuneStart(content[end]) {
-			end--
+
+		if n != len(chunks) {
+			return "", fmt.Errorf("chunk [%d/%d]: got %d chunks, expected %d", i, n, len(chunks), n)
+		}
+
+		if found[i-1] {
+			return "", fmt.Errorf("duplicate chunk [%d/%d]", i, n)
+		}
+
+		ordered[i-1] = body
+		repeated[i-1] = k
+		found[i-1] = true
+	}
+
+	var joined strings.Builder
+	for i, body := range ordered {
+		// Byte length of the repeated runes.
+		length := 0
+		for k := 0; k < repeated[i] && length < len(body); k++ {
+			_, size := utf8.DecodeRuneInString(body[length:])
+			length += size
 		}
+
+		if !strings.HasSuffix(joined.String(), body[:length]) || utf8.RuneCountInString(body[:length]) != repeated[i] {
+			return "", fmt.Errorf("chunk [%d/%d]: repeated runes don't match the previous chunk", i+1, len(chunks))
+		}
+
+		joined.WriteString(body[length:])
+	}
+
+	return joined.String(), nil
+}
+
+// parseOverlapHeader parses the SplitContentOverlap header of chunk, returning
+// the chunk index i of n, the number of repeated runes k, and the chunk without
+// its header.
+func parseOverlapHeader(chunk string) (i int, n int, k int, body string, err error) {
+	end := strings.IndexByte(chunk, ']')
+	plus := strings.IndexByte(chunk, '+')
+
+	if end < 0 || plus < 0 || plus > end {
+		return 0, 0, 0, "", fmt.Errorf("chunk %q has no [i/n+k] header", truncate(chunk, 16))
+	}
+
+	k, err = strconv.Atoi(chunk[plus+1 : end])
+	if err != nil || k < 0 {
+		return 0, 0, 0, "", fmt.Errorf("chunk %q has an invalid [i/n+k] header", truncate(chunk, 16))
+	}
+
+	// The rest of the header is as for SplitContentIndexed.
+	i, n, body, err = parseIndexHeader(chunk[:plus] + chunk[end:])
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+
+	return i, n, k, body, nil
+}
+
+// truncate returns s cut to at most n bytes, for error messages.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+
+	return s
+}
+
+// splitContent splits content into chunks, each the longest prefix of the
+// remaining content returned by prefix for which fits returns true.
+func splitContent(content string, fits func(chunk string) bool,
+	prefix func(content string, fits func(prefix string) bool) int) []string {
+	var chunks []string
+	for len(content) > 0 {
+		end := prefix(content, fits)
 		if end == 0 {
 			break
 		}
@@ -63,3 +318,80 @@ func SplitContentUTF8(content string, level RecoveryLevel) []string {
 	}
 	return chunks
 }
+
+// fitsAt returns a function reporting whether a chunk, prefixed by header, is
+// accepted by New at the given recovery level.
+func fitsAt(level RecoveryLevel, header string) func(chunk string) bool {
+	return func(chunk string) bool {
+		_, err := New(header+chunk, level)
+		return err == nil
+	}
+}
+
+// maxSymbolChars is the largest number of characters encodable in a single QR
+// Code: 7089 numeric digits in a Version 40 symbol at the Low recovery level.
+const maxSymbolChars = 7089
+
+// maxEncodablePrefix returns the length in bytes of the longest prefix of
+// content, ending on a rune boundary, for which fits returns true. Zero is
+// returned if no prefix fits.
+//
+// fits is assumed to be monotonic: if a prefix fits, all shorter prefixes fit
+// too.
+func maxEncodablePrefix(content string, fits func(prefix string) bool) int {
+	limit := len(content)
+	if limit > maxSymbolChars {
+		limit = maxSymbolChars
+	}
+
+	// Candidate prefix lengths, in ascending order.
+	var ends []int
+	for i := range content[:limit] {
+		if i > 0 {
+			ends = append(ends, i)
+		}
+	}
+	if limit == len(content) || utf8.RuneStart(content[limit]) {
+		ends = append(ends, limit)
+	}
+
+	return longestPrefix(content, ends, fits)
+}
+
+// maxEncodableByteLength returns the length in bytes of the longest prefix of
+// content for which fits returns true, as maxEncodablePrefix, but without
+// regard to rune boundaries.
+func maxEncodableByteLength(content string, fits func(prefix string) bool) int {
+	limit := len(content)
+	if limit > maxSymbolChars {
+		limit = maxSymbolChars
+	}
+
+	ends := make([]int, limit)
+	for i := range ends {
+		ends[i] = i + 1
+	}
+
+	return longestPrefix(content, ends, fits)
+}
+
+// longestPrefix returns the longest of the candidate prefix lengths ends (in
+// ascending order) for which fits returns true, or zero if none fit.
+func longestPrefix(content string, ends []int, fits func(prefix string) bool) int {
+	// Binary search for the last candidate which fits.
+	lo, hi := 0, len(ends)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if fits(content[:ends[mid]]) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	if lo == 0 {
+		return 0
+	}
+
+	return ends[lo-1]
+}
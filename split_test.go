@@ -0,0 +1,310 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitContentUTF8Modes(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected int
+	}{
+		// 5000 digits need 4 chunks by byte capacity.
+		{strings.Repeat("0123456789", 500), 2},
+		{strings.Repeat("HELLO WORLD ", 400), 3},
+		{strings.Repeat("hello world ", 400), 4},
+		{strings.Repeat("Grüße, ", 500), 4},
+	}
+
+	for _, test := range tests {
+		chunks := SplitContentUTF8(test.content, Highest)
+
+		if len(chunks) != test.expected {
+			t.Errorf("%.10q...: got %d chunks, expected %d", test.content, len(chunks), test.expected)
+		}
+
+		if strings.Join(chunks, "") != test.content {
+			t.Errorf("%.10q...: chunks do not reassemble to the original content", test.content)
+		}
+
+		for i, chunk := range chunks {
+			if _, err := New(chunk, Highest); err != nil {
+				t.Errorf("%.10q... chunk %d: %s", test.content, i, err.Error())
+			}
+		}
+	}
+
+	digits := strings.Repeat("0123456789", 500)
+	byByteCapacity := (len(digits) + MaxByteCapacity(Highest) - 1) / MaxByteCapacity(Highest)
+	if len(SplitContentUTF8(digits, Highest)) >= byByteCapacity {
+		t.Errorf("numeric content did not split into fewer chunks than by byte capacity")
+	}
+}
+
+func TestSplitContentExact(t *testing.T) {
+	tests := []string{
+		strings.Repeat("0123456789", 500),
+		strings.Repeat("hello world ", 400),
+		strings.Repeat("Grüße, ", 500),
+		strings.Repeat("Mixed 123 CONTENT, ", 300),
+	}
+
+	for _, content := range tests {
+		for _, isUTF8 := range []bool{false, true} {
+			var chunks []string
+			if isUTF8 {
+				chunks = SplitContentUTF8(content, Highest)
+			} else {
+				chunks = SplitContent(content, Highest)
+			}
+
+			if strings.Join(chunks, "") != content {
+				t.Errorf("%.10q... (utf8=%t): chunks do not reassemble to the original content",
+					content, isUTF8)
+			}
+
+			offset := 0
+			for i, chunk := range chunks {
+				offset += len(chunk)
+
+				if _, err := New(chunk, Highest); err != nil {
+					t.Errorf("%.10q... (utf8=%t) chunk %d: %s", content, isUTF8, i, err.Error())
+				}
+
+				if i == len(chunks)-1 {
+					continue
+				}
+
+				// One more byte (or rune) does not fit.
+				next := 1
+				if isUTF8 {
+					next = len(string([]rune(content[offset:])[0]))
+				}
+
+				if _, err := New(chunk+content[offset:offset+next], Highest); err == nil {
+					t.Errorf("%.10q... (utf8=%t) chunk %d: not at maximum fit", content, isUTF8, i)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitContentUTF8RuneBoundaries(t *testing.T) {
+	content := strings.Repeat("日本語", 1000)
+
+	for i, chunk := range SplitContentUTF8(content, Medium) {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %d split a rune", i)
+		}
+	}
+}
+
+func TestSplitBytes(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(data)
+	data[0], data[1] = 0x00, 0xff
+
+	for _, level := range []RecoveryLevel{Low, Highest} {
+		chunks := SplitBytes(data, level)
+
+		capacity := MaxByteCapacity(level)
+		if expected := (len(data) + capacity - 1) / capacity; len(chunks) != expected {
+			t.Errorf("level %d: got %d chunks, expected %d", level, len(chunks), expected)
+		}
+
+		for i, chunk := range chunks {
+			if i < len(chunks)-1 && len(chunk) != capacity {
+				t.Errorf("level %d: chunk %d is %d bytes, expected %d", level, i, len(chunk), capacity)
+			}
+
+			q, err := NewBytes(chunk, level)
+			if err != nil {
+				t.Fatalf("level %d: chunk %d: %s", level, i, err.Error())
+			}
+			if s, err := Decode(q.Image(-3)); i == 0 && (err != nil || s != string(chunk)) {
+				t.Errorf("level %d: chunk %d does not decode", level, i)
+			}
+		}
+
+		if joined := bytes.Join(chunks, nil); !bytes.Equal(joined, data) {
+			t.Errorf("level %d: chunks do not reassemble to the original data", level)
+		}
+	}
+
+	if chunks := SplitBytes(nil, Low); len(chunks) != 0 {
+		t.Errorf("no data: got %d chunks, expected none", len(chunks))
+	}
+}
+
+func TestSplitContentIndexed(t *testing.T) {
+	tests := []struct {
+		content string
+		level   RecoveryLevel
+	}{
+		{"short", Medium},
+		{strings.Repeat("Indexed chunks, grüße. ", 200), Highest},
+		{strings.Repeat("0123456789", 2000), High},
+	}
+
+	for _, test := range tests {
+		chunks := SplitContentIndexed(test.content, test.level)
+
+		for i, chunk := range chunks {
+			if header := fmt.Sprintf("[%d/%d]", i+1, len(chunks)); !strings.HasPrefix(chunk, header) {
+				t.Errorf("chunk %d: got %q, expected header %s", i, chunk[:len(header)], header)
+			}
+
+			if _, err := New(chunk, test.level); err != nil {
+				t.Errorf("chunk %d: %s", i, err.Error())
+			}
+
+			if !utf8.ValidString(chunk) {
+				t.Errorf("chunk %d is not valid UTF-8", i)
+			}
+		}
+
+		// Reassemble out of order.
+		shuffled := append([]string{}, chunks...)
+		for i, j := 0, len(shuffled)-1; i < j; i, j = i+1, j-1 {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		}
+
+		joined, err := JoinIndexed(shuffled)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if joined != test.content {
+			t.Errorf("%d chunks do not reassemble to the original content", len(chunks))
+		}
+	}
+}
+
+func TestSplitContentOverlap(t *testing.T) {
+	const overlap = 5
+
+	tests := []struct {
+		content string
+		level   RecoveryLevel
+	}{
+		{"short", Medium},
+		{strings.Repeat("Overlapping chunks, grüße. ", 200), Highest},
+		{strings.Repeat("0123456789", 2000), High},
+	}
+
+	for _, test := range tests {
+		chunks := SplitContentOverlap(test.content, test.level, overlap)
+		if len(chunks) == 0 {
+			t.Fatalf("%d bytes: got no chunks", len(test.content))
+		}
+
+		var previous string
+		for i, chunk := range chunks {
+			k := overlap
+			if i == 0 {
+				k = 0
+			}
+
+			header := fmt.Sprintf("[%d/%d+%d]", i+1, len(chunks), k)
+			if !strings.HasPrefix(chunk, header) {
+				t.Fatalf("chunk %d: got %q, expected header %s", i, truncate(chunk, 16), header)
+			}
+
+			if _, err := New(chunk, test.level); err != nil {
+				t.Errorf("chunk %d: %s", i, err.Error())
+			}
+
+			// The chunk starts with the last runes of the previous chunk.
+			body := []rune(chunk[len(header):])
+			if last := []rune(previous); i > 0 && string(body[:k]) != string(last[len(last)-k:]) {
+				t.Errorf("chunk %d: got %q, expected to repeat %q", i, string(body[:k]), string(last[len(last)-k:]))
+			}
+			previous = string(body)
+		}
+
+		if len(test.content) > 1000 && len(chunks) < 2 {
+			t.Errorf("%d bytes: got %d chunks, expected several", len(test.content), len(chunks))
+		}
+
+		// Reassemble out of order.
+		shuffled := append([]string{}, chunks...)
+		for i, j := 0, len(shuffled)-1; i < j; i, j = i+1, j-1 {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		}
+
+		joined, err := JoinOverlap(shuffled)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if joined != test.content {
+			t.Errorf("%d chunks do not reassemble to the original content", len(chunks))
+		}
+	}
+
+	if chunks := SplitContentOverlap(strings.Repeat("a", 5000), Highest, 3000); chunks != nil {
+		t.Errorf("overlap 3000: got %d chunks, expected nil", len(chunks))
+	}
+}
+
+func TestJoinOverlapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"empty", nil},
+		{"no header", []string{"[1/2+0]ab", "bc"}},
+		{"index header", []string{"[1/2]ab", "[2/2]bc"}},
+		{"invalid overlap", []string{"[1/2+0]ab", "[2/2+x]bc"}},
+		{"negative overlap", []string{"[1/2+0]ab", "[2/2+-1]bc"}},
+		{"gap", []string{"[1/3+0]ab", "[3/3+1]cd"}},
+		{"duplicate", []string{"[1/2+0]ab", "[1/2+0]ab"}},
+		{"mismatched overlap", []string{"[1/2+0]ab", "[2/2+1]xc"}},
+		{"overlap too long", []string{"[1/2+0]ab", "[2/2+4]bc"}},
+		{"overlap in first chunk", []string{"[1/2+1]ab", "[2/2+1]bc"}},
+	}
+
+	for _, test := range tests {
+		if _, err := JoinOverlap(test.chunks); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+	}
+
+	if s, err := JoinOverlap([]string{"[2/2+2]ü+c]", "[1/2+0][aü+"}); err != nil || s != "[aü+c]" {
+		t.Errorf("got %q, %v, expected \"[aü+c]\"", s, err)
+	}
+}
+
+func TestJoinIndexedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"empty", nil},
+		{"no header", []string{"[1/2]a", "b"}},
+		{"invalid header", []string{"[1/x]a"}},
+		{"zero index", []string{"[0/1]a"}},
+		{"index out of range", []string{"[1/2]a", "[3/2]b"}},
+		{"gap", []string{"[1/3]a", "[3/3]c"}},
+		{"duplicate", []string{"[1/2]a", "[1/2]a"}},
+		{"inconsistent total", []string{"[1/2]a", "[2/3]b"}},
+	}
+
+	for _, test := range tests {
+		if _, err := JoinIndexed(test.chunks); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+	}
+
+	if s, err := JoinIndexed([]string{"[2/2]b]", "[1/2][a"}); err != nil || s != "[ab]" {
+		t.Errorf("got %q, %v, expected \"[ab]\"", s, err)
+	}
+}
@@ -0,0 +1,127 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// SVG returns the QR Code as an SVG document.
+//
+// size is both the rendered width and height in pixels. The document's viewBox
+// is expressed in modules, so the output scales cleanly to any size. Negative
+// values for size set the number of pixels per module instead, as for Image().
+//
+// Dark modules are merged into horizontal runs, which keeps the output small,
+// and emitted as two <path> elements: The dark modules of the finder patterns
+// (the three "eyes") as <path class="finder">, and all other dark modules as
+// <path class="data">. A stylesheet can then style each independently, e.g.
+// to recolour the finder patterns. The paths are filled in the FinderColor (if
+// set) and ForegroundColor respectively, so the document is correct without a
+// stylesheet.
+func (q *QRCode) SVG(size int) ([]byte, error) {
+	var b bytes.Buffer
+	err := q.WriteSVG(size, &b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteSVG writes the QR Code as an SVG document to io.Writer, without
+// buffering the whole document in memory. See the documentation for SVG().
+func (q *QRCode) WriteSVG(size int, out io.Writer) error {
+	if err := q.checkContrast(); err != nil {
+		return err
+	}
+
+	bitmap := q.Bitmap()
+	realSize := len(bitmap)
+
+	if size < 0 {
+		size = size * -1 * realSize
+	}
+
+	if size < realSize {
+		size = realSize
+	}
+
+	buf := bufio.NewWriter(out)
+
+	fmt.Fprintf(buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size, size, realSize, realSize)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" %s/>`+"\n",
+		realSize, realSize, svgFill(q.BackgroundColor))
+
+	finderColor := q.ForegroundColor
+	if q.FinderColor != nil {
+		finderColor = q.FinderColor
+	}
+
+	writeSVGPath(buf, "finder", finderColor, bitmap, q.isFinderModule)
+	writeSVGPath(buf, "data", q.ForegroundColor, bitmap, func(x int, y int) bool {
+		return !q.isFinderModule(x, y)
+	})
+
+	buf.WriteString("</svg>\n")
+
+	return buf.Flush()
+}
+
+// writeSVGPath writes a <path> of class class, filled with c, covering the dark
+// modules of bitmap for which include returns true.
+func writeSVGPath(buf *bufio.Writer, class string, c color.Color, bitmap [][]bool, include func(x int, y int) bool) {
+	dark := func(x int, y int) bool {
+		return bitmap[y][x] && include(x, y)
+	}
+
+	fmt.Fprintf(buf, `<path class="%s" %s d="`, class, svgFill(c))
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !dark(x, y) {
+				x++
+				continue
+			}
+
+			start := x
+			for x < len(row) && dark(x, y) {
+				x++
+			}
+
+			fmt.Fprintf(buf, "M%d,%dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/>` + "\n")
+}
+
+// SVGString returns the QR Code as an SVG document string, suitable for
+// embedding inline in HTML. See SVG() for the meaning of size.
+func (q *QRCode) SVGString(size int) (string, error) {
+	svg, err := q.SVG(size)
+	if err != nil {
+		return "", err
+	}
+
+	return string(svg), nil
+}
+
+// svgFill returns the SVG fill attributes for c. Translucent colors also set a
+// fill-opacity.
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%.3f"`, float64(n.A)/0xff)
+	}
+
+	return fill
+}
@@ -0,0 +1,143 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svg, err := q.SVG(256)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"svg"`
+		Width   string   `xml:"width,attr"`
+		ViewBox string   `xml:"viewBox,attr"`
+		Rect    []struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"rect"`
+		Path []struct {
+			Class string `xml:"class,attr"`
+			Fill  string `xml:"fill,attr"`
+			D     string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+
+	if err := xml.Unmarshal(svg, &doc); err != nil {
+		t.Fatalf("SVG is not valid XML: %s", err.Error())
+	}
+
+	n := len(q.Bitmap())
+	if doc.ViewBox != fmt.Sprintf("0 0 %d %d", n, n) {
+		t.Errorf("viewBox is %q, expected %d modules", doc.ViewBox, n)
+	}
+
+	if doc.Width != "256" {
+		t.Errorf("width is %q, expected 256", doc.Width)
+	}
+
+	if len(doc.Rect) != 1 || doc.Rect[0].Fill != "#ffffff" {
+		t.Errorf("expected a single white background rect, got %v", doc.Rect)
+	}
+
+	if len(doc.Path) != 2 || doc.Path[0].Class != "finder" || doc.Path[1].Class != "data" {
+		t.Fatalf("expected finder and data paths, got %v", doc.Path)
+	}
+
+	// Count the dark modules covered by each path's horizontal runs.
+	numDark := map[string]int{}
+	for y, row := range q.Bitmap() {
+		for x, v := range row {
+			if v && q.isFinderModule(x, y) {
+				numDark["finder"]++
+			} else if v {
+				numDark["data"]++
+			}
+		}
+	}
+
+	for _, path := range doc.Path {
+		if path.Fill != "#000000" {
+			t.Errorf("%s path: got fill %q, expected black", path.Class, path.Fill)
+		}
+
+		numCovered := 0
+		for _, run := range strings.Split(path.D, "z") {
+			if run == "" {
+				continue
+			}
+
+			var x, y, w, w2 int
+			if _, err := fmt.Sscanf(run, "M%d,%dh%dv1h-%d", &x, &y, &w, &w2); err != nil {
+				t.Fatalf("bad path run %q: %s", run, err.Error())
+			}
+			numCovered += w
+
+			// Finder runs lie within the finder patterns.
+			if path.Class == "finder" && !(q.isFinderModule(x, y) && q.isFinderModule(x+w-1, y)) {
+				t.Errorf("finder run %q outside the finder patterns", run)
+			}
+		}
+
+		if numCovered != numDark[path.Class] {
+			t.Errorf("%s path covers %d modules, expected %d", path.Class, numCovered, numDark[path.Class])
+		}
+	}
+
+	// The finder patterns may be coloured separately.
+	q.FinderColor = color.RGBA{R: 0xcc, A: 0xff}
+	s, err := q.SVGString(256)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(s, `<path class="finder" fill="#cc0000"`) || !strings.Contains(s, `<path class="data" fill="#000000"`) {
+		t.Errorf("FinderColor not applied to the finder path")
+	}
+}
+
+func TestSVGColorsAndBorder(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.DisableBorder = true
+	q.ForegroundColor = color.RGBA{R: 0x33, G: 0x33, B: 0x66, A: 0xff}
+	q.BackgroundColor = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}
+
+	s, err := q.SVGString(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	size := q.version.symbolSize()
+
+	if !strings.Contains(s, fmt.Sprintf(`viewBox="0 0 %d %d"`, size, size)) {
+		t.Errorf("viewBox does not match borderless symbol size %d", size)
+	}
+
+	if !strings.Contains(s, fmt.Sprintf(`width="%d"`, size*4)) {
+		t.Errorf("variable size not applied")
+	}
+
+	if !strings.Contains(s, `fill="#333366"`) {
+		t.Errorf("foreground color not applied")
+	}
+
+	if !strings.Contains(s, `fill-opacity="0.502"`) {
+		t.Errorf("background alpha not applied")
+	}
+}
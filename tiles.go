@@ -0,0 +1,80 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// ImageWithTiles returns the QR Code as an image.Image, with each dark module
+// filled with darkTile, and each light module with lightTile, e.g. to texture
+// the modules of a branded QR Code.
+//
+// Both tiles must be exactly modulePixels by modulePixels pixels. The image
+// size is as for ImageExact(). The quiet zone is filled with the BorderColor, or
+// the BackgroundColor, and translucent tiles are composited over the
+// BackgroundColor.
+//
+// Keep the tiles mostly solid, with strong contrast between them, so the QR
+// Code remains readable. An error occurs if a tile is nil or of the wrong size,
+// or modulePixels is less than 1.
+func (q *QRCode) ImageWithTiles(darkTile image.Image, lightTile image.Image, modulePixels int) (image.Image, error) {
+	if modulePixels < 1 {
+		return nil, fmt.Errorf("invalid module size %dpx (expected 1 or more)", modulePixels)
+	}
+
+	tiles := []struct {
+		name string
+		img  image.Image
+	}{
+		{"dark", darkTile},
+		{"light", lightTile},
+	}
+
+	for _, tile := range tiles {
+		if tile.img == nil {
+			return nil, errors.New("nil " + tile.name + " tile")
+		}
+
+		if b := tile.img.Bounds(); b.Dx() != modulePixels || b.Dy() != modulePixels {
+			return nil, fmt.Errorf("invalid %s tile size %dx%d (expected %dx%d)",
+				tile.name, b.Dx(), b.Dy(), modulePixels, modulePixels)
+		}
+	}
+
+	// Build QR code.
+	q.encode()
+
+	size := modulePixels * q.symbol.size
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	border := q.BackgroundColor
+	if q.BorderColor != nil {
+		border = q.BorderColor
+	}
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{border}, image.Point{}, draw.Src)
+
+	symbolStart := q.symbol.quietZoneSize * modulePixels
+	symbolEnd := symbolStart + q.symbol.symbolSize*modulePixels
+	draw.Draw(dst, image.Rect(symbolStart, symbolStart, symbolEnd, symbolEnd),
+		&image.Uniform{q.BackgroundColor}, image.Point{}, draw.Src)
+
+	bitmap := q.symbol.bitmap()
+	for my := q.symbol.quietZoneSize; my < q.symbol.quietZoneSize+q.symbol.symbolSize; my++ {
+		for mx := q.symbol.quietZoneSize; mx < q.symbol.quietZoneSize+q.symbol.symbolSize; mx++ {
+			tile := lightTile
+			if bitmap[my][mx] {
+				tile = darkTile
+			}
+
+			cell := image.Rect(mx*modulePixels, my*modulePixels, (mx+1)*modulePixels, (my+1)*modulePixels)
+			draw.Draw(dst, cell, tile, tile.Bounds().Min, draw.Over)
+		}
+	}
+
+	return dst, nil
+}
@@ -0,0 +1,114 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestImageWithTiles(t *testing.T) {
+	q, err := New("https://example.org/tiles", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const modulePixels = 8
+
+	// Mostly solid tiles, with a speck of colour. The tiles needn't start at
+	// the origin.
+	tile := func(c color.Color, speck color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(3, 3, 3+modulePixels, 3+modulePixels))
+		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+		img.Set(5, 4, speck)
+		return img
+	}
+
+	dark := tile(color.Black, color.RGBA{R: 0xff, A: 0xff})
+	light := tile(color.White, color.RGBA{G: 0xff, A: 0xff})
+
+	img, err := q.ImageWithTiles(dark, light, modulePixels)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bitmap := q.Bitmap()
+	if b := img.Bounds(); b.Dx() != len(bitmap)*modulePixels || b.Dy() != b.Dx() {
+		t.Fatalf("got size %dx%d, expected %dx%d", b.Dx(), b.Dy(), len(bitmap)*modulePixels, len(bitmap)*modulePixels)
+	}
+
+	// Compares the cell of module (mx, my) with tile.
+	matches := func(mx int, my int, tile image.Image) bool {
+		tb := tile.Bounds()
+		for y := 0; y < modulePixels; y++ {
+			for x := 0; x < modulePixels; x++ {
+				got := color.RGBAModel.Convert(img.At(mx*modulePixels+x, my*modulePixels+y))
+				expected := color.RGBAModel.Convert(tile.At(tb.Min.X+x, tb.Min.Y+y))
+				if got != expected {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	border := q.symbol.quietZoneSize
+	var numDark, numLight int
+	for my := border; my < len(bitmap)-border; my++ {
+		for mx := border; mx < len(bitmap)-border; mx++ {
+			expected := light
+			if bitmap[my][mx] {
+				expected = dark
+				numDark++
+			} else {
+				numLight++
+			}
+
+			if !matches(mx, my, expected) {
+				t.Fatalf("module (%d, %d) (dark=%t) does not match its tile", mx, my, bitmap[my][mx])
+			}
+		}
+	}
+
+	if numDark == 0 || numLight == 0 {
+		t.Errorf("got %d dark and %d light modules", numDark, numLight)
+	}
+
+	// The quiet zone is left plain.
+	for _, p := range []image.Point{{0, 0}, {2, 4}} {
+		if c := color.RGBAModel.Convert(img.At(p.X, p.Y)); c != color.RGBAModel.Convert(color.White) {
+			t.Errorf("quiet zone pixel %v: got %v, expected white", p, c)
+		}
+	}
+}
+
+func TestImageWithTilesErrors(t *testing.T) {
+	q, err := New("tile errors", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tile := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	wide := image.NewRGBA(image.Rect(0, 0, 5, 4))
+
+	tests := []struct {
+		name         string
+		dark, light  image.Image
+		modulePixels int
+	}{
+		{"nil dark tile", nil, tile, 4},
+		{"nil light tile", tile, nil, 4},
+		{"wrong dark tile size", tile, tile, 5},
+		{"non-square light tile", tile, wide, 4},
+		{"zero module size", tile, tile, 0},
+	}
+
+	for _, test := range tests {
+		if _, err := q.ImageWithTiles(test.dark, test.light, test.modulePixels); err == nil {
+			t.Errorf("%s: got success, expected error", test.name)
+		}
+	}
+}
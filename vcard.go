@@ -0,0 +1,124 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// A VCard describes a contact, encoded as a vCard 3.0 (RFC 2426) payload.
+//
+// Scanning the QR Code offers to add the contact to the reader's address book.
+// Empty fields are omitted. Each element of the slice fields is written as a
+// separate property.
+type VCard struct {
+	// Formatted name, e.g. "Dr. Jane Doe". Required.
+	FormattedName string
+
+	// Structured name components.
+	FamilyName string
+	GivenName  string
+
+	Organization string
+	Title        string
+
+	Phone   []string
+	Email   []string
+	URL     []string
+	Address []string
+}
+
+// Encode constructs a QRCode containing the vCard.
+//
+// An error occurs if FormattedName is empty, or the vCard is too long.
+func (v VCard) Encode(level RecoveryLevel) (*QRCode, error) {
+	content, err := v.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(content, level)
+}
+
+// vCardEscaper escapes the special characters of vCard property values.
+var vCardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// payload returns the vCard as a string.
+func (v VCard) payload() (string, error) {
+	if v.FormattedName == "" {
+		return "", errors.New("vCard requires a formatted name")
+	}
+
+	var b strings.Builder
+
+	add := func(name string, value string) {
+		if value != "" {
+			b.WriteString(foldLine(name + ":" + value))
+		}
+	}
+
+	add("BEGIN", "VCARD")
+	add("VERSION", "3.0")
+	add("N", vCardEscaper.Replace(v.FamilyName)+";"+vCardEscaper.Replace(v.GivenName)+";;;")
+	add("FN", vCardEscaper.Replace(v.FormattedName))
+	add("ORG", vCardEscaper.Replace(v.Organization))
+	add("TITLE", vCardEscaper.Replace(v.Title))
+
+	for _, phone := range v.Phone {
+		add("TEL", vCardEscaper.Replace(phone))
+	}
+	for _, email := range v.Email {
+		add("EMAIL", vCardEscaper.Replace(email))
+	}
+	for _, url := range v.URL {
+		add("URL", vCardEscaper.Replace(url))
+	}
+	for _, address := range v.Address {
+		// The address is free text, placed in the street address component.
+		add("ADR", ";;"+vCardEscaper.Replace(address)+";;;;")
+	}
+
+	add("END", "VCARD")
+
+	return b.String(), nil
+}
+
+// maxLineLength is the maximum length of a content line in octets, excluding
+// the line break, as recommended by RFC 2425 (vCard) and RFC 5545 (iCalendar).
+const maxLineLength = 75
+
+// foldLine returns line terminated by CRLF, folded onto multiple lines so that
+// no line is longer than maxLineLength octets. Continuation lines begin with a
+// single space. Lines are only folded at rune boundaries.
+func foldLine(line string) string {
+	var b strings.Builder
+
+	limit := maxLineLength
+	for len(line) > limit {
+		end := limit
+		for end > 0 && !utf8.RuneStart(line[end]) {
+			end--
+		}
+
+		b.WriteString(line[:end])
+		b.WriteString("\r\n ")
+		line = line[end:]
+
+		// Allow for the leading space of continuation lines.
+		limit = maxLineLength - 1
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+
+	return b.String()
+}
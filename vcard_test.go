@@ -0,0 +1,110 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseContentLines unfolds and splits a vCard or iCalendar payload into
+// property names and values.
+func parseContentLines(t *testing.T, payload string) map[string][]string {
+	if !strings.HasSuffix(payload, "\r\n") {
+		t.Fatalf("payload %q does not end with CRLF", payload)
+	}
+
+	for _, line := range strings.Split(payload, "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is longer than %d octets", line, maxLineLength)
+		}
+	}
+
+	unfolded := strings.Replace(payload, "\r\n ", "", -1)
+
+	properties := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSuffix(unfolded, "\r\n"), "\r\n") {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			t.Fatalf("invalid content line %q", line)
+		}
+
+		properties[line[:i]] = append(properties[line[:i]], line[i+1:])
+	}
+
+	return properties
+}
+
+func TestVCard(t *testing.T) {
+	v := VCard{
+		FormattedName: "Jane Doe",
+		FamilyName:    "Doe",
+		GivenName:     "Jane",
+		Organization:  "Example, Inc.",
+		Title:         "Engineer",
+		Phone:         []string{"+1 555 0100", "+1 555 0199"},
+		Email:         []string{"jane@example.org"},
+		URL:           []string{"https://example.org/" + strings.Repeat("long-path/", 10)},
+		Address:       []string{"1 Main St; Springfield"},
+	}
+
+	payload, err := v.payload()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	properties := parseContentLines(t, payload)
+
+	expected := map[string][]string{
+		"BEGIN":   {"VCARD"},
+		"VERSION": {"3.0"},
+		"N":       {"Doe;Jane;;;"},
+		"FN":      {"Jane Doe"},
+		"ORG":     {`Example\, Inc.`},
+		"TITLE":   {"Engineer"},
+		"TEL":     {"+1 555 0100", "+1 555 0199"},
+		"EMAIL":   {"jane@example.org"},
+		"URL":     v.URL,
+		"ADR":     {`;;1 Main St\; Springfield;;;;`},
+		"END":     {"VCARD"},
+	}
+
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("got %v, expected %v", properties, expected)
+	}
+
+	if !strings.HasPrefix(payload, "BEGIN:VCARD\r\nVERSION:3.0\r\n") {
+		t.Errorf("payload does not start with the vCard header")
+	}
+
+	q, err := v.Encode(Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.Content != payload {
+		t.Errorf("QR Code content does not match the payload")
+	}
+
+	if _, err := (VCard{}).Encode(Medium); err == nil {
+		t.Errorf("empty vCard: got success, expected error")
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	// Multi-byte runes are never split across lines.
+	line := strings.Repeat("ü", 100)
+
+	folded := foldLine(line)
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > maxLineLength {
+			t.Errorf("line %q is longer than %d octets", l, maxLineLength)
+		}
+	}
+
+	if unfolded := strings.Replace(folded, "\r\n ", "", -1); unfolded != line+"\r\n" {
+		t.Errorf("got %q after unfolding", unfolded)
+	}
+}
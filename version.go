@@ -4,7 +4,9 @@
 package qrcode
 
 import (
+	"fmt"
 	"log"
+	"sync"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
 )
@@ -30,6 +32,16 @@ const (
 	Highest
 )
 
+// checkRecoveryLevel returns an error if level is not one of the RecoveryLevel
+// constants, e.g. after an unchecked conversion from an integer.
+func checkRecoveryLevel(level RecoveryLevel) error {
+	if level < Low || level > Highest {
+		return fmt.Errorf("invalid recovery level %d (expected Low, Medium, High or Highest)", level)
+	}
+
+	return nil
+}
+
 // qrCodeVersion describes the data length and encoding order of a single QR
 // Code version. There are 40 versions numbers x 4 recovery levels == 160
 // possible qrCodeVersion structures.
@@ -2965,26 +2977,21 @@ func (v qrCodeVersion) numDataBits() int {
 //
 // On success the chosen QR Code version is returned.
 func chooseQRCodeVersion(level RecoveryLevel, encoder *dataEncoder, numDataBits int) *qrCodeVersion {
-	var chosenVersion *qrCodeVersion
-
-	for _, v := range versions {
-		if v.level != level {
-			continue
-		} else if v.version < encoder.minVersion {
-			continue
-		} else if v.version > encoder.maxVersion {
-			break
-		}
+	t := capacities()
+	if !t.valid(level, 1) {
+		return nil
+	}
 
-		numFreeBits := v.numDataBits() - numDataBits
+	for version := max(encoder.minVersion, 1); version <= min(encoder.maxVersion, 40); version++ {
+		numFreeBits := t.numDataBits[level][version] - numDataBits
 
 		if numFreeBits >= 0 {
-			chosenVersion = &v
-			break
+			v := versions[t.index[level][version]]
+			return &v
 		}
 	}
 
-	return chosenVersion
+	return nil
 }
 
 func (v qrCodeVersion) numTerminatorBitsRequired(numDataBits int) int {
@@ -3040,24 +3047,210 @@ func (v qrCodeVersion) quietZoneSize() int {
 // getQRCodeVersion returns the QR Code version by version number and recovery
 // level. Returns nil if the requested combination is not defined.
 func getQRCodeVersion(level RecoveryLevel, version int) *qrCodeVersion {
-	for _, v := range versions {
-		if v.level == level && v.version == version {
-			return &v
-		}
+	t := capacities()
+	if !t.valid(level, version) {
+		return nil
 	}
 
-	return nil
+	v := versions[t.index[level][version]]
+	return &v
+}
+
+// capacityTable holds the capacity of each QR Code version at each recovery
+// level, indexed by [level][version number]. Use capacities() to access it.
+type capacityTable struct {
+	// Position in versions, or -1 if the combination is not defined.
+	index [Highest + 1][41]int
+
+	// Data capacity in bits.
+	numDataBits [Highest + 1][41]int
+
+	// Capacity in bytes using byte-mode encoding.
+	byteCapacity [Highest + 1][41]int
+}
+
+var (
+	capacityTableOnce sync.Once
+	capacityTableData capacityTable
+)
+
+// capacities returns the capacity table, computing it on first use.
+//
+// Capacity queries are frequent when splitting content, so the table saves
+// repeatedly scanning versions and summing the data capacity of its blocks.
+func capacities() *capacityTable {
+	capacityTableOnce.Do(func() {
+		t := &capacityTableData
+
+		for level := range t.index {
+			for version := range t.index[level] {
+				t.index[level][version] = -1
+			}
+		}
+
+		for i, v := range versions {
+			// Mode indicator and character count bits.
+			overhead, err := newDataEncoder(v.dataEncoderType).encodedLength(dataModeByte, 0)
+			if err != nil {
+				log.Panicf("bug: %s", err.Error())
+			}
+
+			t.index[v.level][v.version] = i
+			t.numDataBits[v.level][v.version] = v.numDataBits()
+			t.byteCapacity[v.level][v.version] = (v.numDataBits() - overhead) / 8
+		}
+	})
+
+	return &capacityTableData
+}
+
+// valid returns true if the table contains the QR Code version at the recovery
+// level.
+func (t *capacityTable) valid(level RecoveryLevel, version int) bool {
+	return level >= Low && level <= Highest && version >= 1 && version <= 40 &&
+		t.index[level][version] >= 0
 }
 
 // MaxByteCapacity returns the maximum number of bytes encodable in a single
 // QR code at the given recovery level, using byte-mode encoding at Version 40
 // (the largest QR code version).
 func MaxByteCapacity(level RecoveryLevel) int {
-	v := getQRCodeVersion(level, 40)
-	if v == nil {
+	t := capacities()
+	if !t.valid(level, 40) {
+		return 0
+	}
+
+	return t.byteCapacity[level][40]
+}
+
+// maxContentLength returns an upper bound on the length in bytes of content
+// encodable at the recovery level, in any data mode, or 0 for invalid levels.
+//
+// Numeric mode is the densest per byte of content, at 10 bits per 3 digits
+// (Kanji mode is 13 bits per 2 bytes), so content longer than the bound is too
+// long without needing to be segmented.
+func maxContentLength(level RecoveryLevel) int {
+	t := capacities()
+	if !t.valid(level, 40) {
 		return 0
 	}
-	// Byte mode overhead for v27-40: 4 bits (mode indicator) + 16 bits (char count)
-	const byteOverheadBits = 4 + 16
-	return (v.numDataBits() - byteOverheadBits) / 8
+
+	return t.numDataBits[level][40] * 3 / 10
+}
+
+// ByteCapacity returns the maximum number of bytes encodable in a QR Code of
+// the given version (1-40) and recovery level, using byte-mode encoding.
+//
+// An error occurs in case of invalid version or recovery level.
+func ByteCapacity(version int, level RecoveryLevel) (int, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return 0, err
+	}
+
+	t := capacities()
+	if !t.valid(level, version) {
+		return 0, fmt.Errorf("Invalid version %d (expected 1-40 inclusive)", version)
+	}
+
+	return t.byteCapacity[level][version], nil
+}
+
+// MaxNumericCapacity returns the maximum number of numeric digits encodable in
+// a single QR code at the given recovery level, using numeric-mode encoding at
+// Version 40 (the largest QR code version).
+func MaxNumericCapacity(level RecoveryLevel) int {
+	t := capacities()
+	if !t.valid(level, 40) {
+		return 0
+	}
+	// Numeric mode overhead for v27-40: 4 bits (mode indicator) + 14 bits (char count)
+	const numericOverheadBits = 4 + 14
+	numBits := t.numDataBits[level][40] - numericOverheadBits
+
+	// 3 digits per 10 bits, then a final 1 or 2 digits in 4 or 7 bits.
+	n := 3 * (numBits / 10)
+	switch remainder := numBits % 10; {
+	case remainder >= 7:
+		n += 2
+	case remainder >= 4:
+		n++
+	}
+	return n
+}
+
+// MaxAlphanumericCapacity returns the maximum number of alphanumeric
+// characters (0-9, A-Z, space, and $%*+-./:) encodable in a single QR code at
+// the given recovery level, using alphanumeric-mode encoding at Version 40 (the
+// largest QR code version).
+func MaxAlphanumericCapacity(level RecoveryLevel) int {
+	t := capacities()
+	if !t.valid(level, 40) {
+		return 0
+	}
+	// Alphanumeric mode overhead for v27-40: 4 bits (mode indicator) + 13 bits (char count)
+	const alphanumericOverheadBits = 4 + 13
+	numBits := t.numDataBits[level][40] - alphanumericOverheadBits
+
+	// 2 characters per 11 bits, then a final character in 6 bits.
+	n := 2 * (numBits / 11)
+	if numBits%11 >= 6 {
+		n++
+	}
+	return n
+}
+
+// VersionFor returns the QR Code version, 1-40, New() would choose for content
+// at the recovery level, e.g. to plan the physical size of a print layout.
+//
+// The data modes are chosen as by New(), and the encoded length measured, but
+// the QR Code is not constructed. An error occurs as for New(): If the content
+// is empty, too long (ErrContentTooLong), or level is not one of the
+// RecoveryLevel constants.
+func VersionFor(content string, level RecoveryLevel) (int, error) {
+	if err := checkRecoveryLevel(level); err != nil {
+		return 0, err
+	}
+
+	v, err := versionFor([]byte(content), level, newDataEncoders())
+	if err != nil {
+		return 0, err
+	}
+
+	return v.version, nil
+}
+
+// newDataEncoders returns a dataEncoder for each range of QR Code versions, in
+// ascending order.
+func newDataEncoders() []*dataEncoder {
+	return []*dataEncoder{
+		newDataEncoder(dataEncoderType1To9),
+		newDataEncoder(dataEncoderType10To26),
+		newDataEncoder(dataEncoderType27To40),
+	}
+}
+
+// versionFor returns the QR Code version New() would choose for data at the
+// recovery level, measuring its encoded length with encoders (as returned by
+// newDataEncoders()).
+func versionFor(data []byte, level RecoveryLevel, encoders []*dataEncoder) (*qrCodeVersion, error) {
+	if len(data) > maxContentLength(level) {
+		return nil, ErrContentTooLong
+	}
+
+	err := ErrContentTooLong
+
+	for _, encoder := range encoders {
+		numBits, e := encoder.encodedBits(data)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		if v := chooseQRCodeVersion(level, encoder, numBits); v != nil {
+			return v, nil
+		}
+		err = ErrContentTooLong
+	}
+
+	return nil, err
 }
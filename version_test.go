@@ -4,6 +4,8 @@
 package qrcode
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
@@ -156,3 +158,159 @@ func TestNumBitsToPadToCodeoword(t *testing.T) {
 		}
 	}
 }
+
+func TestMaxCapacity(t *testing.T) {
+	// ISO/IEC 18004:2006 Table 7, Version 40.
+	tests := []struct {
+		level        RecoveryLevel
+		numeric      int
+		alphanumeric int
+		byte         int
+	}{
+		{Low, 7089, 4296, 2953},
+		{Medium, 5596, 3391, 2331},
+		{High, 3993, 2420, 1663},
+		{Highest, 3057, 1852, 1273},
+	}
+
+	for _, test := range tests {
+		if got := MaxNumericCapacity(test.level); got != test.numeric {
+			t.Errorf("level %d: got numeric capacity %d, expected %d", test.level, got, test.numeric)
+		}
+
+		if got := MaxAlphanumericCapacity(test.level); got != test.alphanumeric {
+			t.Errorf("level %d: got alphanumeric capacity %d, expected %d", test.level, got, test.alphanumeric)
+		}
+
+		if got := MaxByteCapacity(test.level); got != test.byte {
+			t.Errorf("level %d: got byte capacity %d, expected %d", test.level, got, test.byte)
+		}
+
+		// The capacities are exact.
+		if _, err := New(strings.Repeat("1", test.numeric), test.level); err != nil {
+			t.Errorf("level %d: numeric capacity not encodable: %s", test.level, err.Error())
+		}
+		if _, err := New(strings.Repeat("1", test.numeric+1), test.level); err == nil {
+			t.Errorf("level %d: numeric capacity+1 encodable", test.level)
+		}
+		if _, err := New(strings.Repeat("A", test.alphanumeric), test.level); err != nil {
+			t.Errorf("level %d: alphanumeric capacity not encodable: %s", test.level, err.Error())
+		}
+		if _, err := New(strings.Repeat("A", test.alphanumeric+1), test.level); err == nil {
+			t.Errorf("level %d: alphanumeric capacity+1 encodable", test.level)
+		}
+	}
+}
+
+func TestVersionFor(t *testing.T) {
+	contents := []string{
+		"1",
+		"HELLO WORLD",
+		"https://example.org",
+		strings.Repeat("0123456789", 100),
+		strings.Repeat("ALPHANUMERIC 123 ", 50),
+		strings.Repeat("Grüße aus Köln, ", 60),
+		strings.Repeat("\x93\xfa\x96\x7b", 200),
+		strings.Repeat("a", 2953),
+	}
+
+	for _, level := range []RecoveryLevel{Low, Medium, High, Highest} {
+		for _, content := range contents {
+			q, qErr := New(content, level)
+
+			version, err := VersionFor(content, level)
+			if (err == nil) != (qErr == nil) {
+				t.Errorf("level %d, %d bytes: got error %v, New got %v", level, len(content), err, qErr)
+				continue
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrContentTooLong) {
+					t.Errorf("level %d, %d bytes: got %v, expected ErrContentTooLong", level, len(content), err)
+				}
+				continue
+			}
+
+			if version != q.Version() {
+				t.Errorf("level %d, %d bytes: got version %d, expected %d", level, len(content), version, q.Version())
+			}
+		}
+	}
+
+	if _, err := VersionFor("", Medium); err == nil {
+		t.Errorf("empty content: got success, expected error")
+	}
+	if _, err := VersionFor("content", RecoveryLevel(-1)); err == nil {
+		t.Errorf("invalid level: got success, expected error")
+	}
+}
+
+func TestByteCapacity(t *testing.T) {
+	// ISO/IEC 18004:2006 Table 7.
+	tests := []struct {
+		version  int
+		level    RecoveryLevel
+		expected int
+	}{
+		{1, Low, 17},
+		{1, Medium, 14},
+		{1, High, 11},
+		{1, Highest, 7},
+		{9, Low, 230},
+		{10, Low, 271},
+		{10, Highest, 119},
+		{26, Medium, 1059},
+		{27, Low, 1465},
+		{40, Low, 2953},
+		{40, Highest, 1273},
+	}
+
+	for _, test := range tests {
+		got, err := ByteCapacity(test.version, test.level)
+		if err != nil {
+			t.Errorf("version %d level %d: got error %s", test.version, test.level, err.Error())
+			continue
+		}
+
+		if got != test.expected {
+			t.Errorf("version %d level %d: got %d, expected %d", test.version, test.level, got, test.expected)
+		}
+
+		// The capacity is exact.
+		if _, err := NewWithForcedVersion(strings.Repeat("a", got), test.version, test.level); err != nil {
+			t.Errorf("version %d level %d: capacity not encodable: %s", test.version, test.level, err.Error())
+		}
+		if _, err := NewWithForcedVersion(strings.Repeat("a", got+1), test.version, test.level); err == nil {
+			t.Errorf("version %d level %d: capacity+1 encodable", test.version, test.level)
+		}
+	}
+
+	if got, _ := ByteCapacity(40, Low); got != MaxByteCapacity(Low) {
+		t.Errorf("version 40 capacity %d differs from MaxByteCapacity %d", got, MaxByteCapacity(Low))
+	}
+
+	for _, version := range []int{0, 41, -1} {
+		if _, err := ByteCapacity(version, Low); err == nil {
+			t.Errorf("version %d: got success, expected error", version)
+		}
+	}
+
+	if _, err := ByteCapacity(1, RecoveryLevel(4)); err == nil {
+		t.Errorf("invalid level: got success, expected error")
+	}
+}
+
+func BenchmarkCapacityQueries(b *testing.B) {
+	b.ReportAllocs()
+
+	levels := []RecoveryLevel{Low, Medium, High, Highest}
+
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < 10000; i++ {
+			level := levels[i%len(levels)]
+
+			ByteCapacity(1+i%40, level)
+			MaxByteCapacity(level)
+		}
+	}
+}
@@ -0,0 +1,519 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// WebP returns the QR Code as a WebP image.
+//
+// Only lossless WebP images are supported, and lossless must be true: Lossy
+// compression blurs the module edges, much as JPEG does, which can make QR
+// Codes difficult to scan. Lossless WebP images preserve every pixel exactly,
+// including translucent colours.
+//
+// size is both the image width and height in pixels. If size is too small then
+// a larger image is silently returned, or an error if StrictSize is set.
+// Negative values for size cause a variable sized image to be returned: See the
+// documentation for Image().
+//
+// An error occurs if lossless is false, or the image is larger than 16384px.
+func (q *QRCode) WebP(size int, lossless bool) ([]byte, error) {
+	var b bytes.Buffer
+	err := q.WriteWebP(size, lossless, &b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteWebP writes the QR Code as a WebP image to io.Writer. See the
+// documentation for WebP().
+//
+// Unlike the other Write methods, the image is encoded in memory before it is
+// written, as the WebP container is prefixed by its length.
+func (q *QRCode) WriteWebP(size int, lossless bool, out io.Writer) error {
+	if !lossless {
+		return errors.New("lossy WebP is not supported: use lossless WebP for QR Codes")
+	}
+
+	if err := q.checkImage(size); err != nil {
+		return err
+	}
+
+	data, err := encodeWebPLossless(q.Image(size))
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	return err
+}
+
+// Lossless WebP (VP8L) constants.
+const (
+	// Maximum image width and height in pixels.
+	webpMaxSize = 1 << 14
+
+	// Number of literal symbols per colour channel.
+	webpNumLiterals = 256
+
+	// Number of backward reference length and distance prefix symbols.
+	webpNumLengthCodes   = 24
+	webpNumDistanceCodes = 40
+
+	// Maximum length of a backward reference, in pixels.
+	webpMaxCopyLength = 4096
+
+	// Distance codes for the pixel above, and the pixel to the left.
+	webpDistanceAbove = 1
+	webpDistanceLeft  = 2
+
+	// Minimum length of a backward reference worth encoding.
+	webpMinCopyLength = 3
+
+	// Maximum code lengths of the prefix codes, and of the code length code.
+	webpMaxCodeLength           = 15
+	webpMaxCodeLengthCodeLength = 7
+)
+
+// webpCodeLengthCodeOrder is the order the code length code lengths are
+// written in.
+var webpCodeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// webpToken is a literal pixel, or a backward reference to pixels already
+// encoded.
+type webpToken struct {
+	pixel color.NRGBA
+
+	// Length of the backward reference in pixels, or 0 for a literal pixel.
+	length int
+
+	// Distance code of the backward reference.
+	distanceCode int
+}
+
+// encodeWebPLossless encodes img as a lossless WebP image, in a RIFF container.
+//
+// No transforms or colour cache are used. Runs of pixels matching the pixel to
+// the left or the row above are encoded as backward references, which suits
+// the large blocks of a single colour in QR Codes.
+func encodeWebPLossless(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w < 1 || h < 1 || w > webpMaxSize || h > webpMaxSize {
+		return nil, fmt.Errorf("invalid WebP image size %dx%d (expected 1-%d pixels)", w, h, webpMaxSize)
+	}
+
+	pixels := make([]color.NRGBA, 0, w*h)
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			hasAlpha = hasAlpha || p.A != 0xff
+			pixels = append(pixels, p)
+		}
+	}
+
+	tokens := webpTokens(pixels, w)
+
+	// Symbol frequencies: green and lengths, red, blue, alpha and distance.
+	histograms := [5][]int{
+		make([]int, webpNumLiterals+webpNumLengthCodes),
+		make([]int, webpNumLiterals),
+		make([]int, webpNumLiterals),
+		make([]int, webpNumLiterals),
+		make([]int, webpNumDistanceCodes),
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthPrefix, _, _ := webpPrefixEncode(t.length)
+			distancePrefix, _, _ := webpPrefixEncode(t.distanceCode)
+
+			histograms[0][webpNumLiterals+lengthPrefix]++
+			histograms[4][distancePrefix]++
+			continue
+		}
+
+		histograms[0][t.pixel.G]++
+		histograms[1][t.pixel.R]++
+		histograms[2][t.pixel.B]++
+		histograms[3][t.pixel.A]++
+	}
+
+	bw := &webpBitWriter{}
+
+	// Image header.
+	bw.writeBits(0x2f, 8)
+	bw.writeBits(uint32(w-1), 14)
+	bw.writeBits(uint32(h-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // Version.
+
+	bw.writeBits(0, 1) // No transforms.
+	bw.writeBits(0, 1) // No colour cache.
+	bw.writeBits(0, 1) // A single set of prefix codes.
+
+	var codes [5]webpPrefixCode
+	for i, histogram := range histograms {
+		codes[i] = newWebPPrefixCode(histogram, webpMaxCodeLength)
+		bw.writePrefixCode(codes[i], histogram)
+	}
+
+	// Image data.
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthPrefix, numExtraBits, extraBits := webpPrefixEncode(t.length)
+			codes[0].write(bw, webpNumLiterals+lengthPrefix)
+			bw.writeBits(extraBits, numExtraBits)
+
+			distancePrefix, numExtraBits, extraBits := webpPrefixEncode(t.distanceCode)
+			codes[4].write(bw, distancePrefix)
+			bw.writeBits(extraBits, numExtraBits)
+			continue
+		}
+
+		codes[0].write(bw, int(t.pixel.G))
+		codes[1].write(bw, int(t.pixel.R))
+		codes[2].write(bw, int(t.pixel.B))
+		codes[3].write(bw, int(t.pixel.A))
+	}
+
+	data := bw.bytes()
+
+	// RIFF container, with a single VP8L chunk padded to an even length.
+	var out bytes.Buffer
+	chunkSize := len(data)
+	paddedSize := chunkSize + chunkSize%2
+
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(4+8+paddedSize))
+	out.WriteString("WEBPVP8L")
+	binary.Write(&out, binary.LittleEndian, uint32(chunkSize))
+	out.Write(data)
+	if chunkSize%2 != 0 {
+		out.WriteByte(0)
+	}
+
+	return out.Bytes(), nil
+}
+
+// webpTokens returns the image pixels, w pixels wide, as literal pixels and
+// backward references to the pixel to the left or the row above.
+func webpTokens(pixels []color.NRGBA, w int) []webpToken {
+	var tokens []webpToken
+
+	// matchLength returns the number of pixels from i matching those distance
+	// pixels earlier.
+	matchLength := func(i int, distance int) int {
+		if distance > i {
+			return 0
+		}
+
+		n := 0
+		for i+n < len(pixels) && n < webpMaxCopyLength && pixels[i+n] == pixels[i+n-distance] {
+			n++
+		}
+		return n
+	}
+
+	for i := 0; i < len(pixels); {
+		above := matchLength(i, w)
+		left := matchLength(i, 1)
+
+		switch {
+		case above >= webpMinCopyLength && above >= left:
+			tokens = append(tokens, webpToken{length: above, distanceCode: webpDistanceAbove})
+			i += above
+		case left >= webpMinCopyLength:
+			tokens = append(tokens, webpToken{length: left, distanceCode: webpDistanceLeft})
+			i += left
+		default:
+			tokens = append(tokens, webpToken{pixel: pixels[i]})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// webpPrefixEncode returns the prefix symbol and extra bits encoding the
+// backward reference length or distance code value (1 or greater).
+func webpPrefixEncode(value int) (prefix int, numExtraBits int, extraBits uint32) {
+	v := value - 1
+	if v < 4 {
+		return v, 0, 0
+	}
+
+	highestBit := 0
+	for v>>uint(highestBit+1) != 0 {
+		highestBit++
+	}
+
+	secondBit := (v >> uint(highestBit-1)) & 1
+	numExtraBits = highestBit - 1
+
+	return 2*highestBit + secondBit, numExtraBits, uint32(v & (1<<uint(numExtraBits) - 1))
+}
+
+// webpPrefixCode is a canonical prefix (Huffman) code.
+type webpPrefixCode struct {
+	// Code length of each symbol, 0 for unused symbols.
+	lengths []int
+
+	// Code of each symbol, in the order its bits are written.
+	codes []uint32
+
+	// Symbols of a simple code, which has at most two symbols, both less than
+	// 256.
+	simple []int
+
+	// True if the code has a single symbol, which is written with zero bits.
+	singleSymbol bool
+}
+
+// newWebPPrefixCode returns the prefix code for the symbol frequencies
+// histogram, with code lengths of at most maxLength bits.
+func newWebPPrefixCode(histogram []int, maxLength int) webpPrefixCode {
+	var used []int
+	for symbol, n := range histogram {
+		if n > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	c := webpPrefixCode{
+		lengths: make([]int, len(histogram)),
+		codes:   make([]uint32, len(histogram)),
+	}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < webpNumLiterals) {
+		c.simple = used
+		if len(c.simple) == 0 {
+			c.simple = []int{0}
+		}
+		c.singleSymbol = len(c.simple) == 1
+
+		if len(c.simple) == 2 {
+			c.lengths[used[0]], c.lengths[used[1]] = 1, 1
+			c.codes[used[1]] = 1
+		}
+
+		return c
+	}
+
+	c.lengths = webpCodeLengths(histogram, maxLength)
+	c.assignCodes()
+
+	return c
+}
+
+// assignCodes assigns canonical codes to the symbols from their code lengths.
+func (c *webpPrefixCode) assignCodes() {
+	numUsed := 0
+	for _, l := range c.lengths {
+		if l > 0 {
+			numUsed++
+		}
+	}
+
+	if numUsed < 2 {
+		c.singleSymbol = true
+		return
+	}
+
+	code := uint32(0)
+	for length := 1; length <= webpMaxCodeLength; length++ {
+		for symbol, l := range c.lengths {
+			if l != length {
+				continue
+			}
+
+			// Reverse the code, as the bits are written least significant
+			// first.
+			reversed := uint32(0)
+			for i := 0; i < length; i++ {
+				reversed |= (code >> uint(i) & 1) << uint(length-1-i)
+			}
+			c.codes[symbol] = reversed
+
+			code++
+		}
+		code <<= 1
+	}
+}
+
+// write writes the code of symbol.
+func (c webpPrefixCode) write(bw *webpBitWriter, symbol int) {
+	if c.singleSymbol {
+		return
+	}
+
+	bw.writeBits(c.codes[symbol], c.lengths[symbol])
+}
+
+// webpCodeLengths returns Huffman code lengths of at most maxLength bits for the
+// symbol frequencies histogram, which must contain at least two used symbols.
+//
+// Code lengths exceeding maxLength are avoided by raising the frequencies of
+// the least frequent symbols until the tree is shallow enough.
+func webpCodeLengths(histogram []int, maxLength int) []int {
+	minCount := 1
+
+	for {
+		lengths := huffmanCodeLengths(histogram, minCount)
+
+		longest := 0
+		for _, l := range lengths {
+			longest = max(longest, l)
+		}
+
+		if longest <= maxLength {
+			return lengths
+		}
+
+		minCount *= 2
+	}
+}
+
+// huffmanCodeLengths returns Huffman code lengths for the symbol frequencies
+// histogram, treating the frequency of each used symbol as at least minCount.
+func huffmanCodeLengths(histogram []int, minCount int) []int {
+	type node struct {
+		count int
+
+		// Symbols in the subtree.
+		symbols []int
+	}
+
+	var nodes []node
+	for symbol, n := range histogram {
+		if n > 0 {
+			nodes = append(nodes, node{count: max(n, minCount), symbols: []int{symbol}})
+		}
+	}
+
+	lengths := make([]int, len(histogram))
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbols[0]] = 1
+		return lengths
+	}
+
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i int, j int) bool {
+			return nodes[i].count < nodes[j].count
+		})
+
+		// Merge the two least frequent subtrees, one level deeper.
+		merged := node{
+			count:   nodes[0].count + nodes[1].count,
+			symbols: append(append([]int{}, nodes[0].symbols...), nodes[1].symbols...),
+		}
+		for _, symbol := range merged.symbols {
+			lengths[symbol]++
+		}
+
+		nodes = append(nodes[2:], merged)
+	}
+
+	return lengths
+}
+
+// webpBitWriter writes a bitstream, least significant bit first.
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// writeBits writes the n low bits of v.
+func (bw *webpBitWriter) writeBits(v uint32, n int) {
+	bw.acc |= uint64(v&(1<<uint(n)-1)) << bw.nbits
+	bw.nbits += uint(n)
+
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+// bytes returns the bitstream, zero padded to a whole byte.
+func (bw *webpBitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.writeBits(0, int(8-bw.nbits))
+	}
+
+	return bw.buf
+}
+
+// writePrefixCode writes the code lengths of the prefix code c, for the symbol
+// frequencies histogram.
+func (bw *webpBitWriter) writePrefixCode(c webpPrefixCode, histogram []int) {
+	if c.simple != nil {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(len(c.simple)-1), 1)
+
+		if c.simple[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(c.simple[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(c.simple[0]), 8)
+		}
+
+		if len(c.simple) == 2 {
+			bw.writeBits(uint32(c.simple[1]), 8)
+		}
+
+		return
+	}
+
+	// Normal code: The code lengths are themselves prefix coded, by the code
+	// length code. Each symbol's code length is written as a literal 0-15.
+	codeLengthHistogram := make([]int, len(webpCodeLengthCodeOrder))
+	for _, l := range c.lengths {
+		codeLengthHistogram[l]++
+	}
+
+	codeLengthCode := webpPrefixCode{
+		lengths: webpCodeLengths(codeLengthHistogram, webpMaxCodeLengthCodeLength),
+		codes:   make([]uint32, len(codeLengthHistogram)),
+	}
+	codeLengthCode.assignCodes()
+
+	numCodeLengths := 4
+	for i, symbol := range webpCodeLengthCodeOrder {
+		if codeLengthCode.lengths[symbol] > 0 {
+			numCodeLengths = max(numCodeLengths, i+1)
+		}
+	}
+
+	bw.writeBits(0, 1)
+	bw.writeBits(uint32(numCodeLengths-4), 4)
+	for _, symbol := range webpCodeLengthCodeOrder[:numCodeLengths] {
+		bw.writeBits(uint32(codeLengthCode.lengths[symbol]), 3)
+	}
+
+	bw.writeBits(0, 1) // Code lengths for every symbol follow.
+	for _, l := range c.lengths {
+		codeLengthCode.write(bw, l)
+	}
+}
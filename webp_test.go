@@ -0,0 +1,400 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWebP(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := q.WebP(-10, true)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(data) < 25 || !bytes.HasPrefix(data, []byte("RIFF")) || string(data[8:16]) != "WEBPVP8L" {
+		t.Fatalf("got prefix %q, expected RIFF/WEBP/VP8L", data[:min(len(data), 16)])
+	}
+
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Errorf("got RIFF size %d, expected %d", size, len(data)-8)
+	}
+
+	// VP8L signature, then the 14-bit width-1 and height-1.
+	if data[20] != 0x2f {
+		t.Errorf("got signature %#x, expected 0x2f", data[20])
+	}
+
+	header := binary.LittleEndian.Uint32(data[21:25])
+	width := int(header&0x3fff) + 1
+	height := int(header>>14&0x3fff) + 1
+
+	size := len(q.Bitmap()) * 10
+	if width != size || height != size {
+		t.Errorf("got size %dx%d, expected %dx%d", width, height, size, size)
+	}
+
+	// Backward references keep the large blocks of a single colour small.
+	png, err := q.PNG(-10)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(data) > 2*len(png) {
+		t.Errorf("got %d bytes, PNG is %d bytes", len(data), len(png))
+	}
+
+	if _, err := q.WebP(-10, false); err == nil {
+		t.Errorf("lossy: got success, expected error")
+	}
+
+	q.StrictSize = true
+	if _, err := q.WebP(10, true); err == nil {
+		t.Errorf("too small: got success, expected error")
+	}
+}
+
+func TestWebPPrefixEncode(t *testing.T) {
+	tests := []struct {
+		value        int
+		prefix       int
+		numExtraBits int
+		extraBits    uint32
+	}{
+		{1, 0, 0, 0},
+		{4, 3, 0, 0},
+		{5, 4, 1, 0},
+		{6, 4, 1, 1},
+		{7, 5, 1, 0},
+		{9, 6, 2, 0},
+		{4096, 23, 10, 1023},
+	}
+
+	for _, test := range tests {
+		prefix, numExtraBits, extraBits := webpPrefixEncode(test.value)
+
+		if prefix != test.prefix || numExtraBits != test.numExtraBits || extraBits != test.extraBits {
+			t.Errorf("%d: got (%d, %d, %d), expected (%d, %d, %d)", test.value,
+				prefix, numExtraBits, extraBits, test.prefix, test.numExtraBits, test.extraBits)
+		}
+	}
+}
+
+func TestWebPDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(q *QRCode)
+	}{
+		{"opaque", func(q *QRCode) {}},
+		{"transparent background", func(q *QRCode) {
+			q.BackgroundColor = color.RGBA{}
+		}},
+		{"gradient", func(q *QRCode) {
+			q.Gradient = &Gradient{
+				Start:     color.RGBA{0x80, 0, 0, 0xff},
+				End:       color.RGBA{0, 0, 0x80, 0xff},
+				Direction: Diagonal,
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		q, err := New("https://example.org/webp", Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		test.setup(q)
+
+		const size = 300
+		data, err := q.WebP(size, true)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		img, err := decodeWebPLossless(data)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+
+		expected := q.Image(size)
+		if img.Bounds() != expected.Bounds() {
+			t.Fatalf("%s: got bounds %v, expected %v", test.name, img.Bounds(), expected.Bounds())
+		}
+
+		b := expected.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				got := img.NRGBAAt(x, y)
+				want := color.NRGBAModel.Convert(expected.At(x, y)).(color.NRGBA)
+
+				if got != want {
+					t.Fatalf("%s: pixel (%d, %d): got %v, expected %v", test.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// decodeWebPLossless decodes a lossless WebP image, as written by WebP(), per
+// the WebP Lossless Bitstream Specification (RFC 9649).
+//
+// Only the features needed to check WebP() are supported: Transforms, colour
+// caches, meta prefix codes and most backward reference distance codes are
+// rejected.
+func decodeWebPLossless(data []byte) (*image.NRGBA, error) {
+	if len(data) < 20 || string(data[:4]) != "RIFF" || string(data[8:16]) != "WEBPVP8L" {
+		return nil, errors.New("not a lossless WebP image")
+	}
+
+	chunkSize := int(binary.LittleEndian.Uint32(data[16:20]))
+	if 20+chunkSize > len(data) {
+		return nil, errors.New("truncated VP8L chunk")
+	}
+
+	br := &webpBitReader{data: data[20 : 20+chunkSize]}
+
+	if br.readBits(8) != 0x2f {
+		return nil, errors.New("bad VP8L signature")
+	}
+	w := int(br.readBits(14)) + 1
+	h := int(br.readBits(14)) + 1
+	br.readBits(1) // Alpha hint.
+	if br.readBits(3) != 0 {
+		return nil, errors.New("bad VP8L version")
+	}
+
+	if br.readBits(1) != 0 {
+		return nil, errors.New("transforms are not supported")
+	}
+	if br.readBits(1) != 0 {
+		return nil, errors.New("colour caches are not supported")
+	}
+	if br.readBits(1) != 0 {
+		return nil, errors.New("meta prefix codes are not supported")
+	}
+
+	// Green and lengths, red, blue, alpha and distance.
+	alphabetSizes := [5]int{256 + 24, 256, 256, 256, 40}
+	var codes [5]*webpTestPrefixCode
+	for i, n := range alphabetSizes {
+		var err error
+		if codes[i], err = br.readPrefixCode(n); err != nil {
+			return nil, err
+		}
+	}
+
+	// Distance codes 1-4, as offsets (x, y) to the left and above.
+	distanceMap := [][2]int{{0, 1}, {1, 0}, {1, 1}, {-1, 1}}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	pixels := make([]color.NRGBA, 0, w*h)
+	for len(pixels) < w*h {
+		if br.err != nil {
+			return nil, br.err
+		}
+
+		green := codes[0].decode(br)
+		if green < 256 {
+			red := codes[1].decode(br)
+			blue := codes[2].decode(br)
+			alpha := codes[3].decode(br)
+			pixels = append(pixels, color.NRGBA{uint8(red), uint8(green), uint8(blue), uint8(alpha)})
+			continue
+		}
+
+		length := br.readPrefixValue(green - 256)
+		distanceCode := br.readPrefixValue(codes[4].decode(br))
+		if distanceCode > len(distanceMap) {
+			return nil, fmt.Errorf("distance code %d is not supported", distanceCode)
+		}
+
+		offset := distanceMap[distanceCode-1]
+		distance := max(1, offset[0]+offset[1]*w)
+		if distance > len(pixels) || len(pixels)+length > w*h {
+			return nil, errors.New("backward reference out of range")
+		}
+
+		for i := 0; i < length; i++ {
+			pixels = append(pixels, pixels[len(pixels)-distance])
+		}
+	}
+
+	if br.err != nil {
+		return nil, br.err
+	}
+
+	for i, p := range pixels {
+		img.SetNRGBA(i%w, i/w, p)
+	}
+
+	return img, nil
+}
+
+// webpBitReader reads a bitstream, least significant bit first.
+type webpBitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// readBits reads n bits, as an integer least significant bit first.
+func (br *webpBitReader) readBits(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		if br.pos >= 8*len(br.data) {
+			br.err = errors.New("unexpected end of bitstream")
+			return 0
+		}
+
+		v |= int(br.data[br.pos/8]>>uint(br.pos%8)&1) << uint(i)
+		br.pos++
+	}
+
+	return v
+}
+
+// readPrefixValue reads a backward reference length or distance code encoded
+// with prefix.
+func (br *webpBitReader) readPrefixValue(prefix int) int {
+	if prefix < 4 {
+		return prefix + 1
+	}
+
+	numExtraBits := (prefix - 2) >> 1
+	offset := (2 + prefix&1) << uint(numExtraBits)
+
+	return offset + br.readBits(numExtraBits) + 1
+}
+
+// readPrefixCode reads a simple or normal prefix code of an alphabet of n
+// symbols.
+func (br *webpBitReader) readPrefixCode(n int) (*webpTestPrefixCode, error) {
+	lengths := make([]int, n)
+
+	if br.readBits(1) == 1 {
+		numSymbols := br.readBits(1) + 1
+		symbols := []int{br.readBits(1 + 7*br.readBits(1))}
+		if numSymbols == 2 {
+			symbols = append(symbols, br.readBits(8))
+		}
+
+		for _, s := range symbols {
+			if s >= n {
+				return nil, fmt.Errorf("simple code symbol %d out of range", s)
+			}
+			lengths[s] = 1
+		}
+
+		return newWebPTestPrefixCode(lengths), br.err
+	}
+
+	codeLengthCodeLengths := make([]int, 19)
+	numCodeLengths := 4 + br.readBits(4)
+	for _, s := range webpCodeLengthCodeOrder[:numCodeLengths] {
+		codeLengthCodeLengths[s] = br.readBits(3)
+	}
+	codeLengthCode := newWebPTestPrefixCode(codeLengthCodeLengths)
+
+	maxSymbol := n
+	if br.readBits(1) == 1 {
+		lengthNumBits := 2 + 2*br.readBits(3)
+		maxSymbol = 2 + br.readBits(lengthNumBits)
+	}
+
+	previous := 8
+	for s := 0; s < n && maxSymbol > 0 && br.err == nil; maxSymbol-- {
+		l := codeLengthCode.decode(br)
+
+		repeat, value := 1, l
+		switch l {
+		case 16:
+			repeat, value = 3+br.readBits(2), previous
+		case 17:
+			repeat, value = 3+br.readBits(3), 0
+		case 18:
+			repeat, value = 11+br.readBits(7), 0
+		}
+
+		if s+repeat > n {
+			return nil, errors.New("code lengths out of range")
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[s] = value
+			s++
+		}
+
+		if l < 16 && l != 0 {
+			previous = l
+		}
+	}
+
+	return newWebPTestPrefixCode(lengths), br.err
+}
+
+// webpTestPrefixCode is a canonical prefix code, decoded a bit at a time.
+type webpTestPrefixCode struct {
+	// Symbols of each code, by code length and code.
+	symbols map[[2]int]int
+
+	// Symbol of a code with a single symbol, read with zero bits, or -1.
+	single int
+}
+
+// newWebPTestPrefixCode returns the canonical prefix code with the symbol code
+// lengths lengths.
+func newWebPTestPrefixCode(lengths []int) *webpTestPrefixCode {
+	c := &webpTestPrefixCode{symbols: map[[2]int]int{}, single: -1}
+
+	var used []int
+	for s, l := range lengths {
+		if l > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 1 {
+		c.single = used[0]
+		return c
+	}
+
+	code := 0
+	for length := 1; length <= 15; length++ {
+		for s, l := range lengths {
+			if l == length {
+				c.symbols[[2]int{length, code}] = s
+				code++
+			}
+		}
+		code <<= 1
+	}
+
+	return c
+}
+
+// decode reads a symbol.
+func (c *webpTestPrefixCode) decode(br *webpBitReader) int {
+	if c.single >= 0 {
+		return c.single
+	}
+
+	code := 0
+	for length := 1; length <= 15; length++ {
+		code = code<<1 | br.readBits(1)
+		if s, ok := c.symbols[[2]int{length, code}]; ok {
+			return s
+		}
+	}
+
+	br.err = errors.New("invalid prefix code")
+	return 0
+}